@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -18,9 +23,12 @@ import (
 
 	"github.com/chrisdietr/coolify-patrol/internal/config"
 	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/leader"
 	"github.com/chrisdietr/coolify-patrol/internal/registry"
 	"github.com/chrisdietr/coolify-patrol/internal/server"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
 	"github.com/chrisdietr/coolify-patrol/internal/watcher"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
 )
 
 var (
@@ -39,10 +47,22 @@ func main() {
 		logFormat   = flag.String("log-format", "json", "Log format: json or text")
 		port        = flag.Int("port", 8080, "HTTP server port")
 		showVersion = flag.Bool("version", false, "Print version and exit")
-		command     = flag.String("command", "", "Command to run: check, status, discover")
+		command     = flag.String("command", "", "Command to run: check, status, discover, history, pause, resume, pending, approve, reject, reset-breaker")
+		historyApp  = flag.String("app", "", "history, reset-breaker: only entries or the breaker of this app name or UUID")
+		since       = flag.String("since", "", "history: only entries since a duration ago (24h), date or RFC 3339 time")
+		limit       = flag.Int("limit", 50, "history: maximum number of entries")
+		output      = flag.String("output", "table", "history, pending: output format, table or json")
+		reason      = flag.String("reason", "", "pause: why updates are paused")
+		addr        = flag.String("addr", "", "pause, resume, approve, reject, reset-breaker: address of the running patrol (default: http://localhost:<port>)")
+		pendingID   = flag.String("id", "", "approve, reject: ID of the pending update")
 	)
 	flag.Parse()
 
+	// SIGHUP terminates by default, catch it before anything slow like the
+	// connection test runs. It only triggers reloads once the watcher runs.
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+
 	if *showVersion {
 		fmt.Printf("coolify-patrol %s\n", version)
 		fmt.Printf("Commit: %s\n", commit)
@@ -127,65 +147,97 @@ func main() {
 	)
 
 	// Load configuration
-	cfg, err := config.Load(*configPath)
+	cfg, err := loadConfig(*configPath, *schedule, *interval)
 	if err != nil {
 		logger.Error("Failed to load configuration", "error", err)
 		os.Exit(1)
 	}
 
-	// Override schedule or interval if specified (schedule takes priority)
-	if *schedule != "" {
-		// Validate cron schedule
-		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		if _, err := parser.Parse(*schedule); err != nil {
-			logger.Error("Invalid cron schedule format", "schedule", *schedule, "error", err)
+	// Create clients
+	coolifyClients := newCoolifyClients(cfg)
+	registryClient := registry.NewClient()
+
+	// State from previous runs, kept in memory only without a state directory
+	store := state.NewStore(cfg.StateDir)
+	if cfg.StateDir == "" {
+		logger.Warn("No state directory configured, cooldowns and status are lost on restart; set PATROL_STATE_DIR to persist them")
+	}
+
+	// These commands only read the saved state or talk to the running patrol, Coolify needn't be reachable
+	switch *command {
+	case "status":
+		status := watcher.NewWatcher(cfg, coolifyClients, registryClient, store, logger, *dryRun).GetStatus()
+		if err := json.NewEncoder(os.Stdout).Encode(status); err != nil {
+			logger.Error("Failed to encode status", "error", err)
 			os.Exit(1)
 		}
-		cfg.Defaults.Schedule = *schedule
-		cfg.Defaults.Interval = "" // Clear interval when schedule is set
-	} else if *interval != "" {
-		if _, err := time.ParseDuration(*interval); err != nil {
-			logger.Error("Invalid interval format", "interval", *interval, "error", err)
-			os.Exit(1)
+		return
+	case "history":
+		w := watcher.NewWatcher(cfg, coolifyClients, registryClient, store, logger, *dryRun)
+		handleHistoryCommand(w, logger, *historyApp, *since, *limit, *output)
+		return
+	case "pending":
+		w := watcher.NewWatcher(cfg, coolifyClients, registryClient, store, logger, *dryRun)
+		handlePendingCommand(w, logger, *output)
+		return
+	case "pause", "resume", "approve", "reject", "reset-breaker":
+		// The running patrol owns the state, so ask it instead of changing the state file
+		if *addr == "" {
+			*addr = fmt.Sprintf("http://localhost:%d", *port)
 		}
-		cfg.Defaults.Interval = *interval
-		cfg.Defaults.Schedule = "" // Clear schedule when interval is set
+		switch *command {
+		case "pause", "resume":
+			handlePauseCommand(*command, *addr, cfg.APIToken, *reason, logger)
+		case "reset-breaker":
+			handleResetBreakerCommand(*addr, cfg.APIToken, *historyApp, logger)
+		default:
+			handleDecisionCommand(*command, *addr, cfg.APIToken, *pendingID, logger)
+		}
+		return
 	}
 
-	// Create clients
-	coolifyClient := coolify.NewClient(cfg.Coolify.URL, cfg.Coolify.Token)
-	registryClient := registry.NewClient()
-
-	// Test Coolify connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	if err := coolifyClient.TestConnection(ctx); err != nil {
-		logger.Error("Failed to connect to Coolify", "error", err)
+	// Test Coolify connections
+	for _, instance := range cfg.Instances {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := coolifyClients[instance.Name].TestConnection(ctx)
 		cancel()
-		os.Exit(1)
+		if err != nil {
+			logger.Error("Failed to connect to Coolify", "instance", instance.Name, "url", instance.URL, "error", err)
+			os.Exit(1)
+		}
 	}
-	cancel()
 
 	// Create watcher
-	w := watcher.NewWatcher(cfg, coolifyClient, registryClient, logger, *dryRun)
+	w := watcher.NewWatcher(cfg, coolifyClients, registryClient, store, logger, *dryRun)
 
 	// Handle commands
 	switch *command {
 	case "check":
 		*once = true
-	case "status":
-		status := w.GetStatus()
-		if err := json.NewEncoder(os.Stdout).Encode(status); err != nil {
-			logger.Error("Failed to encode status", "error", err)
-			os.Exit(1)
-		}
-		return
 	case "discover":
 		handleDiscoverCommand(w, logger)
 		return
 	}
 
+	// Only the instance holding the leader lock checks and updates apps
+	lock, err := leader.New(cfg, coolifyClients)
+	if err != nil {
+		logger.Error("Failed to set up leader lock", "error", err)
+		os.Exit(1)
+	}
+	if cfg.Leader.Lock == types.LockFile && !cfg.Leader.Shared {
+		// Docker gives every container its own anonymous volume, the lock then keeps nobody out
+		logger.Warn("The file leader lock only works if all patrol containers mount the same volume, an anonymous or per-container volume lets the old and new container both update apps during a redeploy; mount a named volume and set PATROL_LEADER_SHARED=true, or use PATROL_LEADER_LOCK=coolify",
+			"path", cfg.Leader.Path,
+		)
+	}
+	if lock != nil {
+		logger.Info("Using leader lock", "lock", cfg.Leader.Lock, "id", cfg.Leader.ID)
+		w.UseLock(lock, leader.RenewInterval(cfg.Leader))
+	}
+
 	// Setup context for graceful shutdown
-	ctx, cancel = context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Handle shutdown signals
@@ -195,7 +247,7 @@ func main() {
 	// Start HTTP server (unless running once)
 	var httpServer *server.Server
 	if !*once {
-		httpServer = server.NewServer(w, logger, *port, version)
+		httpServer = server.NewServer(w, logger, *port, version, cfg.APIToken)
 		go func() {
 			if err := httpServer.Start(); err != nil && err != http.ErrServerClosed {
 				logger.Error("HTTP server failed", "error", err)
@@ -210,6 +262,28 @@ func main() {
 		watcherDone <- w.Start(ctx, *once)
 	}()
 
+	// Reload the configuration on SIGHUP and whenever the file changes
+	if !*once {
+		go watchConfig(ctx, *configPath, hupCh, logger, func() {
+			newCfg, err := loadConfig(*configPath, *schedule, *interval)
+			if err != nil {
+				logger.Error("Failed to reload configuration, keeping the previous one", "error", err)
+				w.ReloadFailed(err)
+				return
+			}
+			changes, err := w.Reload(newCfg, newCoolifyClients(newCfg))
+			if err != nil {
+				logger.Error("Failed to reload configuration, keeping the previous one", "error", err)
+				return
+			}
+			httpServer.SetAPIToken(newCfg.APIToken)
+			logger.Info("Configuration reloaded", "changes", len(changes))
+			for _, change := range changes {
+				logger.Info("Configuration changed", "change", change)
+			}
+		})
+	}
+
 	// Wait for completion or signal
 	select {
 	case sig := <-sigCh:
@@ -247,6 +321,97 @@ func main() {
 	logger.Info("Coolify patrol stopped")
 }
 
+// loadConfig loads the configuration and applies the -schedule or -interval
+// override, schedule taking priority
+func loadConfig(path, schedule, interval string) (*types.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if schedule != "" {
+		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+		if _, err := parser.Parse(schedule); err != nil {
+			return nil, fmt.Errorf("invalid cron schedule '%s': %w", schedule, err)
+		}
+		cfg.Defaults.Schedule = schedule
+		cfg.Defaults.Interval = "" // Clear interval when schedule is set
+	} else if interval != "" {
+		if _, err := time.ParseDuration(interval); err != nil {
+			return nil, fmt.Errorf("invalid interval '%s': %w", interval, err)
+		}
+		cfg.Defaults.Interval = interval
+		cfg.Defaults.Schedule = "" // Clear schedule when interval is set
+	}
+	return cfg, nil
+}
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 10 * time.Second
+
+// watchConfig calls reload on a signal from hupCh, which SIGHUP is relayed to,
+// and, if path is set, whenever the modification time or size of the file
+// changes
+func watchConfig(ctx context.Context, path string, hupCh <-chan os.Signal, logger *slog.Logger, reload func()) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	// A file that can't be read, like one being replaced, keeps the last known version
+	type version struct {
+		modTime int64
+		size    int64
+	}
+	stat := func(previous version) version {
+		info, err := os.Stat(path)
+		if err != nil {
+			return previous
+		}
+		return version{modTime: info.ModTime().UnixNano(), size: info.Size()}
+	}
+
+	var last version
+	if path != "" {
+		last = stat(last)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hupCh:
+			logger.Info("Received SIGHUP, reloading configuration")
+			if path != "" {
+				last = stat(last)
+			}
+			reload()
+		case <-ticker.C:
+			if path == "" {
+				continue
+			}
+			if current := stat(last); current != last {
+				last = current
+				logger.Info("Configuration file changed, reloading", "config", path)
+				reload()
+			}
+		}
+	}
+}
+
+// newCoolifyClients creates one Coolify API client per configured instance
+func newCoolifyClients(cfg *types.Config) map[string]*coolify.Client {
+	clients := make(map[string]*coolify.Client, len(cfg.Instances))
+	for _, instance := range cfg.Instances {
+		client := coolify.NewClient(instance.URL, instance.Token)
+		retryBackoff, _ := time.ParseDuration(instance.RetryBackoff)
+		client.SetRetryConfig(coolify.RetryConfig{
+			MaxRetries:     *instance.Retries,
+			InitialBackoff: retryBackoff,
+			MaxBackoff:     30 * time.Second,
+		})
+		clients[instance.Name] = client
+	}
+	return clients
+}
+
 func handleDiscoverCommand(w *watcher.Watcher, logger *slog.Logger) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -279,8 +444,170 @@ func handleDiscoverCommand(w *watcher.Watcher, logger *slog.Logger) {
 
 	fmt.Println("\n# Applications found:")
 	for _, app := range apps {
-		fmt.Printf("# - %s (%s): %s\n", app.Name, app.UUID, app.DockerImage)
+		fmt.Printf("# - [%s] %s (%s): %s\n", app.Instance, app.Name, app.UUID, app.DockerImage)
+	}
+}
+
+func handleHistoryCommand(w *watcher.Watcher, logger *slog.Logger, app, since string, limit int, output string) {
+	sinceTime, err := state.ParseSince(since, time.Now())
+	if err != nil {
+		logger.Error("Invalid history query", "error", err)
+		os.Exit(1)
+	}
+
+	entries := w.History(types.HistoryQuery{App: app, Since: sinceTime, Limit: limit})
+
+	switch output {
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(types.HistoryResponse{Entries: entries}); err != nil {
+			logger.Error("Failed to encode history", "error", err)
+			os.Exit(1)
+		}
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tINSTANCE\tAPP\tEVENT\tFROM\tTO\tPOLICY\tACTOR\tREASON")
+		for _, entry := range entries {
+			event := string(entry.Event)
+			if entry.DryRun {
+				event += " (dry run)"
+			}
+			if entry.Repeats > 0 {
+				event += fmt.Sprintf(" x%d, last %s", entry.Repeats+1, entry.LastSeen.Local().Format("2006-01-02 15:04"))
+			}
+			// Hook output spans lines, it is only part of the JSON output
+			reason := entry.Reason
+			if entry.Hook != "" {
+				reason = strings.TrimSuffix(entry.Hook+": "+entry.Reason, ": ")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				entry.Time.Local().Format("2006-01-02 15:04:05"),
+				entry.Instance,
+				entry.App,
+				event,
+				entry.FromTag,
+				entry.ToTag,
+				entry.Policy,
+				entry.Actor,
+				reason,
+			)
+		}
+		tw.Flush()
+	default:
+		logger.Error("Invalid output format, must be table or json", "output", output)
+		os.Exit(1)
+	}
+}
+
+func handlePauseCommand(command, addr, token, reason string, logger *slog.Logger) {
+	var status types.StatusResponse
+	if err := callPatrol(addr+"/"+command, token, types.ActionRequest{Reason: reason, Actor: "cli"}, &status); err != nil {
+		logger.Error("Failed to "+command+" updates", "addr", addr, "error", err)
+		os.Exit(1)
+	}
+
+	if status.Pause != nil {
+		fmt.Printf("Updates paused since %s by %s", status.Pause.Since.Local().Format("2006-01-02 15:04:05"), status.Pause.Actor)
+		if status.Pause.Reason != "" {
+			fmt.Printf(": %s", status.Pause.Reason)
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("Updates running")
+	}
+}
+
+func handleResetBreakerCommand(addr, token, app string, logger *slog.Logger) {
+	endpoint := addr + "/breaker/reset"
+	if app != "" {
+		endpoint += "?app=" + url.QueryEscape(app)
+	}
+
+	var status types.StatusResponse
+	if err := callPatrol(endpoint, token, types.ActionRequest{Actor: "cli"}, &status); err != nil {
+		logger.Error("Failed to reset circuit breaker", "addr", addr, "app", app, "error", err)
+		os.Exit(1)
+	}
+	fmt.Println("Circuit breaker reset, updates resume with the next cycle")
+}
+
+func handleDecisionCommand(command, addr, token, id string, logger *slog.Logger) {
+	if id == "" {
+		logger.Error("Missing -id of the pending update, see the pending command")
+		os.Exit(1)
+	}
+
+	var decided types.PendingUpdate
+	if err := callPatrol(addr+"/pending/"+url.PathEscape(id)+"/"+command, token, types.ActionRequest{Actor: "cli"}, &decided); err != nil {
+		logger.Error("Failed to "+command+" update", "addr", addr, "id", id, "error", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %s %s -> %s %s\n", decided.ID, decided.App, decided.FromTag, decided.ToTag, decided.Status)
+}
+
+func handlePendingCommand(w *watcher.Watcher, logger *slog.Logger, output string) {
+	updates := w.Pending()
+
+	switch output {
+	case "json":
+		if err := json.NewEncoder(os.Stdout).Encode(types.PendingResponse{Updates: updates}); err != nil {
+			logger.Error("Failed to encode pending updates", "error", err)
+			os.Exit(1)
+		}
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCREATED\tINSTANCE\tAPP\tFROM\tTO\tSTATUS\tDECIDED BY\tREASON")
+		for _, update := range updates {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				update.ID,
+				update.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				update.Instance,
+				update.App,
+				update.FromTag,
+				update.ToTag,
+				update.Status,
+				update.DecidedBy,
+				update.Reason,
+			)
+		}
+		tw.Flush()
+	default:
+		logger.Error("Invalid output format, must be table or json", "output", output)
+		os.Exit(1)
+	}
+}
+
+// callPatrol posts request to an endpoint of the running patrol and decodes
+// the JSON response into response
+func callPatrol(endpoint, token string, request types.ActionRequest, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encoding request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid patrol address: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("reaching running patrol: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("patrol responded %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
 	}
+	return nil
 }
 
 func showHelp() {
@@ -294,12 +621,20 @@ func showHelp() {
 	
 	fmt.Println("\nCOMMANDS:")
 	fmt.Println("  check                 Run one check cycle and exit")
-	fmt.Println("  status                Print current status of all watched apps")
+	fmt.Println("  status                Print the saved status of all watched apps")
 	fmt.Println("  discover              List all Coolify apps and suggest config")
+	fmt.Println("  history               Print recorded decisions (-app, -since, -limit, -output table|json)")
+	fmt.Println("  pause                 Pause updates of the running patrol, checks continue (-reason, -addr)")
+	fmt.Println("  resume                Resume updates of the running patrol (-addr)")
+	fmt.Println("  pending               Print updates awaiting approval and past decisions (-output table|json)")
+	fmt.Println("  approve               Approve a pending update, applied by the next cycle (-id, -addr)")
+	fmt.Println("  reject                Reject a pending update, it isn't proposed again (-id, -addr)")
+	fmt.Println("  reset-breaker         Close the circuit breakers of the running patrol, or of one app (-app, -addr)")
 	
 	fmt.Println("\nCONFIGURATION:")
 	fmt.Println("  Coolify Patrol can be configured via YAML file OR environment variables.")
 	fmt.Println("  Environment variables take precedence over YAML settings.")
+	fmt.Println("  The YAML file is reloaded when it changes and on SIGHUP, without a restart.")
 	
 	fmt.Println("\n  Required Environment Variables:")
 	fmt.Println("    COOLIFY_URL         Coolify server URL (e.g., http://localhost:8000)")
@@ -310,9 +645,32 @@ func showHelp() {
 	fmt.Println("    PATROL_INTERVAL     Check interval (default: 15m)")
 	fmt.Println("    PATROL_POLICY       Default policy: auto-patch|auto-minor|auto-all|notify-only")
 	fmt.Println("    PATROL_COOLDOWN     Cooldown between updates (default: 1h)")
+	fmt.Println("    PATROL_UPDATE_DELAY Pause between two updates in a cycle (default: 30s)")
+	fmt.Println("    PATROL_MAX_BACKOFF  Longest wait between checks of a failing app (default: 24h)")
+	fmt.Println("    PATROL_CHECK_CONCURRENCY  Apps checked in parallel (default: 4)")
+	fmt.Println("    PATROL_MAX_UPDATES_PER_CYCLE  Updates applied per cycle, the rest is queued (default: no limit)")
+	fmt.Println("    PATROL_MAX_CONCURRENT_PER_SERVER  Deployments running at once on the same Coolify server (default: no limit)")
+	fmt.Println("    PATROL_BATCH_SIZE   Updates per cycle as a share of the watched apps, like 10%")
 	fmt.Println("    PATROL_DRY_RUN      Set to 'true' for dry-run mode")
 	fmt.Println("    PATROL_PORT         HTTP server port (default: 8080)")
 	fmt.Println("    PATROL_EXCLUDE_PATTERNS  Comma-separated patterns to exclude (e.g., '-alpha,-beta')")
+	fmt.Println("    PATROL_COOLIFY_RETRIES   Retries for transient Coolify API errors (default: 3)")
+	fmt.Println("    PATROL_COOLIFY_RETRY_BACKOFF  Initial retry backoff, doubled per retry (default: 1s)")
+	fmt.Println("    PATROL_SELF_UUID    Coolify UUID of patrol itself")
+	fmt.Println("    PATROL_SELF_IMAGE   Image of patrol itself (default: any image named coolify-patrol)")
+	fmt.Println("    PATROL_SELF_UPDATE  Set to 'true' to let patrol update itself last in each cycle")
+	fmt.Println("    PATROL_STATE_DIR    Directory for persistent state (cooldowns, status); in memory if unset")
+	fmt.Println("    PATROL_API_TOKEN    Bearer token required by POST endpoints like /pause and /resume, enables /check")
+	fmt.Println("    PATROL_LEADER_LOCK  Lock against two instances updating: file|http|coolify|none (default: file with a state dir)")
+	fmt.Println("    PATROL_LEADER_PATH  Lock file (default: <state dir>/patrol.lock)")
+	fmt.Println("    PATROL_LEADER_SHARED  Set to 'true' if all patrol containers mount the lock file's volume")
+	fmt.Println("    PATROL_LEADER_URL   Lease endpoint of the http lock")
+	fmt.Println("    PATROL_LEADER_APP   UUID of the app holding the coolify lock marker (default: PATROL_SELF_UUID)")
+	fmt.Println("    PATROL_LEADER_TTL   Lease duration of the http and coolify locks (default: 30s)")
+	fmt.Println("    PATROL_BREAKER_THRESHOLD  Failed updates of an app that stop its updates (default: 3, 0 disables)")
+	fmt.Println("    PATROL_BREAKER_GLOBAL_THRESHOLD  Failed updates of any apps that stop all updates (default: 5, 0 disables)")
+	fmt.Println("    PATROL_BREAKER_WINDOW  Only failures within this period count (default: 24h)")
+	fmt.Println("    PATROL_BREAKER_TIMEOUT  Updates resume after this without a reset (default: 6h)")
 	
 	fmt.Println("\n  App Configuration (choose one):")
 	fmt.Println("    PATROL_AUTO_DISCOVER=true    Auto-discover all Coolify applications")
@@ -4,4 +4,4 @@ go 1.23
 
 require gopkg.in/yaml.v3 v3.0.1
 
-require github.com/robfig/cron/v3 v3.0.1 // indirect
+require github.com/robfig/cron/v3 v3.0.1
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,6 +13,8 @@ import (
 	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
 
+	"github.com/chrisdietr/coolify-patrol/internal/hook"
+	"github.com/chrisdietr/coolify-patrol/internal/window"
 	"github.com/chrisdietr/coolify-patrol/pkg/types"
 )
 
@@ -57,13 +61,34 @@ func Load(path string) (*types.Config, error) {
 	if len(config.Defaults.ExcludePatterns) == 0 {
 		config.Defaults.ExcludePatterns = []string{"-alpha", "-beta", "-rc", "-dev", "-nightly"}
 	}
+	if config.Defaults.UpdateDelay == "" {
+		config.Defaults.UpdateDelay = "30s"
+	}
+	if config.Defaults.MaxBackoff == "" {
+		config.Defaults.MaxBackoff = "24h"
+	}
+	if config.CheckConcurrency == 0 {
+		config.CheckConcurrency = 4
+	}
+	if config.RegistryConcurrency == 0 {
+		config.RegistryConcurrency = 2
+	}
+	if config.Coolify.Retries == nil {
+		retries := 3
+		config.Coolify.Retries = &retries
+	}
+	if config.Coolify.RetryBackoff == "" {
+		config.Coolify.RetryBackoff = "1s"
+	}
 
 	// Validate required fields
-	if config.Coolify.URL == "" {
-		return nil, fmt.Errorf("COOLIFY_URL is required")
-	}
-	if config.Coolify.Token == "" {
-		return nil, fmt.Errorf("COOLIFY_TOKEN is required")
+	if len(config.Instances) == 0 {
+		if config.Coolify.URL == "" {
+			return nil, fmt.Errorf("COOLIFY_URL is required")
+		}
+		if config.Coolify.Token == "" {
+			return nil, fmt.Errorf("COOLIFY_TOKEN is required")
+		}
 	}
 
 	// Validate intervals and schedule
@@ -83,10 +108,380 @@ func Load(path string) (*types.Config, error) {
 	if _, err := time.ParseDuration(config.Defaults.Cooldown); err != nil {
 		return nil, fmt.Errorf("invalid PATROL_COOLDOWN: %w", err)
 	}
+	if _, err := time.ParseDuration(config.Defaults.UpdateDelay); err != nil {
+		return nil, fmt.Errorf("invalid PATROL_UPDATE_DELAY: %w", err)
+	}
+	if _, err := time.ParseDuration(config.Defaults.MaxBackoff); err != nil {
+		return nil, fmt.Errorf("invalid PATROL_MAX_BACKOFF: %w", err)
+	}
+	if _, err := window.ParseAll(config.Defaults.MaintenanceWindows); err != nil {
+		return nil, fmt.Errorf("defaults: %w", err)
+	}
+	if _, err := window.ParseFreezes(config.Freezes); err != nil {
+		return nil, err
+	}
+	if err := hook.ValidateAll(config.Hooks); err != nil {
+		return nil, err
+	}
+	if config.CheckConcurrency < 1 {
+		return nil, fmt.Errorf("invalid PATROL_CHECK_CONCURRENCY: must be at least 1")
+	}
+	if config.RegistryConcurrency < 1 {
+		return nil, fmt.Errorf("invalid registry_concurrency: must be at least 1")
+	}
+	if config.MaxUpdatesPerCycle < 0 {
+		return nil, fmt.Errorf("invalid PATROL_MAX_UPDATES_PER_CYCLE: must not be negative")
+	}
+	if config.MaxConcurrentPerServer < 0 {
+		return nil, fmt.Errorf("invalid PATROL_MAX_CONCURRENT_PER_SERVER: must not be negative")
+	}
+	if _, err := ParseBatchSize(config.BatchSize); err != nil {
+		return nil, fmt.Errorf("invalid PATROL_BATCH_SIZE: %w", err)
+	}
+
+	if *config.Coolify.Retries < 0 {
+		return nil, fmt.Errorf("invalid PATROL_COOLIFY_RETRIES: must not be negative")
+	}
+	if _, err := time.ParseDuration(config.Coolify.RetryBackoff); err != nil {
+		return nil, fmt.Errorf("invalid PATROL_COOLIFY_RETRY_BACKOFF: %w", err)
+	}
+
+	if err := resolveInstances(&config); err != nil {
+		return nil, err
+	}
+
+	if err := resolveLeader(&config); err != nil {
+		return nil, err
+	}
+
+	if err := resolveBreaker(&config.Breaker); err != nil {
+		return nil, err
+	}
+
+	if err := validateDiscovery(&config.Discovery); err != nil {
+		return nil, err
+	}
+
+	if config.Self.Policy != "" && !IsValidPolicy(config.Self.Policy) {
+		return nil, fmt.Errorf("invalid self policy '%s'", config.Self.Policy)
+	}
 
 	return &config, nil
 }
 
+// resolveLeader fills in the defaults of the leader lock and validates it
+func resolveLeader(config *types.Config) error {
+	leader := &config.Leader
+	if leader.Lock == "" {
+		leader.Lock = types.LockNone
+		if config.StateDir != "" {
+			leader.Lock = types.LockFile
+		}
+	}
+	if leader.TTL == "" {
+		leader.TTL = "30s"
+	}
+	if ttl, err := time.ParseDuration(leader.TTL); err != nil {
+		return fmt.Errorf("invalid PATROL_LEADER_TTL: %w", err)
+	} else if ttl < 3*time.Second {
+		return fmt.Errorf("invalid PATROL_LEADER_TTL '%s': must be at least 3s", leader.TTL)
+	}
+	if leader.ID == "" {
+		// Distinct for a second process in the same container, like a manual check
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "patrol"
+		}
+		leader.ID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	} else if strings.ContainsAny(leader.ID, " \t\n") {
+		return fmt.Errorf("invalid leader id '%s': must not contain whitespace", leader.ID)
+	}
+
+	switch leader.Lock {
+	case types.LockNone:
+	case types.LockFile:
+		if leader.Path == "" {
+			if config.StateDir == "" {
+				return fmt.Errorf("leader file lock needs a path or a state_dir")
+			}
+			leader.Path = filepath.Join(config.StateDir, "patrol.lock")
+		}
+	case types.LockHTTP:
+		if leader.URL == "" {
+			return fmt.Errorf("leader http lock needs a url")
+		}
+	case types.LockCoolify:
+		if leader.App == "" {
+			leader.App = config.Self.UUID
+		}
+		if leader.App == "" {
+			return fmt.Errorf("leader coolify lock needs an app or self.uuid")
+		}
+		if leader.Instance == "" {
+			leader.Instance = config.Instances[0].Name
+		} else if GetInstance(config, leader.Instance) == nil {
+			return fmt.Errorf("leader lock refers to unknown instance '%s'", leader.Instance)
+		}
+	default:
+		return fmt.Errorf("invalid leader lock '%s': use file, http, coolify or none", leader.Lock)
+	}
+	return nil
+}
+
+// DefaultInstanceName is the name given to the instance built from the
+// top-level coolify section when no instances are configured
+const DefaultInstanceName = "default"
+
+// resolveBreaker fills in the defaults of the circuit breakers and validates them
+func resolveBreaker(breaker *types.BreakerConfig) error {
+	if breaker.Threshold == nil {
+		threshold := 3
+		breaker.Threshold = &threshold
+	}
+	if breaker.GlobalThreshold == nil {
+		threshold := 5
+		breaker.GlobalThreshold = &threshold
+	}
+	if breaker.Window == "" {
+		breaker.Window = "24h"
+	}
+	if breaker.Timeout == "" {
+		breaker.Timeout = "6h"
+	}
+
+	if *breaker.Threshold < 0 {
+		return fmt.Errorf("invalid PATROL_BREAKER_THRESHOLD: must not be negative")
+	}
+	if *breaker.GlobalThreshold < 0 {
+		return fmt.Errorf("invalid PATROL_BREAKER_GLOBAL_THRESHOLD: must not be negative")
+	}
+	if window, err := time.ParseDuration(breaker.Window); err != nil || window <= 0 {
+		return fmt.Errorf("invalid PATROL_BREAKER_WINDOW '%s': must be a positive duration", breaker.Window)
+	}
+	if timeout, err := time.ParseDuration(breaker.Timeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid PATROL_BREAKER_TIMEOUT '%s': must be a positive duration", breaker.Timeout)
+	}
+	return nil
+}
+
+// resolveInstances normalizes the Coolify instance list so that it always holds
+// at least one instance with fully merged defaults, and assigns every app to
+// an existing instance
+func resolveInstances(config *types.Config) error {
+	if len(config.Instances) == 0 {
+		config.Instances = []types.CoolifyInstance{{
+			Name:          DefaultInstanceName,
+			CoolifyConfig: config.Coolify,
+		}}
+	}
+
+	seen := make(map[string]bool)
+	for i := range config.Instances {
+		instance := &config.Instances[i]
+		if instance.Name == "" {
+			return fmt.Errorf("instance #%d: name is required", i+1)
+		}
+		if seen[instance.Name] {
+			return fmt.Errorf("duplicate instance name '%s'", instance.Name)
+		}
+		seen[instance.Name] = true
+
+		if instance.URL == "" {
+			return fmt.Errorf("instance '%s': url is required", instance.Name)
+		}
+		if instance.Token == "" {
+			return fmt.Errorf("instance '%s': token is required", instance.Name)
+		}
+
+		// Connection tuning falls back to the top-level coolify section
+		if instance.Retries == nil {
+			instance.Retries = config.Coolify.Retries
+		}
+		if instance.RetryBackoff == "" {
+			instance.RetryBackoff = config.Coolify.RetryBackoff
+		}
+		if *instance.Retries < 0 {
+			return fmt.Errorf("instance '%s': retries must not be negative", instance.Name)
+		}
+		if _, err := time.ParseDuration(instance.RetryBackoff); err != nil {
+			return fmt.Errorf("instance '%s': invalid retry_backoff: %w", instance.Name, err)
+		}
+
+		defaults := MergeDefaults(config.Defaults, instance.Defaults)
+		if defaults.Schedule != "" {
+			parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+			if _, err := parser.Parse(defaults.Schedule); err != nil {
+				return fmt.Errorf("instance '%s': invalid schedule '%s': %w", instance.Name, defaults.Schedule, err)
+			}
+		}
+		if _, err := time.ParseDuration(defaults.Cooldown); err != nil {
+			return fmt.Errorf("instance '%s': invalid cooldown: %w", instance.Name, err)
+		}
+		if _, err := time.ParseDuration(defaults.UpdateDelay); err != nil {
+			return fmt.Errorf("instance '%s': invalid update_delay: %w", instance.Name, err)
+		}
+		if _, err := time.ParseDuration(defaults.MaxBackoff); err != nil {
+			return fmt.Errorf("instance '%s': invalid max_backoff: %w", instance.Name, err)
+		}
+		if _, err := window.ParseAll(defaults.MaintenanceWindows); err != nil {
+			return fmt.Errorf("instance '%s': %w", instance.Name, err)
+		}
+		instance.Defaults = &defaults
+	}
+
+	for i := range config.Apps {
+		app := &config.Apps[i]
+		if app.Name == "" {
+			return fmt.Errorf("app #%d: name is required", i+1)
+		}
+		if app.Dockerfile != "" {
+			app.WatchBaseImages = true
+		}
+		if app.Image == "" && !app.WatchBaseImages {
+			return fmt.Errorf("app '%s': image is required", app.Name)
+		}
+		if app.WatchBaseImages && app.TagEnv != "" {
+			return fmt.Errorf("app '%s': tag_env can't be combined with base image watching", app.Name)
+		}
+		if app.RebuildOnBaseUpdate && !app.WatchBaseImages {
+			return fmt.Errorf("app '%s': rebuild_on_base_update requires watch_base_images or dockerfile", app.Name)
+		}
+		if _, err := window.ParseAll(app.MaintenanceWindows); err != nil {
+			return fmt.Errorf("app '%s': %w", app.Name, err)
+		}
+		if err := hook.ValidateAll(app.Hooks); err != nil {
+			return fmt.Errorf("app '%s': %w", app.Name, err)
+		}
+		if app.BackupBeforeUpdate && app.BackupDatabase == "" {
+			return fmt.Errorf("app '%s': backup_before_update needs backup_database, the UUID of the Coolify database to back up", app.Name)
+		}
+		if app.BackupTimeout != "" {
+			if timeout, err := time.ParseDuration(app.BackupTimeout); err != nil || timeout <= 0 {
+				return fmt.Errorf("app '%s': invalid backup_timeout '%s'", app.Name, app.BackupTimeout)
+			}
+		}
+		switch app.Type {
+		case "", types.ResourceApplication:
+		case types.ResourceService:
+			if app.TagEnv == "" {
+				return fmt.Errorf("app '%s': services need tag_env, they have no single image to update", app.Name)
+			}
+			if app.UUID == "" {
+				return fmt.Errorf("app '%s': services must be configured by uuid", app.Name)
+			}
+		default:
+			return fmt.Errorf("app '%s': invalid type '%s'. Must be: application or service", app.Name, app.Type)
+		}
+		if app.Instance == "" {
+			if len(config.Instances) > 1 {
+				return fmt.Errorf("app '%s': instance is required when several instances are configured", app.Name)
+			}
+			app.Instance = config.Instances[0].Name
+		}
+		if !seen[app.Instance] {
+			return fmt.Errorf("app '%s': unknown instance '%s'", app.Name, app.Instance)
+		}
+
+		defaults := AppDefaults(app, GetInstance(config, app.Instance).Defaults)
+		if _, err := ParseSchedule(&defaults); err != nil {
+			return fmt.Errorf("app '%s': %w", app.Name, err)
+		}
+		if _, err := time.ParseDuration(defaults.Cooldown); err != nil {
+			return fmt.Errorf("app '%s': invalid cooldown: %w", app.Name, err)
+		}
+		if _, err := time.ParseDuration(defaults.UpdateDelay); err != nil {
+			return fmt.Errorf("app '%s': invalid update_delay: %w", app.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// MergeDefaults returns base with every field that is set in override replaced
+func MergeDefaults(base types.DefaultsConfig, override *types.DefaultsConfig) types.DefaultsConfig {
+	merged := base
+	if override == nil {
+		return merged
+	}
+
+	if override.Policy != "" {
+		merged.Policy = override.Policy
+	}
+	if override.Schedule != "" {
+		merged.Schedule = override.Schedule
+	}
+	if override.Interval != "" {
+		merged.Interval = override.Interval
+	}
+	if override.Cooldown != "" {
+		merged.Cooldown = override.Cooldown
+	}
+	if len(override.ExcludePatterns) > 0 {
+		merged.ExcludePatterns = override.ExcludePatterns
+	}
+	if override.UpdateDelay != "" {
+		merged.UpdateDelay = override.UpdateDelay
+	}
+	if override.MaxBackoff != "" {
+		merged.MaxBackoff = override.MaxBackoff
+	}
+	if len(override.MaintenanceWindows) > 0 {
+		merged.MaintenanceWindows = override.MaintenanceWindows
+	}
+
+	return merged
+}
+
+// AppDefaults returns defaults with the overrides set on app applied. An app
+// interval replaces an inherited schedule, as it would be ignored otherwise.
+func AppDefaults(app *types.AppConfig, defaults *types.DefaultsConfig) types.DefaultsConfig {
+	merged := MergeDefaults(*defaults, &types.DefaultsConfig{
+		Policy:             app.Policy,
+		Schedule:           app.Schedule,
+		Interval:           app.Interval,
+		Cooldown:           app.Cooldown,
+		ExcludePatterns:    app.ExcludePatterns,
+		UpdateDelay:        app.UpdateDelay,
+		MaintenanceWindows: app.MaintenanceWindows,
+	})
+	if app.Interval != "" && app.Schedule == "" {
+		merged.Schedule = ""
+	}
+	return merged
+}
+
+// ParseSchedule returns the check schedule of defaults, the cron schedule if
+// set and the interval otherwise
+func ParseSchedule(defaults *types.DefaultsConfig) (cron.Schedule, error) {
+	if defaults.Schedule != "" {
+		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+		schedule, err := parser.Parse(defaults.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", defaults.Schedule, err)
+		}
+		return schedule, nil
+	}
+
+	interval, err := time.ParseDuration(defaults.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval: %w", err)
+	}
+	if interval < time.Second {
+		return nil, fmt.Errorf("invalid interval '%s': must be at least 1s", defaults.Interval)
+	}
+	return cron.Every(interval), nil
+}
+
+// GetInstance returns the instance with the given name, or nil if there is none
+func GetInstance(config *types.Config, name string) *types.CoolifyInstance {
+	for i := range config.Instances {
+		if config.Instances[i].Name == name {
+			return &config.Instances[i]
+		}
+	}
+	return nil
+}
+
 // loadFromEnv loads configuration from environment variables
 func loadFromEnv(config *types.Config) error {
 	// Core Coolify settings (required)
@@ -96,6 +491,16 @@ func loadFromEnv(config *types.Config) error {
 	if token := os.Getenv("COOLIFY_TOKEN"); token != "" {
 		config.Coolify.Token = token
 	}
+	if retries := os.Getenv("PATROL_COOLIFY_RETRIES"); retries != "" {
+		n, err := strconv.Atoi(retries)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_COOLIFY_RETRIES '%s': must be a number", retries)
+		}
+		config.Coolify.Retries = &n
+	}
+	if backoff := os.Getenv("PATROL_COOLIFY_RETRY_BACKOFF"); backoff != "" {
+		config.Coolify.RetryBackoff = backoff
+	}
 
 	// Patrol settings (optional, will use defaults if not set)
 	if schedule := os.Getenv("PATROL_SCHEDULE"); schedule != "" {
@@ -110,6 +515,38 @@ func loadFromEnv(config *types.Config) error {
 	if cooldown := os.Getenv("PATROL_COOLDOWN"); cooldown != "" {
 		config.Defaults.Cooldown = cooldown
 	}
+	if delay := os.Getenv("PATROL_UPDATE_DELAY"); delay != "" {
+		config.Defaults.UpdateDelay = delay
+	}
+	if backoff := os.Getenv("PATROL_MAX_BACKOFF"); backoff != "" {
+		config.Defaults.MaxBackoff = backoff
+	}
+	if concurrency := os.Getenv("PATROL_CHECK_CONCURRENCY"); concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_CHECK_CONCURRENCY '%s': must be a number", concurrency)
+		}
+		config.CheckConcurrency = n
+	}
+
+	// Update limits per cycle
+	if limit := os.Getenv("PATROL_MAX_UPDATES_PER_CYCLE"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_MAX_UPDATES_PER_CYCLE '%s': must be a number", limit)
+		}
+		config.MaxUpdatesPerCycle = n
+	}
+	if limit := os.Getenv("PATROL_MAX_CONCURRENT_PER_SERVER"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_MAX_CONCURRENT_PER_SERVER '%s': must be a number", limit)
+		}
+		config.MaxConcurrentPerServer = n
+	}
+	if size := os.Getenv("PATROL_BATCH_SIZE"); size != "" {
+		config.BatchSize = size
+	}
 
 	// Exclude patterns (comma-separated)
 	if patterns := os.Getenv("PATROL_EXCLUDE_PATTERNS"); patterns != "" {
@@ -120,6 +557,66 @@ func loadFromEnv(config *types.Config) error {
 		}
 	}
 
+	// Patrol's own deployment
+	if uuid := os.Getenv("PATROL_SELF_UUID"); uuid != "" {
+		config.Self.UUID = uuid
+	}
+	if image := os.Getenv("PATROL_SELF_IMAGE"); image != "" {
+		config.Self.Image = image
+	}
+	if update := os.Getenv("PATROL_SELF_UPDATE"); update != "" {
+		config.Self.Update = update == "true"
+	}
+
+	if stateDir := os.Getenv("PATROL_STATE_DIR"); stateDir != "" {
+		config.StateDir = stateDir
+	}
+	if token := os.Getenv("PATROL_API_TOKEN"); token != "" {
+		config.APIToken = token
+	}
+
+	// Leader lock
+	if lock := os.Getenv("PATROL_LEADER_LOCK"); lock != "" {
+		config.Leader.Lock = types.LockKind(lock)
+	}
+	if path := os.Getenv("PATROL_LEADER_PATH"); path != "" {
+		config.Leader.Path = path
+	}
+	if shared := os.Getenv("PATROL_LEADER_SHARED"); shared != "" {
+		config.Leader.Shared = shared == "true"
+	}
+	if url := os.Getenv("PATROL_LEADER_URL"); url != "" {
+		config.Leader.URL = url
+	}
+	if app := os.Getenv("PATROL_LEADER_APP"); app != "" {
+		config.Leader.App = app
+	}
+	if ttl := os.Getenv("PATROL_LEADER_TTL"); ttl != "" {
+		config.Leader.TTL = ttl
+	}
+
+	// Circuit breakers
+	if threshold := os.Getenv("PATROL_BREAKER_THRESHOLD"); threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_BREAKER_THRESHOLD '%s': must be a number", threshold)
+		}
+		config.Breaker.Threshold = &n
+	}
+	if threshold := os.Getenv("PATROL_BREAKER_GLOBAL_THRESHOLD"); threshold != "" {
+		n, err := strconv.Atoi(threshold)
+		if err != nil {
+			return fmt.Errorf("invalid PATROL_BREAKER_GLOBAL_THRESHOLD '%s': must be a number", threshold)
+		}
+		config.Breaker.GlobalThreshold = &n
+	}
+	if window := os.Getenv("PATROL_BREAKER_WINDOW"); window != "" {
+		config.Breaker.Window = window
+	}
+	if timeout := os.Getenv("PATROL_BREAKER_TIMEOUT"); timeout != "" {
+		config.Breaker.Timeout = timeout
+	}
+
 	// Apps configuration - compact format or auto-discovery
 	if appsStr := os.Getenv("PATROL_APPS"); appsStr != "" {
 		apps, err := parseCompactApps(appsStr)
@@ -171,13 +668,11 @@ func parseCompactApps(appsStr string) ([]types.AppConfig, error) {
 
 		// Optional policy (4th field)
 		if len(parts) > 3 && parts[3] != "" {
-			policy := strings.TrimSpace(parts[3])
-			switch policy {
-			case "auto-patch", "auto-minor", "auto-all", "notify-only":
-				app.Policy = types.UpdatePolicy(policy)
-			default:
+			policy := types.UpdatePolicy(strings.TrimSpace(parts[3]))
+			if !IsValidPolicy(policy) {
 				return nil, fmt.Errorf("invalid policy '%s' in spec '%s'. Must be: auto-patch, auto-minor, auto-all, or notify-only", policy, spec)
 			}
+			app.Policy = policy
 		}
 
 		// Optional pin (5th field)
@@ -201,6 +696,22 @@ func LoadFromEnvOnly() (*types.Config, error) {
 	return Load("")
 }
 
+// DefaultSelfImageName is the image name patrol recognizes itself by when
+// neither a self UUID nor a self image is configured
+const DefaultSelfImageName = "coolify-patrol"
+
+// IsSelf reports whether the app with the given UUID and image (without tag)
+// is patrol's own deployment
+func IsSelf(self *types.SelfConfig, uuid, image string) bool {
+	if self.UUID != "" {
+		return uuid == self.UUID
+	}
+	if self.Image != "" {
+		return image == self.Image
+	}
+	return image != "" && path.Base(image) == DefaultSelfImageName
+}
+
 // GetUpdatePolicy returns the effective update policy for an app
 func GetUpdatePolicy(app *types.AppConfig, defaults *types.DefaultsConfig) types.UpdatePolicy {
 	if app.Policy != "" {
@@ -209,6 +720,31 @@ func GetUpdatePolicy(app *types.AppConfig, defaults *types.DefaultsConfig) types
 	return defaults.Policy
 }
 
+// GetMaintenanceWindows returns the effective maintenance windows for an app
+func GetMaintenanceWindows(app *types.AppConfig, defaults *types.DefaultsConfig) []types.MaintenanceWindow {
+	if len(app.MaintenanceWindows) > 0 {
+		return app.MaintenanceWindows
+	}
+	return defaults.MaintenanceWindows
+}
+
+// ParseBatchSize parses a batch size like "10%" into a percentage. An empty
+// value returns 0, no limit.
+func ParseBatchSize(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, ok := strings.CutSuffix(strings.TrimSpace(value), "%")
+	if !ok {
+		return 0, fmt.Errorf("batch size '%s' must be a percentage like 10%%", value)
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("batch size '%s' must be a percentage between 0 and 100%%", value)
+	}
+	return percent, nil
+}
+
 // ParseInterval parses a duration string into time.Duration
 func ParseInterval(interval string) (time.Duration, error) {
 	return time.ParseDuration(interval)
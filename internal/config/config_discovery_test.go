@@ -0,0 +1,251 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestSelectDiscovered(t *testing.T) {
+	discovery := &types.DiscoveryConfig{
+		Include: []types.DiscoveryFilter{
+			{Environment: "production", Policy: types.AutoPatch},
+			{Environment: "staging", Image: "ghcr.io/*/*", Policy: types.AutoMinor},
+			{Environment: "staging", Policy: types.AutoMinor, Pin: "2"},
+		},
+		Exclude: []types.DiscoveryFilter{
+			{Name: "sandbox-*"},
+			{Server: "laptop"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		app            types.CoolifyApplication
+		image          string
+		expectSelected bool
+		expectPolicy   types.UpdatePolicy
+		expectPin      string
+	}{
+		{
+			name:           "production app",
+			app:            types.CoolifyApplication{Name: "n8n", Environment: "production"},
+			image:          "n8nio/n8n",
+			expectSelected: true,
+			expectPolicy:   types.AutoPatch,
+		},
+		{
+			name:           "staging app from GHCR matches first staging filter",
+			app:            types.CoolifyApplication{Name: "plausible", Environment: "staging"},
+			image:          "ghcr.io/plausible/community-edition",
+			expectSelected: true,
+			expectPolicy:   types.AutoMinor,
+		},
+		{
+			name:           "staging app from Docker Hub falls through to pinned filter",
+			app:            types.CoolifyApplication{Name: "redis", Environment: "staging"},
+			image:          "redis",
+			expectSelected: true,
+			expectPolicy:   types.AutoMinor,
+			expectPin:      "2",
+		},
+		{
+			name:           "unmatched environment",
+			app:            types.CoolifyApplication{Name: "n8n", Environment: "development"},
+			image:          "n8nio/n8n",
+			expectSelected: false,
+		},
+		{
+			name:           "excluded by name",
+			app:            types.CoolifyApplication{Name: "sandbox-chris", Environment: "production"},
+			image:          "nginx",
+			expectSelected: false,
+		},
+		{
+			name:           "excluded by server",
+			app:            types.CoolifyApplication{Name: "n8n", Environment: "production", Server: "laptop"},
+			image:          "n8nio/n8n",
+			expectSelected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, selected := SelectDiscovered(discovery, tt.app, tt.image)
+			if selected != tt.expectSelected {
+				t.Fatalf("expected selected=%v, got %v", tt.expectSelected, selected)
+			}
+			if !selected {
+				return
+			}
+			if filter == nil {
+				t.Fatal("expected matching filter, got nil")
+			}
+			if filter.Policy != tt.expectPolicy {
+				t.Errorf("expected policy '%s', got '%s'", tt.expectPolicy, filter.Policy)
+			}
+			if filter.Pin != tt.expectPin {
+				t.Errorf("expected pin '%s', got '%s'", tt.expectPin, filter.Pin)
+			}
+		})
+	}
+}
+
+func TestSelectDiscoveredWithoutFilters(t *testing.T) {
+	filter, selected := SelectDiscovered(&types.DiscoveryConfig{}, types.CoolifyApplication{Name: "n8n"}, "n8nio/n8n")
+	if !selected {
+		t.Error("expected app to be selected when no filters are configured")
+	}
+	if filter != nil {
+		t.Errorf("expected no filter, got %+v", filter)
+	}
+}
+
+func TestDiscoveryNeedsLocations(t *testing.T) {
+	if DiscoveryNeedsLocations(&types.DiscoveryConfig{
+		Include: []types.DiscoveryFilter{{Name: "n8n*", Image: "n8nio/*"}},
+	}) {
+		t.Error("name and image filters should not need locations")
+	}
+
+	if !DiscoveryNeedsLocations(&types.DiscoveryConfig{
+		Exclude: []types.DiscoveryFilter{{Project: "personal"}},
+	}) {
+		t.Error("project filter should need locations")
+	}
+}
+
+func TestLoadDiscoveryValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		discovery string
+		expectErr string
+	}{
+		{
+			name: "valid filters",
+			discovery: `
+discovery:
+  include:
+    - environment: production
+      policy: auto-patch
+    - environment: staging
+      policy: auto-minor
+      pin: "17"
+  exclude:
+    - name: "sandbox-*"
+`,
+		},
+		{
+			name: "invalid pattern",
+			discovery: `
+discovery:
+  include:
+    - name: "[n8n"
+`,
+			expectErr: "invalid pattern",
+		},
+		{
+			name: "invalid policy",
+			discovery: `
+discovery:
+  include:
+    - environment: production
+      policy: yolo
+`,
+			expectErr: "invalid policy 'yolo'",
+		},
+		{
+			name: "invalid pin",
+			discovery: `
+discovery:
+  include:
+    - environment: production
+      pin: latest
+`,
+			expectErr: "invalid pin 'latest'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+` + tt.discovery
+
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.expectErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(cfg.Discovery.Include) != 2 || len(cfg.Discovery.Exclude) != 1 {
+					t.Errorf("expected 2 include and 1 exclude filters, got %+v", cfg.Discovery)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("expected error containing '%s', got nil", tt.expectErr)
+			}
+			if !strings.Contains(err.Error(), tt.expectErr) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.expectErr, err.Error())
+			}
+		})
+	}
+}
+
+func TestApplyAnnotations(t *testing.T) {
+	app := types.AppConfig{Name: "postgres", Policy: types.AutoMinor}
+	enable, err := ApplyAnnotations(&app, map[string]string{
+		"patrol.policy": "auto-patch",
+		"patrol.pin":    "17",
+		"patrol.enable": "true",
+		"patrol.labels": "production, shop,",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if enable == nil || !*enable {
+		t.Errorf("expected enable=true, got %v", enable)
+	}
+	if app.Policy != types.AutoPatch {
+		t.Errorf("expected policy 'auto-patch', got '%s'", app.Policy)
+	}
+	if app.Pin != "17" {
+		t.Errorf("expected pin '17', got '%s'", app.Pin)
+	}
+	if len(app.Labels) != 2 || app.Labels[0] != "production" || app.Labels[1] != "shop" {
+		t.Errorf("expected labels [production shop], got %v", app.Labels)
+	}
+
+	app = types.AppConfig{Name: "redis", Policy: types.AutoMinor}
+	enable, err = ApplyAnnotations(&app, map[string]string{
+		"patrol.policy": "yolo",
+		"patrol.pin":    "7",
+		"patrol.color":  "blue",
+	})
+	if err == nil {
+		t.Fatal("expected error for invalid annotations, got nil")
+	}
+	if !strings.Contains(err.Error(), "invalid patrol.policy 'yolo'") || !strings.Contains(err.Error(), "unknown annotation patrol.color") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if enable != nil {
+		t.Errorf("expected no enable annotation, got %v", *enable)
+	}
+	if app.Policy != types.AutoMinor {
+		t.Errorf("expected invalid policy to be ignored, got '%s'", app.Policy)
+	}
+	if app.Pin != "7" {
+		t.Errorf("expected valid pin to be applied, got '%s'", app.Pin)
+	}
+}
@@ -377,4 +377,387 @@ func TestLoadMissingRequired(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestLoadFromEnvWithRetries(t *testing.T) {
+	// Clean environment
+	cleanEnv := func() {
+		os.Unsetenv("COOLIFY_URL")
+		os.Unsetenv("COOLIFY_TOKEN")
+		os.Unsetenv("PATROL_COOLIFY_RETRIES")
+		os.Unsetenv("PATROL_COOLIFY_RETRY_BACKOFF")
+	}
+	
+	defer cleanEnv()
+	cleanEnv() // Clean before test
+
+	os.Setenv("COOLIFY_URL", "http://localhost:8000")
+	os.Setenv("COOLIFY_TOKEN", "test-token")
+
+	// Defaults
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.Coolify.Retries == nil || *cfg.Coolify.Retries != 3 {
+		t.Errorf("expected default retries 3, got %v", cfg.Coolify.Retries)
+	}
+	if cfg.Coolify.RetryBackoff != "1s" {
+		t.Errorf("expected default retry backoff '1s', got '%s'", cfg.Coolify.RetryBackoff)
+	}
+
+	// Disabling retries must be possible
+	os.Setenv("PATROL_COOLIFY_RETRIES", "0")
+	os.Setenv("PATROL_COOLIFY_RETRY_BACKOFF", "250ms")
+
+	cfg, err = LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if *cfg.Coolify.Retries != 0 {
+		t.Errorf("expected retries 0, got %d", *cfg.Coolify.Retries)
+	}
+	if cfg.Coolify.RetryBackoff != "250ms" {
+		t.Errorf("expected retry backoff '250ms', got '%s'", cfg.Coolify.RetryBackoff)
+	}
+
+	// Invalid values
+	os.Setenv("PATROL_COOLIFY_RETRIES", "-1")
+	if _, err := LoadFromEnvOnly(); err == nil {
+		t.Error("expected error for negative retries, got nil")
+	}
+
+	os.Setenv("PATROL_COOLIFY_RETRIES", "3")
+	os.Setenv("PATROL_COOLIFY_RETRY_BACKOFF", "soon")
+	if _, err := LoadFromEnvOnly(); err == nil {
+		t.Error("expected error for invalid retry backoff, got nil")
+	}
+}
+
+func TestLoadFromEnvWithSelf(t *testing.T) {
+	// Clean environment
+	cleanEnv := func() {
+		os.Unsetenv("COOLIFY_URL")
+		os.Unsetenv("COOLIFY_TOKEN")
+		os.Unsetenv("PATROL_SELF_UUID")
+		os.Unsetenv("PATROL_SELF_IMAGE")
+		os.Unsetenv("PATROL_SELF_UPDATE")
+	}
+	
+	defer cleanEnv()
+	cleanEnv() // Clean before test
+
+	os.Setenv("COOLIFY_URL", "http://localhost:8000")
+	os.Setenv("COOLIFY_TOKEN", "test-token")
+	os.Setenv("PATROL_SELF_UUID", "patrol-uuid")
+	os.Setenv("PATROL_SELF_UPDATE", "true")
+
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+
+	if cfg.Self.UUID != "patrol-uuid" {
+		t.Errorf("expected self uuid 'patrol-uuid', got '%s'", cfg.Self.UUID)
+	}
+	if !cfg.Self.Update {
+		t.Error("expected self update to be enabled")
+	}
+}
+
+func TestLoadFromEnvWithStateDir(t *testing.T) {
+	// Clean environment
+	cleanEnv := func() {
+		os.Unsetenv("COOLIFY_URL")
+		os.Unsetenv("COOLIFY_TOKEN")
+		os.Unsetenv("PATROL_STATE_DIR")
+	}
+	
+	defer cleanEnv()
+	cleanEnv() // Clean before test
+
+	os.Setenv("COOLIFY_URL", "http://localhost:8000")
+	os.Setenv("COOLIFY_TOKEN", "test-token")
+
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.StateDir != "" {
+		t.Errorf("expected no state dir by default, got '%s'", cfg.StateDir)
+	}
+
+	os.Setenv("PATROL_STATE_DIR", "/data")
+	cfg, err = LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.StateDir != "/data" {
+		t.Errorf("expected state dir '/data', got '%s'", cfg.StateDir)
+	}
+}
+
+func TestLoadFromEnvWithConcurrency(t *testing.T) {
+	// Clean environment
+	cleanEnv := func() {
+		os.Unsetenv("COOLIFY_URL")
+		os.Unsetenv("COOLIFY_TOKEN")
+		os.Unsetenv("PATROL_UPDATE_DELAY")
+		os.Unsetenv("PATROL_CHECK_CONCURRENCY")
+	}
+	
+	defer cleanEnv()
+	cleanEnv() // Clean before test
+
+	os.Setenv("COOLIFY_URL", "http://localhost:8000")
+	os.Setenv("COOLIFY_TOKEN", "test-token")
+
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.Defaults.UpdateDelay != "30s" {
+		t.Errorf("expected default update delay '30s', got '%s'", cfg.Defaults.UpdateDelay)
+	}
+	if cfg.CheckConcurrency != 4 || cfg.RegistryConcurrency != 2 {
+		t.Errorf("expected default concurrency 4/2, got %d/%d", cfg.CheckConcurrency, cfg.RegistryConcurrency)
+	}
+
+	os.Setenv("PATROL_UPDATE_DELAY", "5s")
+	os.Setenv("PATROL_CHECK_CONCURRENCY", "8")
+	cfg, err = LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.Defaults.UpdateDelay != "5s" {
+		t.Errorf("expected update delay '5s', got '%s'", cfg.Defaults.UpdateDelay)
+	}
+	if cfg.CheckConcurrency != 8 {
+		t.Errorf("expected check concurrency 8, got %d", cfg.CheckConcurrency)
+	}
+
+	os.Setenv("PATROL_UPDATE_DELAY", "later")
+	if _, err := LoadFromEnvOnly(); err == nil {
+		t.Error("expected error for invalid update delay, got nil")
+	}
+
+	os.Setenv("PATROL_UPDATE_DELAY", "5s")
+	os.Setenv("PATROL_CHECK_CONCURRENCY", "-1")
+	if _, err := LoadFromEnvOnly(); err == nil {
+		t.Error("expected error for invalid check concurrency, got nil")
+	}
+}
+
+func TestLoadFromEnvWithMaxBackoff(t *testing.T) {
+	t.Setenv("COOLIFY_URL", "http://localhost:8000")
+	t.Setenv("COOLIFY_TOKEN", "test-token")
+
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.Defaults.MaxBackoff != "24h" {
+		t.Errorf("expected default max backoff '24h', got '%s'", cfg.Defaults.MaxBackoff)
+	}
+
+	t.Setenv("PATROL_MAX_BACKOFF", "6h")
+	cfg, err = LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.Instances[0].Defaults.MaxBackoff != "6h" {
+		t.Errorf("expected max backoff '6h', got '%s'", cfg.Instances[0].Defaults.MaxBackoff)
+	}
+
+	t.Setenv("PATROL_MAX_BACKOFF", "forever")
+	if _, err := LoadFromEnvOnly(); err == nil {
+		t.Error("expected error for invalid max backoff, got nil")
+	}
+}
+
+func TestLoadFromEnvWithLeader(t *testing.T) {
+	t.Setenv("COOLIFY_URL", "http://localhost:8000")
+	t.Setenv("COOLIFY_TOKEN", "test-token")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    types.LeaderConfig
+		wantErr bool
+	}{
+		{
+			name: "no lock without state dir",
+			want: types.LeaderConfig{Lock: types.LockNone},
+		},
+		{
+			name: "file lock in state dir",
+			env:  map[string]string{"PATROL_STATE_DIR": "/data"},
+			want: types.LeaderConfig{Lock: types.LockFile, Path: "/data/patrol.lock"},
+		},
+		{
+			name: "file lock on shared volume",
+			env:  map[string]string{"PATROL_STATE_DIR": "/data", "PATROL_LEADER_SHARED": "true"},
+			want: types.LeaderConfig{Lock: types.LockFile, Path: "/data/patrol.lock", Shared: true},
+		},
+		{
+			name: "http lock",
+			env:  map[string]string{"PATROL_LEADER_LOCK": "http", "PATROL_LEADER_URL": "https://locks.example.com/patrol", "PATROL_LEADER_TTL": "1m"},
+			want: types.LeaderConfig{Lock: types.LockHTTP, URL: "https://locks.example.com/patrol", TTL: "1m"},
+		},
+		{
+			name: "coolify lock on own app",
+			env:  map[string]string{"PATROL_LEADER_LOCK": "coolify", "PATROL_SELF_UUID": "patrol-uuid"},
+			want: types.LeaderConfig{Lock: types.LockCoolify, App: "patrol-uuid", Instance: DefaultInstanceName},
+		},
+		{
+			name:    "http lock without url",
+			env:     map[string]string{"PATROL_LEADER_LOCK": "http"},
+			wantErr: true,
+		},
+		{
+			name:    "coolify lock without app",
+			env:     map[string]string{"PATROL_LEADER_LOCK": "coolify"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown lock",
+			env:     map[string]string{"PATROL_LEADER_LOCK": "redis"},
+			wantErr: true,
+		},
+		{
+			name:    "short ttl",
+			env:     map[string]string{"PATROL_LEADER_LOCK": "http", "PATROL_LEADER_URL": "https://locks.example.com/patrol", "PATROL_LEADER_TTL": "1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := LoadFromEnvOnly()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load from env: %v", err)
+			}
+
+			if tt.want.TTL == "" {
+				tt.want.TTL = "30s"
+			}
+			leader := cfg.Leader
+			if leader.ID == "" {
+				t.Error("expected a default leader id")
+			}
+			leader.ID = ""
+			if leader != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, leader)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvWithBreaker(t *testing.T) {
+	t.Setenv("COOLIFY_URL", "http://localhost:8000")
+	t.Setenv("COOLIFY_TOKEN", "test-token")
+
+	tests := []struct {
+		name                       string
+		env                        map[string]string
+		threshold, globalThreshold int
+		window, timeout            string
+		wantErr                    bool
+	}{
+		{
+			name:            "defaults",
+			threshold:       3,
+			globalThreshold: 5,
+			window:          "24h",
+			timeout:         "6h",
+		},
+		{
+			name:            "configured",
+			env:             map[string]string{"PATROL_BREAKER_THRESHOLD": "2", "PATROL_BREAKER_GLOBAL_THRESHOLD": "0", "PATROL_BREAKER_WINDOW": "1h", "PATROL_BREAKER_TIMEOUT": "30m"},
+			threshold:       2,
+			globalThreshold: 0,
+			window:          "1h",
+			timeout:         "30m",
+		},
+		{
+			name:    "negative threshold",
+			env:     map[string]string{"PATROL_BREAKER_THRESHOLD": "-1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid timeout",
+			env:     map[string]string{"PATROL_BREAKER_TIMEOUT": "soon"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, err := LoadFromEnvOnly()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to load from env: %v", err)
+			}
+
+			breaker := cfg.Breaker
+			if *breaker.Threshold != tt.threshold || *breaker.GlobalThreshold != tt.globalThreshold || breaker.Window != tt.window || breaker.Timeout != tt.timeout {
+				t.Errorf("unexpected breaker config: threshold %d, global threshold %d, window %s, timeout %s",
+					*breaker.Threshold, *breaker.GlobalThreshold, breaker.Window, breaker.Timeout)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvWithUpdateLimits(t *testing.T) {
+	t.Setenv("COOLIFY_URL", "http://localhost:8000")
+	t.Setenv("COOLIFY_TOKEN", "test-token")
+	t.Setenv("PATROL_MAX_UPDATES_PER_CYCLE", "5")
+	t.Setenv("PATROL_MAX_CONCURRENT_PER_SERVER", "2")
+	t.Setenv("PATROL_BATCH_SIZE", "10%")
+
+	cfg, err := LoadFromEnvOnly()
+	if err != nil {
+		t.Fatalf("failed to load from env: %v", err)
+	}
+	if cfg.MaxUpdatesPerCycle != 5 || cfg.MaxConcurrentPerServer != 2 || cfg.BatchSize != "10%" {
+		t.Errorf("unexpected update limits %d/%d/%s", cfg.MaxUpdatesPerCycle, cfg.MaxConcurrentPerServer, cfg.BatchSize)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{name: "negative cycle limit", key: "PATROL_MAX_UPDATES_PER_CYCLE", value: "-1"},
+		{name: "server limit not a number", key: "PATROL_MAX_CONCURRENT_PER_SERVER", value: "two"},
+		{name: "batch size without percent", key: "PATROL_BATCH_SIZE", value: "10"},
+		{name: "batch size over 100%", key: "PATROL_BATCH_SIZE", value: "150%"},
+		{name: "zero batch size", key: "PATROL_BATCH_SIZE", value: "0%"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			if _, err := LoadFromEnvOnly(); err == nil {
+				t.Errorf("expected error for %s=%s, got nil", tt.key, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestLoadSingleInstance(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "patrol.yaml")
+
+	configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(cfg.Instances) != 1 {
+		t.Fatalf("expected 1 instance, got %d", len(cfg.Instances))
+	}
+
+	instance := cfg.Instances[0]
+	if instance.Name != DefaultInstanceName {
+		t.Errorf("expected instance name '%s', got '%s'", DefaultInstanceName, instance.Name)
+	}
+	if instance.URL != "http://localhost:8000" {
+		t.Errorf("expected URL 'http://localhost:8000', got '%s'", instance.URL)
+	}
+	if instance.Defaults == nil || instance.Defaults.Policy != types.AutoPatch {
+		t.Errorf("expected instance defaults to inherit policy 'auto-patch', got %+v", instance.Defaults)
+	}
+
+	if cfg.Apps[0].Instance != DefaultInstanceName {
+		t.Errorf("expected app instance '%s', got '%s'", DefaultInstanceName, cfg.Apps[0].Instance)
+	}
+}
+
+func TestLoadMultipleInstances(t *testing.T) {
+	tempDir := t.TempDir()
+	configFile := filepath.Join(tempDir, "patrol.yaml")
+
+	configContent := `
+defaults:
+  policy: auto-patch
+  cooldown: 1h
+
+instances:
+  - name: prod
+    url: https://prod.example.com
+    token: prod-token
+  - name: staging
+    url: https://staging.example.com
+    token: staging-token
+    retries: 0
+    defaults:
+      policy: auto-minor
+      cooldown: 10m
+
+apps:
+  - name: n8n
+    uuid: n8n-prod
+    image: n8nio/n8n
+    instance: prod
+  - name: n8n
+    uuid: n8n-staging
+    image: n8nio/n8n
+    instance: staging
+`
+
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(cfg.Instances) != 2 {
+		t.Fatalf("expected 2 instances, got %d", len(cfg.Instances))
+	}
+
+	prod := GetInstance(cfg, "prod")
+	if prod == nil {
+		t.Fatal("expected instance 'prod'")
+	}
+	if prod.Defaults.Policy != types.AutoPatch {
+		t.Errorf("expected prod policy 'auto-patch', got '%s'", prod.Defaults.Policy)
+	}
+	if *prod.Retries != 3 {
+		t.Errorf("expected prod to inherit 3 retries, got %d", *prod.Retries)
+	}
+
+	staging := GetInstance(cfg, "staging")
+	if staging == nil {
+		t.Fatal("expected instance 'staging'")
+	}
+	if staging.Defaults.Policy != types.AutoMinor {
+		t.Errorf("expected staging policy 'auto-minor', got '%s'", staging.Defaults.Policy)
+	}
+	if staging.Defaults.Cooldown != "10m" {
+		t.Errorf("expected staging cooldown '10m', got '%s'", staging.Defaults.Cooldown)
+	}
+	if staging.Defaults.Interval != "15m" {
+		t.Errorf("expected staging to inherit interval '15m', got '%s'", staging.Defaults.Interval)
+	}
+	if *staging.Retries != 0 {
+		t.Errorf("expected staging retries 0, got %d", *staging.Retries)
+	}
+
+	if GetInstance(cfg, "eu") != nil {
+		t.Error("expected no instance 'eu'")
+	}
+}
+
+func TestLoadInstanceValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		expectErr string
+	}{
+		{
+			name: "missing instance name",
+			config: `
+instances:
+  - url: https://prod.example.com
+    token: prod-token
+`,
+			expectErr: "name is required",
+		},
+		{
+			name: "duplicate instance name",
+			config: `
+instances:
+  - name: prod
+    url: https://prod.example.com
+    token: prod-token
+  - name: prod
+    url: https://prod2.example.com
+    token: prod-token
+`,
+			expectErr: "duplicate instance name",
+		},
+		{
+			name: "missing token",
+			config: `
+instances:
+  - name: prod
+    url: https://prod.example.com
+`,
+			expectErr: "token is required",
+		},
+		{
+			name: "app without instance",
+			config: `
+instances:
+  - name: prod
+    url: https://prod.example.com
+    token: prod-token
+  - name: staging
+    url: https://staging.example.com
+    token: staging-token
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+`,
+			expectErr: "instance is required",
+		},
+		{
+			name: "app with unknown instance",
+			config: `
+instances:
+  - name: prod
+    url: https://prod.example.com
+    token: prod-token
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    instance: eu
+`,
+			expectErr: "unknown instance 'eu'",
+		},
+		{
+			name: "invalid instance cooldown",
+			config: `
+instances:
+  - name: prod
+    url: https://prod.example.com
+    token: prod-token
+    defaults:
+      cooldown: forever
+`,
+			expectErr: "invalid cooldown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			_, err := Load(configFile)
+			if err == nil {
+				t.Fatalf("expected error containing '%s', got nil", tt.expectErr)
+			}
+			if !strings.Contains(err.Error(), tt.expectErr) {
+				t.Errorf("expected error containing '%s', got '%s'", tt.expectErr, err.Error())
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -171,6 +172,36 @@ coolify:
   token: test-token
 defaults:
   cooldown: invalid
+`,
+			expectError: true,
+		},
+		{
+			name: "backup without database",
+			config: `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+apps:
+  - name: postgres
+    uuid: pg-uuid
+    image: postgres
+    backup_before_update: true
+`,
+			expectError: true,
+		},
+		{
+			name: "invalid backup timeout",
+			config: `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+apps:
+  - name: postgres
+    uuid: pg-uuid
+    image: postgres
+    backup_before_update: true
+    backup_database: db-uuid
+    backup_timeout: 0s
 `,
 			expectError: true,
 		},
@@ -316,4 +347,468 @@ defaults:
 	if cfg.Coolify.Token != "secret-token" {
 		t.Errorf("expected token 'secret-token', got '%s'", cfg.Coolify.Token)
 	}
-}
\ No newline at end of file
+}
+func TestLoadAppsByName(t *testing.T) {
+	tests := []struct {
+		name      string
+		apps      string
+		expectErr string
+	}{
+		{
+			name: "name without uuid",
+			apps: `
+apps:
+  - name: n8n
+    environment: production
+    image: n8nio/n8n
+`,
+		},
+		{
+			name: "missing name",
+			apps: `
+apps:
+  - uuid: n8n-uuid
+    image: n8nio/n8n
+`,
+			expectErr: "app #1: name is required",
+		},
+		{
+			name: "missing image",
+			apps: `
+apps:
+  - name: n8n
+`,
+			expectErr: "app 'n8n': image is required",
+		},
+		{
+			name: "service without tag_env",
+			apps: `
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    type: service
+`,
+			expectErr: "app 'n8n': services need tag_env, they have no single image to update",
+		},
+		{
+			name: "invalid type",
+			apps: `
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    type: database
+`,
+			expectErr: "app 'n8n': invalid type 'database'. Must be: application or service",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+` + tt.apps
+
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Errorf("expected error '%s', got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Apps[0].UUID != "" {
+				t.Errorf("expected empty uuid, got '%s'", cfg.Apps[0].UUID)
+			}
+			if cfg.Apps[0].Environment != "production" {
+				t.Errorf("expected environment 'production', got '%s'", cfg.Apps[0].Environment)
+			}
+		})
+	}
+}
+
+func TestLoadBaseImageApps(t *testing.T) {
+	tests := []struct {
+		name        string
+		apps        string
+		expectWatch bool
+		expectErr   string
+	}{
+		{
+			name: "watch without image",
+			apps: `
+apps:
+  - name: api
+    uuid: api-uuid
+    watch_base_images: true
+    rebuild_on_base_update: true
+`,
+			expectWatch: true,
+		},
+		{
+			name: "dockerfile implies watch",
+			apps: `
+apps:
+  - name: api
+    uuid: api-uuid
+    dockerfile: ./api
+`,
+			expectWatch: true,
+		},
+		{
+			name: "combined with tag_env",
+			apps: `
+apps:
+  - name: api
+    uuid: api-uuid
+    watch_base_images: true
+    tag_env: API_TAG
+`,
+			expectErr: "app 'api': tag_env can't be combined with base image watching",
+		},
+		{
+			name: "rebuild without watch",
+			apps: `
+apps:
+  - name: api
+    uuid: api-uuid
+    image: ghcr.io/acme/api
+    rebuild_on_base_update: true
+`,
+			expectErr: "app 'api': rebuild_on_base_update requires watch_base_images or dockerfile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+` + tt.apps
+
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Errorf("expected error '%s', got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Apps[0].WatchBaseImages != tt.expectWatch {
+				t.Errorf("expected watch_base_images %v, got %v", tt.expectWatch, cfg.Apps[0].WatchBaseImages)
+			}
+		})
+	}
+}
+
+func TestLoadMaintenanceWindows(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		expectErr string
+	}{
+		{
+			name: "defaults and app",
+			config: `
+defaults:
+  maintenance_windows:
+    - days: mon-fri
+      start: "02:00"
+      end: "05:00"
+      timezone: Europe/Berlin
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    maintenance_windows:
+      - schedule: "0 3 * * sun"
+        duration: 2h
+`,
+		},
+		{
+			name: "invalid default window",
+			config: `
+defaults:
+  maintenance_windows:
+    - start: "02:00"
+`,
+			expectErr: "defaults: maintenance window #1: start and end are required",
+		},
+		{
+			name: "invalid app window",
+			config: `
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    maintenance_windows:
+      - days: weekdays
+        start: "02:00"
+        end: "05:00"
+`,
+			expectErr: "app 'n8n': maintenance window #1: invalid day 'weekdays': use mon, tue, wed, thu, fri, sat or sun",
+		},
+		{
+			name: "invalid freeze",
+			config: `
+freezes:
+  - name: black-friday
+    start: "2026-11-30"
+    end: "2026-11-20"
+`,
+			expectErr: "freeze #1: end must be after start",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+` + tt.config
+
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Errorf("expected error '%s', got %v", tt.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defaults := cfg.Instances[0].Defaults
+			if len(defaults.MaintenanceWindows) != 1 {
+				t.Fatalf("expected instance defaults to inherit 1 window, got %d", len(defaults.MaintenanceWindows))
+			}
+			if windows := GetMaintenanceWindows(&cfg.Apps[0], defaults); len(windows) != 1 || windows[0].Schedule != "0 3 * * sun" {
+				t.Errorf("expected the app's own window, got %+v", windows)
+			}
+		})
+	}
+}
+
+func TestIsSelf(t *testing.T) {
+	tests := []struct {
+		name     string
+		self     types.SelfConfig
+		uuid     string
+		image    string
+		expected bool
+	}{
+		{"default image name", types.SelfConfig{}, "abc", "ghcr.io/chrisdietr/coolify-patrol", true},
+		{"default image name on docker hub", types.SelfConfig{}, "abc", "coolify-patrol", true},
+		{"other image", types.SelfConfig{}, "abc", "n8nio/n8n", false},
+		{"empty image", types.SelfConfig{}, "abc", "", false},
+		{"configured uuid", types.SelfConfig{UUID: "abc"}, "abc", "", true},
+		{"configured uuid wins over image", types.SelfConfig{UUID: "abc"}, "def", "coolify-patrol", false},
+		{"configured image", types.SelfConfig{Image: "registry.example.com/ops/patrol"}, "abc", "registry.example.com/ops/patrol", true},
+		{"configured image replaces default", types.SelfConfig{Image: "registry.example.com/ops/patrol"}, "abc", "coolify-patrol", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := IsSelf(&tt.self, tt.uuid, tt.image); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoadFreezes(t *testing.T) {
+	configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+api_token: secret
+freezes:
+  - name: black-friday
+    start: "2026-11-20"
+    end: "2026-11-30"
+    timezone: Europe/Berlin
+  - name: release
+    start: "2026-06-01T18:00:00+02:00"
+    end: "2026-06-02T06:00:00+02:00"
+    labels: [production]
+`
+	configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+	if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Freezes) != 2 || cfg.Freezes[1].Labels[0] != "production" {
+		t.Errorf("expected 2 freezes, got %+v", cfg.Freezes)
+	}
+	if cfg.APIToken != "secret" {
+		t.Errorf("expected api token 'secret', got '%s'", cfg.APIToken)
+	}
+}
+
+func TestLoadAppOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		app       string
+		expectErr string
+	}{
+		{
+			name: "valid overrides",
+			app: `
+    interval: 1h
+    cooldown: 24h
+    update_delay: 2m
+    exclude_patterns: ["-beta"]
+`,
+		},
+		{name: "valid schedule", app: "\n    schedule: \"0 3 * * *\"\n"},
+		{name: "invalid schedule", app: "\n    schedule: \"every night\"\n", expectErr: "app 'n8n': invalid schedule 'every night'"},
+		{name: "invalid interval", app: "\n    interval: often\n", expectErr: "app 'n8n': invalid interval"},
+		{name: "interval too short", app: "\n    interval: 10ms\n", expectErr: "app 'n8n': invalid interval '10ms': must be at least 1s"},
+		{name: "invalid cooldown", app: "\n    cooldown: forever\n", expectErr: "app 'n8n': invalid cooldown"},
+		{name: "invalid update delay", app: "\n    update_delay: soon\n", expectErr: "app 'n8n': invalid update_delay"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := `
+coolify:
+  url: http://localhost:8000
+  token: test-token
+defaults:
+  schedule: "*/15 * * * *"
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n` + tt.app
+
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.expectErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.expectErr) {
+					t.Errorf("expected error starting with '%s', got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			defaults := AppDefaults(&cfg.Apps[0], cfg.Instances[0].Defaults)
+			if _, err := ParseSchedule(&defaults); err != nil {
+				t.Errorf("unexpected schedule error: %v", err)
+			}
+			if cfg.Apps[0].Interval != "" && defaults.Schedule != "" {
+				t.Errorf("expected app interval to replace the default schedule, got '%s'", defaults.Schedule)
+			}
+		})
+	}
+}
+
+func TestLoadHooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		hooks   string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			hooks: `
+hooks:
+  on_failure:
+    - url: https://alerts.example.com/patrol
+      body: '{"text": "{{.App}} failed to update to {{.ToTag}}"}'
+apps:
+  - name: postgres
+    uuid: pg-uuid
+    image: postgres
+    hooks:
+      pre_update:
+        - name: backup
+          command: /scripts/backup.sh
+          env:
+            BACKUP_NAME: "{{.App}}-{{.FromTag}}"
+          timeout: 30m
+`,
+		},
+		{
+			name: "invalid global hook",
+			hooks: `
+hooks:
+  post_update:
+    - name: nothing
+`,
+			wantErr: "post_update hook #1: needs either a command or a url",
+		},
+		{
+			name: "invalid app hook",
+			hooks: `
+apps:
+  - name: n8n
+    uuid: n8n-uuid
+    image: n8nio/n8n
+    hooks:
+      pre_update:
+        - url: "https://example.com/{{.Tag}}"
+`,
+			wantErr: "app 'n8n': pre_update hook #1: invalid template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configContent := "coolify:\n  url: http://localhost:8000\n  token: test-token\n" + tt.hooks
+			configFile := filepath.Join(t.TempDir(), "patrol.yaml")
+			if err := os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			cfg, err := Load(configFile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing '%s', got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(cfg.Hooks.OnFailure) != 1 || cfg.Apps[0].Hooks.PreUpdate[0].Env["BACKUP_NAME"] != "{{.App}}-{{.FromTag}}" {
+				t.Errorf("unexpected hooks %+v, %+v", cfg.Hooks, cfg.Apps[0].Hooks)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Diff describes the differences between two loaded configurations, one
+// line per changed setting, like "app 'n8n': policy: auto-patch -> auto-minor".
+// Secrets, and hooks which may carry some in headers, are reported as changed
+// without their values.
+func Diff(old, new *types.Config) []string {
+	var changes []string
+
+	// Instances and apps are compared by name, the remaining settings field by field
+	oldInstances := make(map[string]types.CoolifyInstance)
+	for _, instance := range old.Instances {
+		oldInstances[instance.Name] = instance
+	}
+	newInstances := make(map[string]bool)
+	for _, instance := range new.Instances {
+		newInstances[instance.Name] = true
+		previous, ok := oldInstances[instance.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("instance '%s' added", instance.Name))
+			continue
+		}
+		changes = append(changes, diffValues(fmt.Sprintf("instance '%s': ", instance.Name), "", reflect.ValueOf(previous), reflect.ValueOf(instance))...)
+	}
+	for _, instance := range old.Instances {
+		if !newInstances[instance.Name] {
+			changes = append(changes, fmt.Sprintf("instance '%s' removed", instance.Name))
+		}
+	}
+
+	oldApps := make(map[string]types.AppConfig)
+	for _, app := range old.Apps {
+		oldApps[app.Instance+"/"+app.Name] = app
+	}
+	newApps := make(map[string]bool)
+	for _, app := range new.Apps {
+		key := app.Instance + "/" + app.Name
+		newApps[key] = true
+		previous, ok := oldApps[key]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("app '%s' added", app.Name))
+			continue
+		}
+		changes = append(changes, diffValues(fmt.Sprintf("app '%s': ", app.Name), "", reflect.ValueOf(previous), reflect.ValueOf(app))...)
+	}
+	for _, app := range old.Apps {
+		if !newApps[app.Instance+"/"+app.Name] {
+			changes = append(changes, fmt.Sprintf("app '%s' removed", app.Name))
+		}
+	}
+
+	oldRest, newRest := *old, *new
+	oldRest.Instances, newRest.Instances = nil, nil
+	oldRest.Apps, newRest.Apps = nil, nil
+	oldRest.Coolify, newRest.Coolify = types.CoolifyConfig{}, types.CoolifyConfig{} // Copied into the instances
+	changes = append(changes, diffValues("", "", reflect.ValueOf(oldRest), reflect.ValueOf(newRest))...)
+
+	return changes
+}
+
+// diffValues compares two values of the same type at path, descending into
+// structs whose fields are named by their YAML keys
+func diffValues(prefix, path string, old, new reflect.Value) []string {
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return nil
+	}
+
+	if old.Kind() == reflect.Pointer {
+		if old.IsNil() || new.IsNil() {
+			return []string{describeChange(prefix, path, old, new)}
+		}
+		old, new = old.Elem(), new.Elem()
+	}
+	if old.Kind() != reflect.Struct {
+		return []string{describeChange(prefix, path, old, new)}
+	}
+
+	var changes []string
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		fieldPath := path // Embedded structs are inlined into their parent
+		if !field.Anonymous {
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if path != "" {
+				name = path + "." + name
+			}
+			fieldPath = name
+		}
+		changes = append(changes, diffValues(prefix, fieldPath, old.Field(i), new.Field(i))...)
+	}
+	return changes
+}
+
+// describeChange formats the change of a single setting, hiding secrets
+func describeChange(prefix, path string, old, new reflect.Value) string {
+	if strings.HasSuffix(path, "token") || strings.HasPrefix(path, "hooks") {
+		return fmt.Sprintf("%s%s changed", prefix, path)
+	}
+	return fmt.Sprintf("%s%s: %s -> %s", prefix, path, describe(old), describe(new))
+}
+
+// describe formats a value for a change description
+func describe(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "(none)"
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() {
+		return "(none)"
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
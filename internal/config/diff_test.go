@@ -0,0 +1,62 @@
+package config
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestDiff(t *testing.T) {
+	retries := 3
+	base := func() *types.Config {
+		return &types.Config{
+			Instances: []types.CoolifyInstance{{
+				Name:          "default",
+				CoolifyConfig: types.CoolifyConfig{URL: "http://coolify:8000", Token: "old-token", Retries: &retries},
+				Defaults:      &types.DefaultsConfig{Policy: types.AutoPatch, Interval: "15m"},
+			}},
+			Defaults: types.DefaultsConfig{Policy: types.AutoPatch, Interval: "15m", Cooldown: "1h"},
+			Apps: []types.AppConfig{
+				{Name: "n8n", Instance: "default", Image: "n8nio/n8n", Policy: types.AutoPatch},
+				{Name: "redis", Instance: "default", Image: "redis"},
+			},
+			CheckConcurrency: 4,
+		}
+	}
+
+	if changes := Diff(base(), base()); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	changed := base()
+	changed.Instances[0].Token = "new-token"
+	changed.Instances[0].Defaults = &types.DefaultsConfig{Policy: types.AutoPatch, Interval: "5m"}
+	changed.Defaults.Interval = "5m"
+	changed.Apps[0].Policy = types.AutoMinor
+	changed.Apps[0].Labels = []string{"production"}
+	changed.Apps = append(changed.Apps[:1], types.AppConfig{Name: "umami", Instance: "default", Image: "ghcr.io/umami-software/umami"})
+	changed.CheckConcurrency = 8
+	changed.Hooks.OnFailure = []types.Hook{{URL: "https://alerts.example.com", Headers: map[string]string{"Authorization": "Bearer secret"}}}
+
+	expected := []string{
+		"instance 'default': token changed",
+		"instance 'default': defaults.interval: 15m -> 5m",
+		"app 'n8n': policy: auto-patch -> auto-minor",
+		"app 'n8n': labels: (none) -> [production]",
+		"app 'umami' added",
+		"app 'redis' removed",
+		"defaults.interval: 15m -> 5m",
+		"check_concurrency: 4 -> 8",
+		"hooks.on_failure changed",
+	}
+	changes := Diff(base(), changed)
+	for _, change := range expected {
+		if !slices.Contains(changes, change) {
+			t.Errorf("expected change '%s' in %v", change, changes)
+		}
+	}
+	if len(changes) != len(expected) {
+		t.Errorf("expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+}
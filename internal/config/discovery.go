@@ -0,0 +1,175 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// validateDiscovery checks the glob patterns, policies and pins of all discovery filters
+func validateDiscovery(discovery *types.DiscoveryConfig) error {
+	check := func(kind string, i int, filter types.DiscoveryFilter) error {
+		patterns := []string{filter.Instance, filter.Project, filter.Environment, filter.Server, filter.Name, filter.Image}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("discovery %s filter #%d: invalid pattern '%s': %w", kind, i+1, pattern, err)
+			}
+		}
+		if filter.Policy != "" && !IsValidPolicy(filter.Policy) {
+			return fmt.Errorf("discovery %s filter #%d: invalid policy '%s'", kind, i+1, filter.Policy)
+		}
+		if filter.Pin != "" {
+			if _, err := strconv.Atoi(filter.Pin); err != nil {
+				return fmt.Errorf("discovery %s filter #%d: invalid pin '%s'. Must be a number (e.g., 17)", kind, i+1, filter.Pin)
+			}
+		}
+		return nil
+	}
+
+	for i, filter := range discovery.Include {
+		if err := check("include", i, filter); err != nil {
+			return err
+		}
+	}
+	for i, filter := range discovery.Exclude {
+		if err := check("exclude", i, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsValidPolicy reports whether policy is one of the known update policies
+func IsValidPolicy(policy types.UpdatePolicy) bool {
+	switch policy {
+	case types.AutoPatch, types.AutoMinor, types.AutoAll, types.NotifyOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+// MatchDiscoveryFilter reports whether a discovered application matches filter.
+// image is the application's image reference without the tag.
+func MatchDiscoveryFilter(filter types.DiscoveryFilter, app types.CoolifyApplication, image string) bool {
+	fields := []struct {
+		pattern string
+		value   string
+	}{
+		{filter.Instance, app.Instance},
+		{filter.Project, app.Project},
+		{filter.Environment, app.Environment},
+		{filter.Server, app.Server},
+		{filter.Name, app.Name},
+		{filter.Image, image},
+	}
+
+	for _, field := range fields {
+		if field.pattern == "" {
+			continue
+		}
+		if matched, _ := path.Match(field.pattern, field.value); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// SelectDiscovered decides whether auto-discovery should pick up an application.
+// It returns the first matching include filter (nil if no include filters are
+// configured) and whether the application was selected at all.
+func SelectDiscovered(discovery *types.DiscoveryConfig, app types.CoolifyApplication, image string) (*types.DiscoveryFilter, bool) {
+	for _, filter := range discovery.Exclude {
+		if MatchDiscoveryFilter(filter, app, image) {
+			return nil, false
+		}
+	}
+
+	if len(discovery.Include) == 0 {
+		return nil, true
+	}
+
+	for i := range discovery.Include {
+		if MatchDiscoveryFilter(discovery.Include[i], app, image) {
+			return &discovery.Include[i], true
+		}
+	}
+	return nil, false
+}
+
+// DiscoveryNeedsLocations reports whether any filter matches on project,
+// environment or server, which requires extra Coolify API calls to resolve
+func DiscoveryNeedsLocations(discovery *types.DiscoveryConfig) bool {
+	filters := append(append([]types.DiscoveryFilter{}, discovery.Include...), discovery.Exclude...)
+	for _, filter := range filters {
+		if filter.Project != "" || filter.Environment != "" || filter.Server != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotation keys understood by ApplyAnnotations
+const (
+	AnnotationEnable = "patrol.enable"
+	AnnotationPolicy = "patrol.policy"
+	AnnotationPin    = "patrol.pin"
+	AnnotationLabels = "patrol.labels" // Comma-separated, matched by freeze periods
+)
+
+// ApplyAnnotations maps patrol annotations of a Coolify application onto app.
+// It returns the value of patrol.enable if present. Invalid annotations are
+// skipped and reported in the returned error; valid ones are still applied.
+func ApplyAnnotations(app *types.AppConfig, annotations map[string]string) (*bool, error) {
+	var enable *bool
+	var errs []error
+
+	// Sorted so that errors come out in a stable order
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := annotations[key]
+		switch key {
+		case AnnotationEnable:
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s '%s': must be true or false", key, value))
+				continue
+			}
+			enable = &enabled
+		case AnnotationPolicy:
+			policy := types.UpdatePolicy(value)
+			if !IsValidPolicy(policy) {
+				errs = append(errs, fmt.Errorf("invalid %s '%s'", key, value))
+				continue
+			}
+			app.Policy = policy
+		case AnnotationPin:
+			if _, err := strconv.Atoi(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s '%s': must be a number", key, value))
+				continue
+			}
+			app.Pin = value
+		case AnnotationLabels:
+			app.Labels = nil
+			for _, label := range strings.Split(value, ",") {
+				if label = strings.TrimSpace(label); label != "" {
+					app.Labels = append(app.Labels, label)
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("unknown annotation %s", key))
+		}
+	}
+
+	return enable, errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package coolify
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// AnnotationPrefix marks tags, docker labels and description entries that
+// configure patrol, e.g. "patrol.policy=auto-minor"
+const AnnotationPrefix = "patrol."
+
+// parseAnnotations collects patrol annotations from an application's custom
+// docker labels, description and tags. Later sources win, so a tag set in the
+// Coolify UI overrides a label baked into the resource.
+func parseAnnotations(customLabels, description string, tags []string) map[string]string {
+	annotations := make(map[string]string)
+
+	// Coolify stores custom labels base64 encoded, one label per line
+	labels := customLabels
+	if decoded, err := base64.StdEncoding.DecodeString(customLabels); err == nil {
+		labels = string(decoded)
+	}
+	for _, line := range strings.Split(labels, "\n") {
+		addAnnotation(annotations, strings.TrimPrefix(strings.TrimSpace(line), "- "))
+	}
+
+	for _, field := range strings.FieldsFunc(description, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ',' || r == ';'
+	}) {
+		addAnnotation(annotations, field)
+	}
+
+	for _, tag := range tags {
+		addAnnotation(annotations, tag)
+	}
+
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
+}
+
+// addAnnotation records entry if it has the form "patrol.<key>=<value>".
+// Tags can't always contain '=', so "patrol.<key>:<value>" is accepted too.
+func addAnnotation(annotations map[string]string, entry string) {
+	entry = strings.Trim(strings.TrimSpace(entry), `"'`)
+	if !strings.HasPrefix(entry, AnnotationPrefix) {
+		return
+	}
+
+	sep := strings.IndexAny(entry, "=:")
+	if sep == -1 {
+		return
+	}
+
+	key := strings.TrimSpace(entry[:sep])
+	value := strings.TrimSpace(entry[sep+1:])
+	if key == AnnotationPrefix || value == "" {
+		return
+	}
+	annotations[key] = value
+}
@@ -0,0 +1,81 @@
+package coolify
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	encodedLabels := base64.StdEncoding.EncodeToString([]byte(
+		"traefik.enable=true\npatrol.policy=auto-patch\npatrol.pin=17\n",
+	))
+
+	tests := []struct {
+		name         string
+		customLabels string
+		description  string
+		tags         []string
+		expected     map[string]string
+	}{
+		{
+			name:     "no annotations",
+			expected: nil,
+		},
+		{
+			name:         "base64 encoded custom labels",
+			customLabels: encodedLabels,
+			expected: map[string]string{
+				"patrol.policy": "auto-patch",
+				"patrol.pin":    "17",
+			},
+		},
+		{
+			name:         "plain custom labels",
+			customLabels: "- patrol.enable=false",
+			expected: map[string]string{
+				"patrol.enable": "false",
+			},
+		},
+		{
+			name:        "description annotations",
+			description: "Main workflow engine. patrol.policy=auto-minor, patrol.pin=1",
+			expected: map[string]string{
+				"patrol.policy": "auto-minor",
+				"patrol.pin":    "1",
+			},
+		},
+		{
+			name: "tags with either separator",
+			tags: []string{"production", "patrol.policy:notify-only", "patrol.enable=true"},
+			expected: map[string]string{
+				"patrol.policy": "notify-only",
+				"patrol.enable": "true",
+			},
+		},
+		{
+			name:         "tags override description and labels",
+			customLabels: encodedLabels,
+			description:  "patrol.policy=auto-minor",
+			tags:         []string{"patrol.policy=auto-all"},
+			expected: map[string]string{
+				"patrol.policy": "auto-all",
+				"patrol.pin":    "17",
+			},
+		},
+		{
+			name:        "entries without value are ignored",
+			description: "patrol.policy= patrol. patrol.pin",
+			expected:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseAnnotations(tt.customLabels, tt.description, tt.tags)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
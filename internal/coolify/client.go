@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/chrisdietr/coolify-patrol/pkg/types"
@@ -17,14 +23,23 @@ type Client struct {
 	baseURL    string
 	token      string
 	httpClient *http.Client
+	retry      RetryConfig
 }
 
 // ApplicationResponse represents Coolify's application response
 type ApplicationResponse struct {
-	UUID        string `json:"uuid"`
-	Name        string `json:"name"`
-	DockerImage string `json:"docker_image"`
-	Status      string `json:"status"`
+	UUID          string `json:"uuid"`
+	Name          string `json:"name"`
+	DockerImage   string `json:"docker_image"`
+	Status        string `json:"status"`
+	EnvironmentID int    `json:"environment_id"`
+	BuildPack     string `json:"build_pack"`
+	Dockerfile    string `json:"dockerfile"`
+	Description   string `json:"description"`
+	CustomLabels  string `json:"custom_labels"`
+	Tags          []struct {
+		Name string `json:"name"`
+	} `json:"tags"`
 }
 
 // ApplicationsListResponse represents the response from listing applications
@@ -32,11 +47,115 @@ type ApplicationsListResponse struct {
 	Data []ApplicationResponse `json:"data"`
 }
 
+// toApplication converts the API representation to our type
+func (a ApplicationResponse) toApplication() types.CoolifyApplication {
+	var tags []string
+	for _, tag := range a.Tags {
+		tags = append(tags, tag.Name)
+	}
+	
+	return types.CoolifyApplication{
+		UUID:          a.UUID,
+		Name:          a.Name,
+		DockerImage:   a.DockerImage,
+		Status:        a.Status,
+		EnvironmentID: a.EnvironmentID,
+		BuildPack:     a.BuildPack,
+		Dockerfile:    a.Dockerfile,
+		Annotations:   parseAnnotations(a.CustomLabels, a.Description, tags),
+	}
+}
+
+// ProjectResponse represents a Coolify project
+type ProjectResponse struct {
+	ID           int                   `json:"id"`
+	UUID         string                `json:"uuid"`
+	Name         string                `json:"name"`
+	Environments []EnvironmentResponse `json:"environments"`
+}
+
+// EnvironmentResponse represents an environment within a Coolify project
+type EnvironmentResponse struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// ServerResponse represents a server managed by Coolify
+type ServerResponse struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// ServerResourceResponse represents a resource deployed on a Coolify server
+type ServerResourceResponse struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// DeploymentsListResponse represents the response from listing an application's deployments
+type DeploymentsListResponse struct {
+	Count       int                       `json:"count"`
+	Deployments []types.CoolifyDeployment `json:"deployments"`
+}
+
+// BackupResponse represents a scheduled backup of a Coolify database
+type BackupResponse struct {
+	UUID      string `json:"uuid"`
+	Enabled   bool   `json:"enabled"`
+	Frequency string `json:"frequency"`
+}
+
+// BackupExecutionsResponse represents the response from listing the executions of a backup
+type BackupExecutionsResponse struct {
+	Executions []BackupExecutionResponse `json:"executions"`
+}
+
+// BackupExecutionResponse represents one run of a scheduled backup
+type BackupExecutionResponse struct {
+	UUID      string    `json:"uuid"`
+	Status    string    `json:"status"` // "running", "success" or "failed"
+	Message   string    `json:"message"`
+	Filename  string    `json:"filename"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EnvResponse represents an environment variable of a Coolify resource
+type EnvResponse struct {
+	UUID      string `json:"uuid"`
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	IsPreview bool   `json:"is_preview"`
+}
+
+// EnvUpdateRequest represents an environment variable update request
+type EnvUpdateRequest struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	IsPreview bool   `json:"is_preview"`
+}
+
 // UpdateRequest represents an application update request
 type UpdateRequest struct {
 	DockerImage string `json:"docker_image"`
 }
 
+// RetryConfig controls how idempotent requests are retried on transient failures
+type RetryConfig struct {
+	MaxRetries     int           // Retries after the first attempt, 0 disables retrying
+	InitialBackoff time.Duration // Wait before the first retry, doubled for each further retry
+	MaxBackoff     time.Duration // Upper bound for a single wait
+}
+
+// DefaultRetryConfig returns the retry settings used by NewClient
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 1 * time.Second,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
 // NewClient creates a new Coolify API client
 func NewClient(baseURL, token string) *Client {
 	// Ensure baseURL doesn't end with slash
@@ -48,46 +167,153 @@ func NewClient(baseURL, token string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retry: DefaultRetryConfig(),
 	}
 }
 
-// ListApplications retrieves all applications from Coolify
-func (c *Client) ListApplications(ctx context.Context) ([]types.CoolifyApplication, error) {
-	url := fmt.Sprintf("%s/api/v1/applications", c.baseURL)
+// SetRetryConfig replaces the retry settings for idempotent requests
+func (c *Client) SetRetryConfig(cfg RetryConfig) {
+	c.retry = cfg
+}
+
+// do sends a request to the Coolify API and decodes a successful JSON response
+// into out (if non-nil). Non-2xx responses are returned as *APIError. Requests
+// marked idempotent are retried with exponential backoff on network errors,
+// rate limiting and 5xx responses.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}, idempotent bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshaling request: %w", err)
+		}
+	}
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	attempts := 1
+	if idempotent && c.retry.MaxRetries > 0 {
+		attempts += c.retry.MaxRetries
+	}
+	
+	backoff := c.retry.InitialBackoff
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		wait, err := c.send(ctx, method, path, payload, out)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		
+		if attempt == attempts || !isRetryable(err) {
+			break
+		}
+		
+		// Prefer the server's Retry-After hint over our own backoff
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if c.retry.MaxBackoff > 0 && wait > c.retry.MaxBackoff {
+			wait = c.retry.MaxBackoff
+		}
+		
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	
+	return lastErr
+}
+
+// send performs a single request attempt. It returns the server's Retry-After
+// hint (if any) alongside the error.
+func (c *Client) send(ctx context.Context, method, path string, payload []byte, out interface{}) (time.Duration, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		return 0, fmt.Errorf("creating request: %w", err)
 	}
 	
 	req.Header.Set("Authorization", "Bearer "+c.token)
 	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+		return 0, fmt.Errorf("making request: %w", err)
 	}
 	defer resp.Body.Close()
 	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Coolify API returned status %d", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var wait time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		return wait, &APIError{
+			StatusCode: resp.StatusCode,
+			Message:    errorMessage(respBody),
+		}
+	}
+	
+	if out == nil {
+		return 0, nil
 	}
 	
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("decoding response: %w", err)
+	}
+	
+	return 0, nil
+}
+
+// isRetryable reports whether a failed attempt is worth repeating: temporary
+// API errors and transport failures, but not broken requests or responses
+func isRetryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	// An invalid URL comes as a *url.Error too, which is a net.Error
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Op == "parse" {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// applicationError turns a 404 into a descriptive ErrNotFound for the given application
+func applicationError(err error, uuid string) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("application %w: %s", ErrNotFound, uuid)
+	}
+	return err
+}
+
+// ListApplications retrieves all applications from Coolify
+func (c *Client) ListApplications(ctx context.Context) ([]types.CoolifyApplication, error) {
 	var response ApplicationsListResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.do(ctx, http.MethodGet, "/api/v1/applications", nil, &response, true); err != nil {
+		return nil, err
 	}
 	
 	// Convert to our types
 	var applications []types.CoolifyApplication
 	for _, app := range response.Data {
-		applications = append(applications, types.CoolifyApplication{
-			UUID:        app.UUID,
-			Name:        app.Name,
-			DockerImage: app.DockerImage,
-			Status:      app.Status,
-		})
+		applications = append(applications, app.toApplication())
 	}
 	
 	return applications, nil
@@ -95,110 +321,338 @@ func (c *Client) ListApplications(ctx context.Context) ([]types.CoolifyApplicati
 
 // GetApplication retrieves a specific application by UUID
 func (c *Client) GetApplication(ctx context.Context, uuid string) (*types.CoolifyApplication, error) {
-	url := fmt.Sprintf("%s/api/v1/applications/%s", c.baseURL, uuid)
-	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	var app ApplicationResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/applications/"+uuid, nil, &app, true); err != nil {
+		return nil, applicationError(err, uuid)
 	}
 	
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Accept", "application/json")
+	application := app.toApplication()
+	return &application, nil
+}
+
+// UpdateApplication updates an application's Docker image
+func (c *Client) UpdateApplication(ctx context.Context, uuid, newImage string) error {
+	updateReq := UpdateRequest{
+		DockerImage: newImage,
+	}
 	
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("making request: %w", err)
+	// Setting the image to a fixed value can safely be repeated
+	if err := c.do(ctx, http.MethodPatch, "/api/v1/applications/"+uuid, updateReq, nil, true); err != nil {
+		return applicationError(err, uuid)
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("application not found: %s", uuid)
+	return nil
+}
+
+// RestartApplication triggers a restart/redeploy of an application
+func (c *Client) RestartApplication(ctx context.Context, uuid string) error {
+	// Not retried: a lost response doesn't mean the restart wasn't queued
+	if err := c.do(ctx, http.MethodPost, "/api/v1/applications/"+uuid+"/restart", nil, nil, false); err != nil {
+		return applicationError(err, uuid)
 	}
 	
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Coolify API returned status %d", resp.StatusCode)
+	return nil
+}
+
+// resourcePath returns the API path prefix of an application or service
+func resourcePath(resourceType types.ResourceType, uuid string) string {
+	if resourceType == types.ResourceService {
+		return "/api/v1/services/" + uuid
+	}
+	return "/api/v1/applications/" + uuid
+}
+
+// resourceError turns a 404 into a descriptive ErrNotFound for the given resource
+func resourceError(err error, resourceType types.ResourceType, uuid string) error {
+	if resourceType == types.ResourceService && errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("service %w: %s", ErrNotFound, uuid)
+	}
+	return applicationError(err, uuid)
+}
+
+// GetEnv retrieves the production (non-preview) value of an application's or
+// service's environment variable
+func (c *Client) GetEnv(ctx context.Context, resourceType types.ResourceType, uuid, key string) (string, error) {
+	var envs []EnvResponse
+	if err := c.do(ctx, http.MethodGet, resourcePath(resourceType, uuid)+"/envs", nil, &envs, true); err != nil {
+		return "", resourceError(err, resourceType, uuid)
 	}
 	
-	var app ApplicationResponse
-	if err := json.NewDecoder(resp.Body).Decode(&app); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	for _, env := range envs {
+		if env.Key == key && !env.IsPreview {
+			return env.Value, nil
+		}
 	}
 	
-	return &types.CoolifyApplication{
-		UUID:        app.UUID,
-		Name:        app.Name,
-		DockerImage: app.DockerImage,
-		Status:      app.Status,
-	}, nil
+	return "", fmt.Errorf("environment variable %s %w", key, ErrNotFound)
 }
 
-// UpdateApplication updates an application's Docker image
-func (c *Client) UpdateApplication(ctx context.Context, uuid, newImage string) error {
-	url := fmt.Sprintf("%s/api/v1/applications/%s", c.baseURL, uuid)
+// UpdateEnv sets an existing environment variable of an application or service.
+// The change only takes effect after a restart.
+func (c *Client) UpdateEnv(ctx context.Context, resourceType types.ResourceType, uuid, key, value string) error {
+	updateReq := EnvUpdateRequest{
+		Key:   key,
+		Value: value,
+	}
 	
-	updateReq := UpdateRequest{
-		DockerImage: newImage,
+	// Setting a variable to a fixed value can safely be repeated
+	if err := c.do(ctx, http.MethodPatch, resourcePath(resourceType, uuid)+"/envs", updateReq, nil, true); err != nil {
+		return resourceError(err, resourceType, uuid)
 	}
 	
-	jsonData, err := json.Marshal(updateReq)
-	if err != nil {
-		return fmt.Errorf("marshaling update request: %w", err)
+	return nil
+}
+
+// DeployApplication triggers a new build and deployment of an application.
+// With force set, Coolify builds without cache and pulls fresh base images.
+func (c *Client) DeployApplication(ctx context.Context, uuid string, force bool) error {
+	path := fmt.Sprintf("/api/v1/deploy?uuid=%s&force=%t", uuid, force)
+	
+	// Not retried: a lost response doesn't mean the deployment wasn't queued
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, false); err != nil {
+		return applicationError(err, uuid)
 	}
 	
-	req, err := http.NewRequestWithContext(ctx, "PATCH", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+	return nil
+}
+
+// RestartService triggers a restart of a docker-compose based service
+func (c *Client) RestartService(ctx context.Context, uuid string) error {
+	// Not retried: a lost response doesn't mean the restart wasn't queued
+	if err := c.do(ctx, http.MethodPost, "/api/v1/services/"+uuid+"/restart", nil, nil, false); err != nil {
+		return resourceError(err, types.ResourceService, uuid)
 	}
 	
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// ListDeployments retrieves the most recent deployments of an application
+func (c *Client) ListDeployments(ctx context.Context, uuid string) ([]types.CoolifyDeployment, error) {
+	var response DeploymentsListResponse
+	path := fmt.Sprintf("/api/v1/deployments/applications/%s?skip=0&take=10", uuid)
+	if err := c.do(ctx, http.MethodGet, path, nil, &response, true); err != nil {
+		return nil, applicationError(err, uuid)
+	}
 	
-	resp, err := c.httpClient.Do(req)
+	return response.Deployments, nil
+}
+
+// databaseError turns a 404 into a descriptive ErrNotFound for the given database
+func databaseError(err error, uuid string) error {
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("database %w: %s", ErrNotFound, uuid)
+	}
+	return err
+}
+
+// ListBackups retrieves the scheduled backups of a database
+func (c *Client) ListBackups(ctx context.Context, databaseUUID string) ([]BackupResponse, error) {
+	var backups []BackupResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/databases/"+databaseUUID+"/backups", nil, &backups, true); err != nil {
+		return nil, databaseError(err, databaseUUID)
+	}
+	return backups, nil
+}
+
+// TriggerBackup starts a scheduled backup of a database right away
+func (c *Client) TriggerBackup(ctx context.Context, databaseUUID, backupUUID string) error {
+	path := fmt.Sprintf("/api/v1/databases/%s/backups/%s", databaseUUID, backupUUID)
+	body := map[string]bool{"backup_now": true}
+
+	// Not retried, a repeated request would start a second backup
+	if err := c.do(ctx, http.MethodPatch, path, body, nil, false); err != nil {
+		return databaseError(err, databaseUUID)
+	}
+	return nil
+}
+
+// ListBackupExecutions retrieves the runs of a scheduled backup of a database
+func (c *Client) ListBackupExecutions(ctx context.Context, databaseUUID, backupUUID string) ([]BackupExecutionResponse, error) {
+	var response BackupExecutionsResponse
+	path := fmt.Sprintf("/api/v1/databases/%s/backups/%s/executions", databaseUUID, backupUUID)
+	if err := c.do(ctx, http.MethodGet, path, nil, &response, true); err != nil {
+		return nil, databaseError(err, databaseUUID)
+	}
+	return response.Executions, nil
+}
+
+// ListProjects retrieves all projects. Environments are only included by GetProject.
+func (c *Client) ListProjects(ctx context.Context) ([]ProjectResponse, error) {
+	var projects []ProjectResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/projects", nil, &projects, true); err != nil {
+		return nil, err
+	}
+	return projects, nil
+}
+
+// GetProject retrieves a project including its environments
+func (c *Client) GetProject(ctx context.Context, uuid string) (*ProjectResponse, error) {
+	var project ProjectResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/projects/"+uuid, nil, &project, true); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListServers retrieves all servers
+func (c *Client) ListServers(ctx context.Context) ([]ServerResponse, error) {
+	var servers []ServerResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/servers", nil, &servers, true); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// GetServerResources retrieves the resources deployed on a server
+func (c *Client) GetServerResources(ctx context.Context, uuid string) ([]ServerResourceResponse, error) {
+	var resources []ServerResourceResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/servers/"+uuid+"/resources", nil, &resources, true); err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+// ResolveLocations fills in the project, environment and server names of the
+// given applications. The application list endpoint only carries IDs, so this
+// walks the project and server endpoints once per call.
+func (c *Client) ResolveLocations(ctx context.Context, apps []types.CoolifyApplication) error {
+	type location struct {
+		project     string
+		environment string
+	}
+	
+	environments := make(map[int]location)
+	projects, err := c.ListProjects(ctx)
 	if err != nil {
-		return fmt.Errorf("making request: %w", err)
+		return fmt.Errorf("listing projects: %w", err)
+	}
+	for _, p := range projects {
+		project, err := c.GetProject(ctx, p.UUID)
+		if err != nil {
+			return fmt.Errorf("getting project %s: %w", p.Name, err)
+		}
+		for _, env := range project.Environments {
+			environments[env.ID] = location{project: project.Name, environment: env.Name}
+		}
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("application not found: %s", uuid)
+	serverOf, err := c.ServerNames(ctx)
+	if err != nil {
+		return err
 	}
 	
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("Coolify API returned status %d", resp.StatusCode)
+	for i := range apps {
+		if loc, ok := environments[apps[i].EnvironmentID]; ok {
+			apps[i].Project = loc.project
+			apps[i].Environment = loc.environment
+		}
+		apps[i].Server = serverOf[apps[i].UUID]
 	}
 	
 	return nil
 }
 
-// RestartApplication triggers a restart/redeploy of an application
-func (c *Client) RestartApplication(ctx context.Context, uuid string) error {
-	url := fmt.Sprintf("%s/api/v1/applications/%s/restart", c.baseURL, uuid)
-	
-	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+// ServerNames maps the UUID of every resource to the name of the server it
+// runs on
+func (c *Client) ServerNames(ctx context.Context) (map[string]string, error) {
+	serverOf := make(map[string]string)
+	servers, err := c.ListServers(ctx)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("listing servers: %w", err)
 	}
-	
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-	
-	resp, err := c.httpClient.Do(req)
+	for _, server := range servers {
+		resources, err := c.GetServerResources(ctx, server.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("listing resources of server %s: %w", server.Name, err)
+		}
+		for _, resource := range resources {
+			serverOf[resource.UUID] = server.Name
+		}
+	}
+	return serverOf, nil
+}
+
+// ListActiveDeployments retrieves the queued and running deployments of all
+// applications
+func (c *Client) ListActiveDeployments(ctx context.Context) ([]types.CoolifyDeployment, error) {
+	var deployments []types.CoolifyDeployment
+	if err := c.do(ctx, http.MethodGet, "/api/v1/deployments", nil, &deployments, true); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+// HasActiveDeployment reports whether an application has a queued or running deployment
+func (c *Client) HasActiveDeployment(ctx context.Context, uuid string) (bool, error) {
+	deployments, err := c.ListDeployments(ctx, uuid)
 	if err != nil {
-		return fmt.Errorf("making request: %w", err)
+		return false, err
 	}
-	defer resp.Body.Close()
 	
-	if resp.StatusCode == http.StatusNotFound {
-		return fmt.Errorf("application not found: %s", uuid)
+	for _, deployment := range deployments {
+		if IsActiveDeploymentStatus(deployment.Status) {
+			return true, nil
+		}
 	}
 	
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("Coolify API returned status %d", resp.StatusCode)
+	return false, nil
+}
+
+// IsActiveDeploymentStatus reports whether a Coolify deployment status means the
+// deployment has not finished yet
+func IsActiveDeploymentStatus(status string) bool {
+	switch status {
+	case "queued", "in_progress":
+		return true
+	default:
+		return false
 	}
-	
-	return nil
+}
+
+// FindByName returns the applications with the given name. Empty project or
+// environment match any; non-empty ones require ResolveLocations to have run.
+func FindByName(apps []types.CoolifyApplication, name, project, environment string) []types.CoolifyApplication {
+	var matches []types.CoolifyApplication
+	for _, app := range apps {
+		if app.Name != name {
+			continue
+		}
+		if project != "" && app.Project != project {
+			continue
+		}
+		if environment != "" && app.Environment != environment {
+			continue
+		}
+		matches = append(matches, app)
+	}
+	return matches
+}
+
+// internalImagePrefixes are the registries/namespaces Coolify's own services are published under
+var internalImagePrefixes = []string{"ghcr.io/coollabsio/", "coollabsio/"}
+
+// internalNames are the names of Coolify's own service containers
+var internalNames = map[string]bool{
+	"coolify":          true,
+	"coolify-db":       true,
+	"coolify-redis":    true,
+	"coolify-realtime": true,
+	"coolify-proxy":    true,
+	"coolify-sentinel": true,
+}
+
+// IsCoolifyInternal reports whether an application is one of Coolify's own
+// services, which must never be updated behind Coolify's back
+func IsCoolifyInternal(app types.CoolifyApplication) bool {
+	if internalNames[app.Name] {
+		return true
+	}
+	for _, prefix := range internalImagePrefixes {
+		if strings.HasPrefix(app.DockerImage, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // ExtractImageAndTag splits a Docker image reference into image and tag parts
@@ -232,29 +686,23 @@ func BuildImageReference(image, tag string) string {
 
 // TestConnection tests the connection to Coolify API
 func (c *Client) TestConnection(ctx context.Context) error {
-	url := fmt.Sprintf("%s/api/v1/applications", c.baseURL)
-	
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+	// Single attempt so a misconfigured URL fails fast at startup
+	err := c.do(ctx, http.MethodGet, "/api/v1/applications", nil, nil, false)
+	if err == nil {
+		return nil
 	}
 	
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Accept", "application/json")
-	
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("making request: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode == http.StatusUnauthorized {
+	if errors.Is(err, ErrUnauthorized) {
 		return fmt.Errorf("authentication failed - check your API token")
 	}
+	if errors.Is(err, ErrForbidden) {
+		return fmt.Errorf("permission denied - the API token can't read applications")
+	}
 	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("unexpected status code: %d", apiErr.StatusCode)
 	}
 	
-	return nil
-}
\ No newline at end of file
+	return err
+}
@@ -3,10 +3,18 @@ package coolify
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
 )
 
 func TestNewClient(t *testing.T) {
@@ -161,6 +169,10 @@ func TestGetApplicationNotFound(t *testing.T) {
 	if err.Error() != "application not found: non-existent" {
 		t.Errorf("unexpected error message: %v", err)
 	}
+	
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected error to match ErrNotFound, got %v", err)
+	}
 }
 
 func TestUpdateApplication(t *testing.T) {
@@ -350,4 +362,606 @@ func TestTestConnection(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestHasActiveDeployment(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []string
+		expected bool
+	}{
+		{
+			name:     "no deployments",
+			statuses: nil,
+			expected: false,
+		},
+		{
+			name:     "only finished deployments",
+			statuses: []string{"finished", "failed", "cancelled-by-user"},
+			expected: false,
+		},
+		{
+			name:     "queued deployment",
+			statuses: []string{"queued", "finished"},
+			expected: true,
+		},
+		{
+			name:     "running deployment",
+			statuses: []string{"in_progress"},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				expectedPath := "/api/v1/deployments/applications/test-uuid"
+				if r.URL.Path != expectedPath {
+					t.Errorf("expected path '%s', got '%s'", expectedPath, r.URL.Path)
+				}
+				
+				response := DeploymentsListResponse{Count: len(tt.statuses)}
+				for i, status := range tt.statuses {
+					response.Deployments = append(response.Deployments, types.CoolifyDeployment{
+						DeploymentUUID: fmt.Sprintf("deploy-%d", i),
+						Status:         status,
+					})
+				}
+				
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(response)
+			}))
+			defer server.Close()
+			
+			client := NewClient(server.URL, "test-token")
+			
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			
+			active, err := client.HasActiveDeployment(ctx, "test-uuid")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			
+			if active != tt.expected {
+				t.Errorf("expected active=%v, got %v", tt.expected, active)
+			}
+		})
+	}
+}
+
+func TestListActiveDeployments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/deployments" {
+			t.Errorf("unexpected path '%s'", r.URL.Path)
+		}
+		w.Write([]byte(`[{"deployment_uuid": "d1", "status": "in_progress", "server_name": "alpha", "application_name": "shop"}]`))
+	}))
+	defer server.Close()
+
+	deployments, err := NewClient(server.URL, "test-token").ListActiveDeployments(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deployments) != 1 || deployments[0].ServerName != "alpha" || deployments[0].Status != "in_progress" {
+		t.Errorf("unexpected deployments %+v", deployments)
+	}
+}
+
+// fastRetry keeps retry tests quick
+var fastRetry = RetryConfig{
+	MaxRetries:     3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		sentinel   error
+		message    string
+	}{
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"message":"Unauthenticated."}`,
+			sentinel:   ErrUnauthorized,
+			message:    "Coolify API returned status 401: Unauthenticated.",
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"message":"You are not allowed to access the API."}`,
+			sentinel:   ErrForbidden,
+			message:    "Coolify API returned status 403: You are not allowed to access the API.",
+		},
+		{
+			name:       "rate limited",
+			statusCode: http.StatusTooManyRequests,
+			body:       "Too Many Attempts.",
+			sentinel:   ErrRateLimited,
+			message:    "Coolify API returned status 429: Too Many Attempts.",
+		},
+		{
+			name:       "bad gateway",
+			statusCode: http.StatusBadGateway,
+			body:       "",
+			sentinel:   nil,
+			message:    "Coolify API returned status 502",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			
+			client := NewClient(server.URL, "test-token")
+			client.SetRetryConfig(RetryConfig{})
+			
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			
+			_, err := client.ListApplications(ctx)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != tt.statusCode {
+				t.Errorf("expected status %d, got %d", tt.statusCode, apiErr.StatusCode)
+			}
+			if err.Error() != tt.message {
+				t.Errorf("expected error '%s', got '%s'", tt.message, err.Error())
+			}
+			
+			if tt.sentinel != nil && !errors.Is(err, tt.sentinel) {
+				t.Errorf("expected error to match %v", tt.sentinel)
+			}
+			if errors.Is(err, ErrNotFound) {
+				t.Error("error should not match ErrNotFound")
+			}
+			if tt.sentinel != ErrUnauthorized && errors.Is(err, ErrUnauthorized) {
+				t.Error("error should not match ErrUnauthorized")
+			}
+		})
+	}
+}
+
+func TestRetryIdempotentRequests(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ApplicationResponse{UUID: "test-uuid", Name: "test-app"})
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	client.SetRetryConfig(fastRetry)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	app, err := client.GetApplication(ctx, "test-uuid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	if app.UUID != "test-uuid" {
+		t.Errorf("expected UUID 'test-uuid', got '%s'", app.UUID)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	client.SetRetryConfig(fastRetry)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	_, err := client.ListApplications(ctx)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	
+	if attempts != fastRetry.MaxRetries+1 {
+		t.Errorf("expected %d attempts, got %d", fastRetry.MaxRetries+1, attempts)
+	}
+}
+
+func TestNoRetryForPermanentErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	client.SetRetryConfig(fastRetry)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	_, err := client.GetApplication(ctx, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "server error", err: &APIError{StatusCode: http.StatusBadGateway}, expected: true},
+		{name: "rate limited", err: &APIError{StatusCode: http.StatusTooManyRequests}, expected: true},
+		{name: "not found", err: &APIError{StatusCode: http.StatusNotFound}, expected: false},
+		{name: "connection refused", err: fmt.Errorf("making request: %w", &url.Error{Op: "Get", URL: "http://coolify", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}), expected: true},
+		{name: "connection reset", err: fmt.Errorf("decoding response: %w", syscall.ECONNRESET), expected: true},
+		{name: "truncated response", err: fmt.Errorf("decoding response: %w", io.ErrUnexpectedEOF), expected: true},
+		{name: "invalid json", err: fmt.Errorf("decoding response: %w", &json.SyntaxError{}), expected: false},
+		{name: "invalid url", err: fmt.Errorf("creating request: %w", &url.Error{Op: "parse", URL: "::", Err: errors.New("missing protocol scheme")}), expected: false},
+		{name: "cancelled", err: fmt.Errorf("making request: %w", &url.Error{Op: "Get", URL: "http://coolify", Err: context.Canceled}), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.expected {
+				t.Errorf("expected isRetryable(%v) = %v, got %v", tt.err, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNoRetryForRestart(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	client.SetRetryConfig(fastRetry)
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := client.RestartApplication(ctx, "test-uuid"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if attempts != 1 {
+		t.Errorf("expected restart not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestResolveLocations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/projects", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]ProjectResponse{{ID: 1, UUID: "proj-1", Name: "shop"}})
+	})
+	mux.HandleFunc("/api/v1/projects/proj-1", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ProjectResponse{
+			ID:   1,
+			UUID: "proj-1",
+			Name: "shop",
+			Environments: []EnvironmentResponse{
+				{ID: 10, Name: "production"},
+				{ID: 11, Name: "staging"},
+			},
+		})
+	})
+	mux.HandleFunc("/api/v1/servers", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]ServerResponse{{UUID: "srv-1", Name: "eu-1"}})
+	})
+	mux.HandleFunc("/api/v1/servers/srv-1/resources", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]ServerResourceResponse{{UUID: "app-1", Name: "web", Type: "application"}})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	apps := []types.CoolifyApplication{
+		{UUID: "app-1", Name: "web", EnvironmentID: 10},
+		{UUID: "app-2", Name: "worker", EnvironmentID: 11},
+		{UUID: "app-3", Name: "orphan", EnvironmentID: 99},
+	}
+	
+	if err := client.ResolveLocations(ctx, apps); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	
+	if apps[0].Project != "shop" || apps[0].Environment != "production" || apps[0].Server != "eu-1" {
+		t.Errorf("unexpected location for app-1: %+v", apps[0])
+	}
+	if apps[1].Project != "shop" || apps[1].Environment != "staging" || apps[1].Server != "" {
+		t.Errorf("unexpected location for app-2: %+v", apps[1])
+	}
+	if apps[2].Project != "" || apps[2].Environment != "" {
+		t.Errorf("expected no location for app-3, got %+v", apps[2])
+	}
+}
+
+func TestFindByName(t *testing.T) {
+	apps := []types.CoolifyApplication{
+		{UUID: "a1", Name: "n8n", Project: "automation", Environment: "production"},
+		{UUID: "a2", Name: "n8n", Project: "automation", Environment: "staging"},
+		{UUID: "a3", Name: "postgres", Project: "automation", Environment: "production"},
+	}
+
+	tests := []struct {
+		name        string
+		appName     string
+		project     string
+		environment string
+		expected    []string
+	}{
+		{"unique name", "postgres", "", "", []string{"a3"}},
+		{"ambiguous name", "n8n", "", "", []string{"a1", "a2"}},
+		{"scoped by environment", "n8n", "", "staging", []string{"a2"}},
+		{"scoped by project and environment", "n8n", "automation", "production", []string{"a1"}},
+		{"wrong project", "n8n", "marketing", "", nil},
+		{"missing name", "redis", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := FindByName(apps, tt.appName, tt.project, tt.environment)
+			
+			var uuids []string
+			for _, match := range matches {
+				uuids = append(uuids, match.UUID)
+			}
+			
+			if fmt.Sprint(uuids) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, uuids)
+			}
+		})
+	}
+}
+
+func TestIsCoolifyInternal(t *testing.T) {
+	tests := []struct {
+		app      types.CoolifyApplication
+		expected bool
+	}{
+		{types.CoolifyApplication{Name: "coolify", DockerImage: ""}, true},
+		{types.CoolifyApplication{Name: "realtime", DockerImage: "ghcr.io/coollabsio/coolify-realtime:1.0.6"}, true},
+		{types.CoolifyApplication{Name: "helper", DockerImage: "coollabsio/coolify-helper:1.0.8"}, true},
+		{types.CoolifyApplication{Name: "n8n", DockerImage: "n8nio/n8n:1.63.1"}, false},
+		{types.CoolifyApplication{Name: "coolify-patrol", DockerImage: "ghcr.io/chrisdietr/coolify-patrol:1.0.0"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.app.Name, func(t *testing.T) {
+			if result := IsCoolifyInternal(tt.app); result != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType types.ResourceType
+		expectedPath string
+	}{
+		{"application", types.ResourceApplication, "/api/v1/applications/test-uuid/envs"},
+		{"default type", "", "/api/v1/applications/test-uuid/envs"},
+		{"service", types.ResourceService, "/api/v1/services/test-uuid/envs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.expectedPath {
+					t.Errorf("expected path '%s', got '%s'", tt.expectedPath, r.URL.Path)
+				}
+				
+				json.NewEncoder(w).Encode([]EnvResponse{
+					{Key: "N8N_VERSION", Value: "1.60.0", IsPreview: true},
+					{Key: "N8N_VERSION", Value: "1.63.1"},
+					{Key: "OTHER", Value: "x"},
+				})
+			}))
+			defer server.Close()
+			
+			client := NewClient(server.URL, "test-token")
+			
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			
+			value, err := client.GetEnv(ctx, tt.resourceType, "test-uuid", "N8N_VERSION")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if value != "1.63.1" {
+				t.Errorf("expected production value '1.63.1', got '%s'", value)
+			}
+			
+			_, err = client.GetEnv(ctx, tt.resourceType, "test-uuid", "MISSING")
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound for missing variable, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := "/api/v1/services/test-uuid/envs"
+		if r.URL.Path != expectedPath {
+			t.Errorf("expected path '%s', got '%s'", expectedPath, r.URL.Path)
+		}
+		
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH method, got '%s'", r.Method)
+		}
+		
+		var updateReq EnvUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&updateReq); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+			return
+		}
+		
+		if updateReq.Key != "N8N_VERSION" || updateReq.Value != "1.63.2" || updateReq.IsPreview {
+			t.Errorf("unexpected update request: %+v", updateReq)
+		}
+		
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := client.UpdateEnv(ctx, types.ResourceService, "test-uuid", "N8N_VERSION", "1.63.2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRestartService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := "/api/v1/services/test-uuid/restart"
+		if r.URL.Path != expectedPath {
+			t.Errorf("expected path '%s', got '%s'", expectedPath, r.URL.Path)
+		}
+		
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := client.RestartService(ctx, "test-uuid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDeployApplication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/deploy" {
+			t.Errorf("expected path '/api/v1/deploy', got '%s'", r.URL.Path)
+		}
+		if r.Method != "GET" {
+			t.Errorf("expected GET request, got %s", r.Method)
+		}
+		if got := r.URL.Query().Get("uuid"); got != "test-uuid" {
+			t.Errorf("expected uuid 'test-uuid', got '%s'", got)
+		}
+		if got := r.URL.Query().Get("force"); got != "true" {
+			t.Errorf("expected force 'true', got '%s'", got)
+		}
+		
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"deployments":[{"message":"Application deployment queued.","resource_uuid":"test-uuid"}]}`))
+	}))
+	defer server.Close()
+	
+	client := NewClient(server.URL, "test-token")
+	
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	
+	if err := client.DeployApplication(ctx, "test-uuid", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	triggered := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/databases/db-uuid/backups":
+			w.Write([]byte(`[{"uuid": "backup-uuid", "enabled": true, "frequency": "0 0 * * *"}]`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/databases/db-uuid/backups/backup-uuid":
+			var body map[string]bool
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !body["backup_now"] {
+				t.Errorf("expected backup_now in request body, got %v (%v)", body, err)
+			}
+			triggered++
+			w.WriteHeader(http.StatusBadGateway)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/databases/db-uuid/backups/backup-uuid/executions":
+			w.Write([]byte(`{"executions": [{"uuid": "run-1", "status": "success", "filename": "/backups/db.dmp"}]}`))
+		case r.URL.Path == "/api/v1/databases/missing/backups":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-token")
+	client.SetRetryConfig(fastRetry)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	backups, err := client.ListBackups(ctx, "db-uuid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(backups) != 1 || backups[0].UUID != "backup-uuid" || !backups[0].Enabled {
+		t.Errorf("unexpected backups: %+v", backups)
+	}
+
+	executions, err := client.ListBackupExecutions(ctx, "db-uuid", "backup-uuid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executions) != 1 || executions[0].Status != "success" || executions[0].Filename != "/backups/db.dmp" {
+		t.Errorf("unexpected executions: %+v", executions)
+	}
+
+	// A second backup must not be started by a retry
+	if err := client.TriggerBackup(ctx, "db-uuid", "backup-uuid"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if triggered != 1 {
+		t.Errorf("expected backup trigger not to be retried, got %d attempts", triggered)
+	}
+
+	if _, err := client.ListBackups(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an unknown database, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package coolify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors for Coolify API failures callers need to tell apart.
+// Use errors.Is to match them against errors returned by Client methods.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized") // The token was rejected
+	ErrForbidden    = errors.New("forbidden")    // The token lacks permission for a resource
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// APIError is returned when the Coolify API responds with an unexpected status code
+type APIError struct {
+	StatusCode int
+	Message    string // Message from the response body, if any
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Coolify API returned status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("Coolify API returned status %d: %s", e.StatusCode, e.Message)
+}
+
+// Is maps status codes onto the package's sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Temporary reports whether the same request may succeed if retried later
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// maxErrorMessageLength caps how much of a response body ends up in an error
+const maxErrorMessageLength = 200
+
+// errorMessage extracts a human readable message from an error response body.
+// Coolify answers with {"message": "..."} for most errors, but proxies in front
+// of it return plain text or HTML.
+func errorMessage(body []byte) string {
+	var payload struct {
+		Message string `json:"message"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.Message != "" {
+			return payload.Message
+		}
+		if payload.Error != "" {
+			return payload.Error
+		}
+	}
+
+	message := strings.TrimSpace(string(body))
+	if len(message) > maxErrorMessageLength {
+		message = message[:maxErrorMessageLength] + "..."
+	}
+	return message
+}
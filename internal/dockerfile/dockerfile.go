@@ -0,0 +1,117 @@
+package dockerfile
+
+import (
+	"regexp"
+	"strings"
+)
+
+var argRefRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::?-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// BaseImages returns the external images referenced by FROM instructions, in
+// order of appearance and without duplicates. References to earlier build
+// stages, "scratch", digest-pinned images and images whose name can't be
+// resolved from ARG defaults are left out.
+func BaseImages(content string) []string {
+	var images []string
+	seen := make(map[string]bool)
+	stages := make(map[string]bool)
+	args := make(map[string]string)
+
+	for _, line := range logicalLines(content) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// Only ARGs before the first FROM can be used in FROM lines,
+			// later ones are harmless to record as well
+			for _, field := range fields[1:] {
+				name, value, _ := strings.Cut(field, "=")
+				if _, exists := args[name]; !exists || value != "" {
+					args[name] = strings.Trim(value, `"'`)
+				}
+			}
+
+		case "FROM":
+			var ref, stage string
+			rest := fields[1:]
+			for len(rest) > 0 && strings.HasPrefix(rest[0], "--") {
+				rest = rest[1:] // --platform=...
+			}
+			if len(rest) == 0 {
+				continue
+			}
+			ref = expandArgs(rest[0], args)
+			if len(rest) >= 3 && strings.EqualFold(rest[1], "AS") {
+				stage = strings.ToLower(rest[2])
+			}
+
+			skip := ref == "" ||
+				strings.EqualFold(ref, "scratch") ||
+				stages[strings.ToLower(ref)] ||
+				strings.Contains(ref, "@") ||
+				strings.Contains(ref, "$")
+			if stage != "" {
+				stages[stage] = true
+			}
+			if skip || seen[ref] {
+				continue
+			}
+			seen[ref] = true
+			images = append(images, ref)
+		}
+	}
+
+	return images
+}
+
+// logicalLines joins continued lines and drops comments and blank lines
+func logicalLines(content string) []string {
+	var lines []string
+	var current strings.Builder
+
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(strings.TrimSuffix(raw, "\r"))
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString(" ")
+			continue
+		}
+
+		current.WriteString(line)
+		if joined := strings.TrimSpace(current.String()); joined != "" {
+			lines = append(lines, joined)
+		}
+		current.Reset()
+	}
+
+	if joined := strings.TrimSpace(current.String()); joined != "" {
+		lines = append(lines, joined)
+	}
+	return lines
+}
+
+// expandArgs substitutes $NAME, ${NAME} and ${NAME:-default} with ARG values.
+// Unknown variables without a default are left in place.
+func expandArgs(ref string, args map[string]string) string {
+	return argRefRegex.ReplaceAllStringFunc(ref, func(match string) string {
+		parts := argRefRegex.FindStringSubmatch(match)
+		name, fallback := parts[1], parts[2]
+		if name == "" {
+			name = parts[3]
+		}
+		if value := args[name]; value != "" {
+			return value
+		}
+		if fallback != "" {
+			return fallback
+		}
+		return match
+	})
+}
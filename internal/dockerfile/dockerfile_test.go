@@ -0,0 +1,71 @@
+package dockerfile
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBaseImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "single stage",
+			content:  "FROM node:20.11.0\nWORKDIR /app\n",
+			expected: []string{"node:20.11.0"},
+		},
+		{
+			name: "multi stage with stage references",
+			content: `# build
+FROM --platform=$BUILDPLATFORM golang:1.23-alpine AS builder
+RUN go build ./...
+
+FROM builder AS tested
+RUN go test ./...
+
+from alpine:3.21
+COPY --from=builder /app /app
+`,
+			expected: []string{"golang:1.23-alpine", "alpine:3.21"},
+		},
+		{
+			name: "arg defaults",
+			content: `ARG NODE_VERSION=20.11.0
+ARG DISTRO
+FROM node:${NODE_VERSION}-${DISTRO:-bookworm}
+FROM python:$PYTHON_VERSION
+`,
+			expected: []string{"node:20.11.0-bookworm"},
+		},
+		{
+			name: "skips scratch, digests and duplicates",
+			content: `FROM postgres:17.2@sha256:0123456789abcdef
+FROM scratch
+FROM redis:7.4.1
+FROM redis:7.4.1
+`,
+			expected: []string{"redis:7.4.1"},
+		},
+		{
+			name:     "continued lines",
+			content:  "FROM \\\n  ghcr.io/owner/base:v1.2.3 \\\n  AS base\n",
+			expected: []string{"ghcr.io/owner/base:v1.2.3"},
+		},
+		{
+			name:     "no FROM",
+			content:  "RUN echo hi\n",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := BaseImages(tt.content)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
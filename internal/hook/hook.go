@@ -0,0 +1,267 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+const (
+	// DefaultTimeout limits hooks without a timeout of their own
+	DefaultTimeout = 5 * time.Minute
+
+	// maxOutput is how much of a hook's output is kept, from its end
+	maxOutput = 4096
+)
+
+// Data describes the update a hook runs for. Its fields are available in
+// templates, like {{.ToTag}}, and as PATROL_* environment variables.
+type Data struct {
+	Stage    types.HookStage `json:"stage"`
+	App      string          `json:"app"`
+	UUID     string          `json:"uuid"`
+	Instance string          `json:"instance"`
+	Image    string          `json:"image"`
+	FromTag  string          `json:"from_tag"`
+	ToTag    string          `json:"to_tag"`
+	Error    string          `json:"error,omitempty"` // Why the update failed, on_failure and on_breaker_open only
+}
+
+// env returns data as environment variables
+func (d Data) env() []string {
+	return []string{
+		"PATROL_HOOK=" + string(d.Stage),
+		"PATROL_APP=" + d.App,
+		"PATROL_APP_UUID=" + d.UUID,
+		"PATROL_INSTANCE=" + d.Instance,
+		"PATROL_IMAGE=" + d.Image,
+		"PATROL_FROM_TAG=" + d.FromTag,
+		"PATROL_TO_TAG=" + d.ToTag,
+		"PATROL_ERROR=" + d.Error,
+	}
+}
+
+// Validate checks a configured hook, including its templates
+func Validate(hook types.Hook) error {
+	if (hook.Command == "") == (hook.URL == "") {
+		return fmt.Errorf("needs either a command or a url")
+	}
+	if hook.Command != "" && (hook.Method != "" || len(hook.Headers) > 0 || hook.Body != "") {
+		return fmt.Errorf("method, headers and body are only used with url")
+	}
+	if hook.URL != "" && len(hook.Env) > 0 {
+		return fmt.Errorf("env is only used with command")
+	}
+	if hook.Timeout != "" {
+		if timeout, err := time.ParseDuration(hook.Timeout); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout '%s'", hook.Timeout)
+		}
+	}
+
+	templates := []string{hook.URL, hook.Body}
+	for _, value := range hook.Env {
+		templates = append(templates, value)
+	}
+	for _, value := range hook.Headers {
+		templates = append(templates, value)
+	}
+	for _, text := range templates {
+		if _, err := render(text, Data{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateAll checks all hooks of each stage
+func ValidateAll(hooks types.Hooks) error {
+	stages := []struct {
+		stage types.HookStage
+		hooks []types.Hook
+	}{
+		{types.HookPreUpdate, hooks.PreUpdate},
+		{types.HookPostUpdate, hooks.PostUpdate},
+		{types.HookOnFailure, hooks.OnFailure},
+		{types.HookBreakerOpen, hooks.OnBreakerOpen},
+	}
+	for _, s := range stages {
+		for i, hook := range s.hooks {
+			if err := Validate(hook); err != nil {
+				return fmt.Errorf("%s hook #%d: %w", s.stage, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Stage returns the hooks of a stage
+func Stage(hooks types.Hooks, stage types.HookStage) []types.Hook {
+	switch stage {
+	case types.HookPreUpdate:
+		return hooks.PreUpdate
+	case types.HookPostUpdate:
+		return hooks.PostUpdate
+	case types.HookOnFailure:
+		return hooks.OnFailure
+	case types.HookBreakerOpen:
+		return hooks.OnBreakerOpen
+	}
+	return nil
+}
+
+// Name describes a hook for logs and history
+func Name(hook types.Hook) string {
+	switch {
+	case hook.Name != "":
+		return hook.Name
+	case hook.Command != "":
+		return hook.Command
+	}
+	return method(hook) + " " + hook.URL
+}
+
+// Run runs a hook and returns the end of its output, or of the response for
+// HTTP hooks. Commands fail with a non-zero exit status, HTTP hooks with a
+// status other than 2xx.
+func Run(ctx context.Context, hook types.Hook, data Data) (string, error) {
+	timeout := DefaultTimeout
+	if hook.Timeout != "" {
+		if parsed, err := time.ParseDuration(hook.Timeout); err == nil {
+			timeout = parsed
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		output string
+		err    error
+	)
+	if hook.Command != "" {
+		output, err = runCommand(ctx, hook, data)
+	} else {
+		output, err = call(ctx, hook, data)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+	return output, err
+}
+
+// inherited are the only variables a command gets from patrol's environment,
+// which holds the Coolify and API tokens
+var inherited = []string{"PATH", "HOME"}
+
+// runCommand runs the hook's command with a shell, in an environment of PATH,
+// HOME, the PATROL_* variables of data and the hook's env
+func runCommand(ctx context.Context, hook types.Hook, data Data) (string, error) {
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", hook.Command)
+	cmd.Env = data.env()
+	for _, key := range inherited {
+		if value, ok := os.LookupEnv(key); ok {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	for key, value := range hook.Env {
+		rendered, err := render(value, data)
+		if err != nil {
+			return "", err
+		}
+		cmd.Env = append(cmd.Env, key+"="+rendered)
+	}
+	killGroupOnCancel(cmd)
+	cmd.WaitDelay = 5 * time.Second // Don't wait for background processes holding the output
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return tail(output.String()), err
+}
+
+// call sends the hook's HTTP request
+func call(ctx context.Context, hook types.Hook, data Data) (string, error) {
+	url, err := render(hook.URL, data)
+	if err != nil {
+		return "", err
+	}
+
+	var body []byte
+	if hook.Body != "" {
+		rendered, err := render(hook.Body, data)
+		if err != nil {
+			return "", err
+		}
+		body = []byte(rendered)
+	} else if body, err = json.Marshal(data); err != nil {
+		return "", fmt.Errorf("encoding hook body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method(hook), url, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("creating hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		rendered, err := render(value, data)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(key, rendered)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Only the end is kept, but read some more to find it
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, 64*maxOutput))
+	output := tail(fmt.Sprintf("%s\n%s", resp.Status, response))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return output, fmt.Errorf("hook returned status %d", resp.StatusCode)
+	}
+	return output, nil
+}
+
+// method returns the HTTP method of a hook
+func method(hook types.Hook) string {
+	if hook.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(hook.Method)
+}
+
+// render executes text as a template with data
+func render(text string, data Data) (string, error) {
+	tmpl, err := template.New("hook").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template '%s': %w", text, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("invalid template '%s': %w", text, err)
+	}
+	return out.String(), nil
+}
+
+// tail returns the last maxOutput bytes of output, without surrounding whitespace
+func tail(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxOutput {
+		output = "..." + output[len(output)-maxOutput:]
+	}
+	return output
+}
@@ -0,0 +1,162 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+var testData = Data{
+	Stage:    types.HookPreUpdate,
+	App:      "postgres",
+	UUID:     "pg-uuid",
+	Instance: "default",
+	Image:    "postgres",
+	FromTag:  "16.3",
+	ToTag:    "16.4",
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    types.Hook
+		wantErr bool
+	}{
+		{name: "command", hook: types.Hook{Command: "backup.sh", Env: map[string]string{"NAME": "{{.App}}-{{.FromTag}}"}, Timeout: "10m"}},
+		{name: "http", hook: types.Hook{URL: "https://ci.example.com/check/{{.App}}", Headers: map[string]string{"Authorization": "Bearer token"}}},
+		{name: "neither", hook: types.Hook{Name: "empty"}, wantErr: true},
+		{name: "both", hook: types.Hook{Command: "true", URL: "https://example.com"}, wantErr: true},
+		{name: "body with command", hook: types.Hook{Command: "true", Body: "{}"}, wantErr: true},
+		{name: "env with url", hook: types.Hook{URL: "https://example.com", Env: map[string]string{"A": "b"}}, wantErr: true},
+		{name: "invalid timeout", hook: types.Hook{Command: "true", Timeout: "soon"}, wantErr: true},
+		{name: "unknown field", hook: types.Hook{URL: "https://example.com/{{.Tag}}"}, wantErr: true},
+		{name: "broken template", hook: types.Hook{Command: "true", Env: map[string]string{"A": "{{.App"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.hook)
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    types.Hook
+		output  string
+		wantErr string
+	}{
+		{
+			name:   "environment",
+			hook:   types.Hook{Command: `echo "$PATROL_HOOK $PATROL_APP $PATROL_FROM_TAG -> $PATROL_TO_TAG $BACKUP"`, Env: map[string]string{"BACKUP": "{{.App}}-{{.FromTag}}.sql"}},
+			output: "pre_update postgres 16.3 -> 16.4 postgres-16.3.sql",
+		},
+		{
+			name:   "no secrets from patrol's environment",
+			hook:   types.Hook{Command: `echo "[$COOLIFY_TOKEN][$PATROL_API_TOKEN][${PATH:+path}]"`},
+			output: "[][][path]",
+		},
+		{
+			name:    "failure",
+			hook:    types.Hook{Command: "echo disk full >&2; exit 3"},
+			output:  "disk full",
+			wantErr: "exit status 3",
+		},
+		{
+			name:    "timeout",
+			hook:    types.Hook{Command: "sleep 5", Timeout: "50ms"},
+			wantErr: "timed out after 50ms",
+		},
+	}
+
+	t.Setenv("COOLIFY_TOKEN", "coolify-secret")
+	t.Setenv("PATROL_API_TOKEN", "api-secret")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := Run(context.Background(), tt.hook, testData)
+			if output != tt.output {
+				t.Errorf("expected output '%s', got '%s'", tt.output, output)
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected error containing '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRunHTTP(t *testing.T) {
+	var (
+		path, auth string
+		body       []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+		if strings.HasPrefix(path, "/fail") {
+			http.Error(w, "migration pending", http.StatusConflict)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	hook := types.Hook{URL: server.URL + "/check/{{.App}}/{{.ToTag}}", Headers: map[string]string{"Authorization": "Bearer {{.Instance}}"}}
+	output, err := Run(context.Background(), hook, testData)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/check/postgres/16.4" || auth != "Bearer default" {
+		t.Errorf("unexpected request to '%s' with authorization '%s'", path, auth)
+	}
+	if output != "200 OK\nok" {
+		t.Errorf("unexpected output '%s'", output)
+	}
+
+	// Without a body template, the update is sent as JSON
+	var sent Data
+	if err := json.Unmarshal(body, &sent); err != nil || sent != testData {
+		t.Errorf("expected update as body, got %s (%v)", body, err)
+	}
+
+	output, err = Run(context.Background(), types.Hook{URL: server.URL + "/fail", Body: "{{.App}}"}, testData)
+	if err == nil || !strings.Contains(output, "migration pending") {
+		t.Errorf("expected failure with response, got '%s', %v", output, err)
+	}
+	if string(body) != "postgres" {
+		t.Errorf("expected templated body, got '%s'", body)
+	}
+}
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		hook types.Hook
+		want string
+	}{
+		{types.Hook{Name: "backup", Command: "backup.sh"}, "backup"},
+		{types.Hook{Command: "backup.sh"}, "backup.sh"},
+		{types.Hook{URL: "https://example.com", Method: "put"}, "PUT https://example.com"},
+		{types.Hook{URL: "https://example.com"}, "POST https://example.com"},
+	}
+	for _, tt := range tests {
+		if got := Name(tt.hook); got != tt.want {
+			t.Errorf("expected '%s', got '%s'", tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package leader
+
+import (
+	"context"
+	"errors"
+)
+
+// FileLock is not available on this platform, use the HTTP or Coolify lock
+type FileLock struct{}
+
+// NewFileLock creates a lock that always fails
+func NewFileLock(path string) *FileLock {
+	return &FileLock{}
+}
+
+// Acquire always fails
+func (l *FileLock) Acquire(ctx context.Context) (bool, error) {
+	return false, errors.New("file locks are only supported on unix systems")
+}
+
+// Release does nothing
+func (l *FileLock) Release(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+// FileLock is an exclusive flock on a file. The operating system releases it
+// when the process exits, so a crashed instance never keeps the lock. All
+// instances must see the same file, on a volume they share on one host.
+type FileLock struct {
+	path string
+	mu   sync.Mutex
+	file *os.File // Open while the lock is held
+}
+
+// NewFileLock creates a lock on the file at path, created if needed
+func NewFileLock(path string) *FileLock {
+	return &FileLock{path: path}
+}
+
+// Acquire takes the lock without waiting for it
+func (l *FileLock) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		return true, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return false, fmt.Errorf("creating lock directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, fmt.Errorf("opening lock file: %w", err)
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, fmt.Errorf("locking %s: %w", l.path, err)
+	}
+
+	l.file = file
+	return true, nil
+}
+
+// Release unlocks the file
+func (l *FileLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close() // Closing drops the flock
+	l.file = nil
+	return err
+}
@@ -0,0 +1,55 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Lock is held by at most one patrol instance at a time
+type Lock interface {
+	// Acquire takes the lock, or renews it if already held, and reports
+	// whether this instance holds it
+	Acquire(ctx context.Context) (bool, error)
+	// Release gives the lock up so that another instance can take it right away
+	Release(ctx context.Context) error
+}
+
+// New creates the lock selected by cfg.Leader, or nil if there is none.
+// coolifyClients must hold a client for every instance, keyed by name.
+func New(cfg *types.Config, coolifyClients map[string]*coolify.Client) (Lock, error) {
+	leader := cfg.Leader
+	ttl, err := time.ParseDuration(leader.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader ttl: %w", err)
+	}
+
+	switch leader.Lock {
+	case types.LockFile:
+		return NewFileLock(leader.Path), nil
+	case types.LockHTTP:
+		return NewHTTPLease(leader.URL, leader.ID, ttl), nil
+	case types.LockCoolify:
+		client, ok := coolifyClients[leader.Instance]
+		if !ok {
+			return nil, fmt.Errorf("no Coolify client for instance '%s'", leader.Instance)
+		}
+		return NewCoolifyMarker(client, leader.App, leader.ID, ttl), nil
+	case types.LockNone, "":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("invalid leader lock '%s'", leader.Lock)
+}
+
+// RenewInterval returns how often a lock is renewed by its holder and retried
+// by the others, well within the lease duration
+func RenewInterval(cfg types.LeaderConfig) time.Duration {
+	ttl, err := time.ParseDuration(cfg.TTL)
+	if err != nil || ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return ttl / 3
+}
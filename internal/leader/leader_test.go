@@ -0,0 +1,195 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestFileLock(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data", "patrol.lock")
+	first, second := NewFileLock(path), NewFileLock(path)
+
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected first lock to be acquired, got %v, %v", held, err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected renewal to succeed, got %v, %v", held, err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || held {
+		t.Fatalf("expected second lock to be refused, got %v, %v", held, err)
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected second lock to be acquired after release, got %v, %v", held, err)
+	}
+}
+
+// leaseServer is a minimal lease endpoint
+type leaseServer struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+}
+
+func (s *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req leaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder != "" && s.holder != req.Holder && time.Now().Before(s.expires) {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.holder, s.expires = req.Holder, time.Now().Add(time.Duration(req.TTL)*time.Second)
+	case http.MethodDelete:
+		s.holder = ""
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestHTTPLease(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(&leaseServer{})
+	defer server.Close()
+
+	first := NewHTTPLease(server.URL, "patrol-a", 30*time.Second)
+	second := NewHTTPLease(server.URL, "patrol-b", 30*time.Second)
+
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected lease to be acquired, got %v, %v", held, err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || held {
+		t.Fatalf("expected lease to be refused, got %v, %v", held, err)
+	}
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected lease to be acquired after release, got %v, %v", held, err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if _, err := NewHTTPLease(failing.URL, "patrol-a", 30*time.Second).Acquire(ctx); err == nil {
+		t.Error("expected error for failing lease endpoint")
+	}
+}
+
+func TestCoolifyMarker(t *testing.T) {
+	ctx := context.Background()
+	var (
+		mu    sync.Mutex
+		value string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(w).Encode([]coolify.EnvResponse{{Key: MarkerKey, Value: value}})
+		case http.MethodPatch:
+			var req coolify.EnvUpdateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			value = req.Value
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	client := coolify.NewClient(server.URL, "token")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := NewCoolifyMarker(client, "patrol-uuid", "patrol-a", 30*time.Second)
+	second := NewCoolifyMarker(client, "patrol-uuid", "patrol-b", 30*time.Second)
+	first.now = func() time.Time { return now }
+	second.now = func() time.Time { return now }
+
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected empty marker to be taken, got %v, %v", held, err)
+	}
+	if value != "patrol-a 2026-03-01T12:00:30Z" {
+		t.Errorf("unexpected marker '%s'", value)
+	}
+	if held, err := second.Acquire(ctx); err != nil || held {
+		t.Fatalf("expected marker to be refused, got %v, %v", held, err)
+	}
+
+	// An expired lease is taken over
+	now = now.Add(time.Minute)
+	if held, err := second.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected expired marker to be taken over, got %v, %v", held, err)
+	}
+
+	// Only the holder releases the marker
+	if err := first.Release(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || held {
+		t.Fatalf("expected marker to stay with patrol-b, got %v, %v", held, err)
+	}
+	if err := second.Release(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if held, err := first.Acquire(ctx); err != nil || !held {
+		t.Fatalf("expected released marker to be taken, got %v, %v", held, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	clients := map[string]*coolify.Client{"default": coolify.NewClient("http://coolify:8000", "token")}
+	tests := []struct {
+		name    string
+		leader  types.LeaderConfig
+		want    string // Type of the lock
+		wantErr bool
+	}{
+		{name: "none", leader: types.LeaderConfig{Lock: types.LockNone, TTL: "30s"}, want: "<nil>"},
+		{name: "file", leader: types.LeaderConfig{Lock: types.LockFile, Path: "/data/patrol.lock", TTL: "30s"}, want: "*leader.FileLock"},
+		{name: "http", leader: types.LeaderConfig{Lock: types.LockHTTP, URL: "http://lease", TTL: "30s"}, want: "*leader.HTTPLease"},
+		{name: "coolify", leader: types.LeaderConfig{Lock: types.LockCoolify, App: "uuid", Instance: "default", TTL: "30s"}, want: "*leader.CoolifyMarker"},
+		{name: "unknown instance", leader: types.LeaderConfig{Lock: types.LockCoolify, App: "uuid", Instance: "other", TTL: "30s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock, err := New(&types.Config{Leader: tt.leader}, clients)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprintf("%T", lock); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if interval := RenewInterval(types.LeaderConfig{TTL: "30s"}); interval != 10*time.Second {
+		t.Errorf("expected renew interval 10s, got %v", interval)
+	}
+}
@@ -0,0 +1,174 @@
+package leader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// HTTPLease takes a lease from an HTTP endpoint. Acquire sends
+// PUT {"holder": id, "ttl": seconds}, answered with 2xx if the lease is free,
+// expired or already held by id, and with 409 Conflict or 423 Locked if
+// another holder has it. Release sends DELETE with the same body.
+type HTTPLease struct {
+	url        string
+	holder     string
+	ttl        time.Duration
+	httpClient *http.Client
+}
+
+// leaseRequest is the body of lease requests
+type leaseRequest struct {
+	Holder string `json:"holder"`
+	TTL    int    `json:"ttl"` // Seconds
+}
+
+// NewHTTPLease creates a lease on url held as holder for ttl after every renewal
+func NewHTTPLease(url, holder string, ttl time.Duration) *HTTPLease {
+	return &HTTPLease{
+		url:        url,
+		holder:     holder,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Acquire takes or renews the lease
+func (l *HTTPLease) Acquire(ctx context.Context) (bool, error) {
+	status, err := l.send(ctx, http.MethodPut)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case status >= 200 && status < 300:
+		return true, nil
+	case status == http.StatusConflict || status == http.StatusLocked:
+		return false, nil
+	}
+	return false, fmt.Errorf("lease endpoint returned status %d", status)
+}
+
+// Release ends the lease
+func (l *HTTPLease) Release(ctx context.Context) error {
+	status, err := l.send(ctx, http.MethodDelete)
+	if err != nil {
+		return err
+	}
+	if status >= 300 && status != http.StatusNotFound && status != http.StatusConflict {
+		return fmt.Errorf("lease endpoint returned status %d", status)
+	}
+	return nil
+}
+
+// send makes a lease request and returns the response status
+func (l *HTTPLease) send(ctx context.Context, method string) (int, error) {
+	body, err := json.Marshal(leaseRequest{Holder: l.holder, TTL: int(l.ttl.Seconds())})
+	if err != nil {
+		return 0, fmt.Errorf("encoding lease request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, l.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("creating lease request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("lease request failed: %w", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// MarkerKey is the environment variable holding the Coolify lease marker
+const MarkerKey = "PATROL_LEADER"
+
+// CoolifyMarker keeps a lease in an environment variable of a Coolify app,
+// usually patrol's own, as "<holder> <expiry>". The variable must exist.
+// Coolify can't compare and swap, so the marker is read back after writing
+// it and the last writer wins; renewing well within the lease keeps writes
+// of a waiting instance rare.
+type CoolifyMarker struct {
+	client *coolify.Client
+	uuid   string
+	holder string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewCoolifyMarker creates a lease kept in the app with uuid, held as holder
+// for ttl after every renewal
+func NewCoolifyMarker(client *coolify.Client, uuid, holder string, ttl time.Duration) *CoolifyMarker {
+	return &CoolifyMarker{client: client, uuid: uuid, holder: holder, ttl: ttl, now: time.Now}
+}
+
+// Acquire takes the lease if it is free, expired or already ours
+func (m *CoolifyMarker) Acquire(ctx context.Context) (bool, error) {
+	holder, expires, err := m.read(ctx)
+	if err != nil {
+		return false, err
+	}
+	if holder != "" && holder != m.holder && m.now().Before(expires) {
+		return false, nil
+	}
+
+	if err := m.write(ctx, m.now().Add(m.ttl)); err != nil {
+		return false, err
+	}
+	holder, _, err = m.read(ctx)
+	if err != nil {
+		return false, err
+	}
+	return holder == m.holder, nil
+}
+
+// Release expires the lease if it is ours
+func (m *CoolifyMarker) Release(ctx context.Context) error {
+	holder, _, err := m.read(ctx)
+	if err != nil {
+		return err
+	}
+	if holder != m.holder {
+		return nil
+	}
+	return m.write(ctx, m.now())
+}
+
+// read returns the current holder of the marker and when its lease expires.
+// An empty or malformed marker is free.
+func (m *CoolifyMarker) read(ctx context.Context) (string, time.Time, error) {
+	value, err := m.client.GetEnv(ctx, types.ResourceApplication, m.uuid, MarkerKey)
+	if errors.Is(err, coolify.ErrNotFound) {
+		return "", time.Time{}, fmt.Errorf("reading lease marker, add an empty %s variable to app %s: %w", MarkerKey, m.uuid, err)
+	}
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("reading lease marker: %w", err)
+	}
+
+	holder, expiry, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok {
+		return "", time.Time{}, nil
+	}
+	expires, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return "", time.Time{}, nil
+	}
+	return holder, expires, nil
+}
+
+// write sets the marker to this holder until expires
+func (m *CoolifyMarker) write(ctx context.Context, expires time.Time) error {
+	value := m.holder + " " + expires.UTC().Format(time.RFC3339)
+	if err := m.client.UpdateEnv(ctx, types.ResourceApplication, m.uuid, MarkerKey, value); err != nil {
+		return fmt.Errorf("writing lease marker: %w", err)
+	}
+	return nil
+}
@@ -58,6 +58,23 @@ func NewClient() *Client {
 	}
 }
 
+// NewClientWithHTTPClient creates a registry client sending its requests
+// through the given HTTP client
+func NewClientWithHTTPClient(httpClient *http.Client) *Client {
+	return &Client{
+		httpClient: httpClient,
+		userAgent:  "coolify-patrol/1.0",
+	}
+}
+
+// Host returns the registry an image is fetched from, as used by GetTags
+func Host(image string) string {
+	if strings.HasPrefix(image, "ghcr.io/") {
+		return "ghcr.io"
+	}
+	return "docker.io"
+}
+
 // GetTags fetches all tags for an image from the appropriate registry
 func (c *Client) GetTags(ctx context.Context, image string) ([]types.RegistryTag, error) {
 	if strings.HasPrefix(image, "ghcr.io/") {
@@ -193,25 +210,37 @@ func (c *Client) getGHCRTags(ctx context.Context, image string) ([]types.Registr
 
 // GetLatestTag finds the latest tag from registry, filtering prereleases
 func (c *Client) GetLatestTag(ctx context.Context, image string, excludePatterns []string) (string, error) {
-	tags, err := c.GetTags(ctx, image)
+	tag, err := c.GetLatest(ctx, image, excludePatterns)
 	if err != nil {
 		return "", err
 	}
+	return tag.Name, nil
+}
+
+// GetLatest is like GetLatestTag but also returns the tag's digest, if the
+// registry lists it
+func (c *Client) GetLatest(ctx context.Context, image string, excludePatterns []string) (types.RegistryTag, error) {
+	tags, err := c.GetTags(ctx, image)
+	if err != nil {
+		return types.RegistryTag{}, err
+	}
 	
 	if len(tags) == 0 {
-		return "", fmt.Errorf("no tags found for image %s", image)
+		return types.RegistryTag{}, fmt.Errorf("no tags found for image %s", image)
 	}
 	
 	// Extract tag names
 	var tagNames []string
+	digests := make(map[string]string, len(tags))
 	for _, tag := range tags {
 		tagNames = append(tagNames, tag.Name)
+		digests[tag.Name] = tag.Digest
 	}
 	
 	// Filter prerelease tags
 	filtered := filterTags(tagNames, excludePatterns)
 	if len(filtered) == 0 {
-		return "", fmt.Errorf("no stable tags found after filtering")
+		return types.RegistryTag{}, fmt.Errorf("no stable tags found after filtering")
 	}
 	
 	// Sort by semver if possible, otherwise lexicographically  
@@ -219,7 +248,7 @@ func (c *Client) GetLatestTag(ctx context.Context, image string, excludePatterns
 		return compareVersions(filtered[i], filtered[j]) > 0
 	})
 	
-	return filtered[0], nil
+	return types.RegistryTag{Name: filtered[0], Digest: digests[filtered[0]]}, nil
 }
 
 // filterTags removes tags matching exclude patterns
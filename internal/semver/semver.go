@@ -145,6 +145,16 @@ func IsUpdateAllowed(current, latest string, policy types.UpdatePolicy, pin stri
 	}
 }
 
+// NeedsApproval reports whether an update is held back by the policy alone, so
+// a human may still approve it. Updates crossing a pin are never proposed.
+func NeedsApproval(current, latest string, policy types.UpdatePolicy, pin string) bool {
+	if allowed, _ := IsUpdateAllowed(current, latest, policy, pin); allowed {
+		return false
+	}
+	allowed, _ := IsUpdateAllowed(current, latest, types.AutoAll, pin)
+	return allowed
+}
+
 // FilterPrereleaseTags removes prerelease tags based on exclude patterns
 func FilterPrereleaseTags(tags []string, excludePatterns []string) []string {
 	var filtered []string
@@ -243,6 +243,31 @@ func TestIsUpdateAllowed(t *testing.T) {
 	}
 }
 
+func TestNeedsApproval(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		latest   string
+		policy   types.UpdatePolicy
+		pin      string
+		expected bool
+	}{
+		{name: "allowed update", current: "1.2.3", latest: "1.2.4", policy: types.AutoPatch, expected: false},
+		{name: "major update with auto-minor", current: "1.2.3", latest: "2.0.0", policy: types.AutoMinor, expected: true},
+		{name: "notify-only", current: "1.2.3", latest: "1.2.4", policy: types.NotifyOnly, expected: true},
+		{name: "crossing the pin", current: "17.1.0", latest: "18.0.0", policy: types.NotifyOnly, pin: "17", expected: false},
+		{name: "not newer", current: "1.2.3", latest: "1.2.3", policy: types.NotifyOnly, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsApproval(tt.current, tt.latest, tt.policy, tt.pin); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
 func TestFilterPrereleaseTags(t *testing.T) {
 	tags := []string{
 		"1.0.0",
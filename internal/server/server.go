@@ -2,12 +2,19 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/chrisdietr/coolify-patrol/internal/state"
 	"github.com/chrisdietr/coolify-patrol/internal/watcher"
 	"github.com/chrisdietr/coolify-patrol/pkg/types"
 )
@@ -18,19 +25,34 @@ type Server struct {
 	logger  *slog.Logger
 	server  *http.Server
 	version string
+	token   atomic.Pointer[string] // Required by endpoints that change state, if set
 }
 
-// NewServer creates a new HTTP server
-func NewServer(watcher *watcher.Watcher, logger *slog.Logger, port int, version string) *Server {
+// NewServer creates a new HTTP server. If apiToken is set, requests to
+// endpoints that change state must send it as a bearer token. The job
+// endpoints, which can deploy any tag, are refused without one.
+func NewServer(watcher *watcher.Watcher, logger *slog.Logger, port int, version, apiToken string) *Server {
 	s := &Server{
 		watcher: watcher,
 		logger:  logger,
 		version: version,
 	}
+	s.SetAPIToken(apiToken)
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", s.healthHandler)
 	mux.HandleFunc("/status", s.statusHandler)
+	mux.HandleFunc("/history", s.historyHandler)
+	mux.HandleFunc("/pause", s.authorize(s.leaderOnly(s.pauseHandler)))
+	mux.HandleFunc("/resume", s.authorize(s.leaderOnly(s.resumeHandler)))
+	mux.HandleFunc("GET /pending", s.pendingHandler)
+	mux.HandleFunc("POST /pending/{id}/approve", s.authorize(s.leaderOnly(s.decisionHandler(s.watcher.Approve))))
+	mux.HandleFunc("POST /pending/{id}/reject", s.authorize(s.leaderOnly(s.decisionHandler(s.watcher.Reject))))
+	mux.HandleFunc("POST /check", s.requireToken(s.authorize(s.leaderOnly(s.jobHandler(types.JobCheck)))))
+	mux.HandleFunc("POST /apps/{uuid}/check", s.requireToken(s.authorize(s.leaderOnly(s.jobHandler(types.JobCheckApp)))))
+	mux.HandleFunc("POST /apps/{uuid}/update", s.requireToken(s.authorize(s.leaderOnly(s.jobHandler(types.JobUpdateApp)))))
+	mux.HandleFunc("GET /jobs/{id}", s.jobStatusHandler)
+	mux.HandleFunc("POST /breaker/reset", s.authorize(s.leaderOnly(s.breakerResetHandler)))
 
 	s.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
@@ -42,9 +64,18 @@ func NewServer(watcher *watcher.Watcher, logger *slog.Logger, port int, version
 	return s
 }
 
+// SetAPIToken replaces the token required by endpoints that change state,
+// an empty token disables the check
+func (s *Server) SetAPIToken(apiToken string) {
+	s.token.Store(&apiToken)
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	s.logger.Info("Starting HTTP server", "addr", s.server.Addr)
+	if *s.token.Load() == "" {
+		s.logger.Warn("No API token set, the check and update endpoints are disabled and the other endpoints that change state accept any request")
+	}
 	return s.server.ListenAndServe()
 }
 
@@ -89,4 +120,274 @@ func (s *Server) statusHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-}
\ No newline at end of file
+}
+
+// defaultHistoryLimit is the number of history entries returned without a limit parameter
+const defaultHistoryLimit = 100
+
+// historyHandler handles GET /history?app=&since=&limit=
+func (s *Server) historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	since, err := state.ParseSince(params.Get("since"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if value := params.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			http.Error(w, fmt.Sprintf("invalid limit '%s': must be a positive number", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := types.HistoryResponse{
+		Entries: s.watcher.History(types.HistoryQuery{
+			App:   params.Get("app"),
+			Since: since,
+			Limit: limit,
+		}),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode history response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// authorize rejects requests without the configured bearer token
+func (s *Server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		expected := *s.token.Load()
+		if expected == "" {
+			next(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireToken refuses requests while no API token is configured
+func (s *Server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if *s.token.Load() == "" {
+			http.Error(w, "Set api_token to enable this endpoint", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// leaderOnly rejects changes while another instance holds the leader lock,
+// this one only serves its status then
+func (s *Server) leaderOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.watcher.Standby() {
+			http.Error(w, "Standing by, another patrol instance holds the leader lock", http.StatusServiceUnavailable)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// pauseHandler handles POST /pause, stopping all updates until resumed
+func (s *Server) pauseHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.watcher.Pause(request.Reason, request.Actor)
+	s.writeStatus(w)
+}
+
+// resumeHandler handles POST /resume
+func (s *Server) resumeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.watcher.Resume(request.Actor)
+	s.writeStatus(w)
+}
+
+// breakerResetHandler handles POST /breaker/reset?app=, closing the circuit
+// breaker of an app or all breakers
+func (s *Server) breakerResetHandler(w http.ResponseWriter, r *http.Request) {
+	request, err := decodeActionRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = s.watcher.ResetBreaker(r.URL.Query().Get("app"), request.Actor)
+	if errors.Is(err, watcher.ErrBreakerNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeStatus(w)
+}
+
+// pendingHandler handles GET /pending
+func (s *Server) pendingHandler(w http.ResponseWriter, r *http.Request) {
+	response := types.PendingResponse{Updates: s.watcher.Pending()}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Error("Failed to encode pending response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// decisionHandler handles POST /pending/{id}/approve and /pending/{id}/reject
+// with decide, the watcher's Approve or Reject
+func (s *Server) decisionHandler(decide func(id, actor string) (types.PendingUpdate, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := decodeActionRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		decided, err := decide(r.PathValue("id"), request.Actor)
+		if errors.Is(err, watcher.ErrPendingNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(decided); err != nil {
+			s.logger.Error("Failed to encode decision response", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// jobHandler handles POST /check, /apps/{uuid}/check and /apps/{uuid}/update
+// by queuing a job of the given kind. App jobs take an optional instance
+// parameter, updates an optional tag.
+func (s *Server) jobHandler(kind types.JobKind) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := decodeActionRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		job := types.Job{Kind: kind, Actor: request.Actor}
+		if kind != types.JobCheck {
+			job.UUID = r.PathValue("uuid")
+			job.Instance = r.URL.Query().Get("instance")
+		}
+		if kind == types.JobUpdateApp {
+			job.Tag = r.URL.Query().Get("tag")
+		}
+
+		queued, err := s.watcher.Enqueue(job)
+		if errors.Is(err, watcher.ErrJobConflict) {
+			w.Header().Set("Location", "/jobs/"+queued.ID)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, watcher.ErrJobQueueFull) || errors.Is(err, watcher.ErrNotLeader) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/jobs/"+queued.ID)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(queued); err != nil {
+			s.logger.Error("Failed to encode job response", "error", err)
+		}
+	}
+}
+
+// jobStatusHandler handles GET /jobs/{id}
+func (s *Server) jobStatusHandler(w http.ResponseWriter, r *http.Request) {
+	job, err := s.watcher.Job(r.PathValue("id"))
+	if errors.Is(err, watcher.ErrJobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		s.logger.Error("Failed to encode job response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// writeStatus responds with the current status
+func (s *Server) writeStatus(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.watcher.GetStatus()); err != nil {
+		s.logger.Error("Failed to encode status response", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
+
+// decodeActionRequest reads the optional JSON body of a request that changes
+// state. The reason may also be given as a query parameter.
+func decodeActionRequest(r *http.Request) (types.ActionRequest, error) {
+	var request types.ActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return request, fmt.Errorf("invalid request body: %w", err)
+	}
+	if reason := r.URL.Query().Get("reason"); reason != "" {
+		request.Reason = reason
+	}
+	if request.Actor == "" {
+		request.Actor = types.ActorAPI
+	}
+	return request, nil
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/internal/watcher"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func newTestServer(t *testing.T, apiToken string) (*httptest.Server, *watcher.Watcher) {
+	t.Helper()
+	cfg := &types.Config{Defaults: types.DefaultsConfig{Interval: "1h"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := watcher.NewWatcher(cfg, nil, nil, state.NewMemoryStore(), logger, false)
+	server := httptest.NewServer(NewServer(w, logger, 0, "test", apiToken).server.Handler)
+	t.Cleanup(server.Close)
+	return server, w
+}
+
+// request sends a request with the bearer token, if any, and decodes a JSON
+// job from the response
+func request(t *testing.T, method, url, token string) (*http.Response, types.Job) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var job types.Job
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+	}
+	return resp, job
+}
+
+func TestJobEndpointsRequireToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		apiToken string
+		token    string
+		status   int
+	}{
+		{name: "no api token configured", apiToken: "", token: "", status: http.StatusForbidden},
+		{name: "no api token configured, token sent", apiToken: "", token: "secret", status: http.StatusForbidden},
+		{name: "missing token", apiToken: "secret", token: "", status: http.StatusUnauthorized},
+		{name: "wrong token", apiToken: "secret", token: "guess", status: http.StatusUnauthorized},
+		{name: "valid token", apiToken: "secret", token: "secret", status: http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, tt.apiToken)
+			for _, path := range []string{"/check", "/apps/web-uuid/check", "/apps/web-uuid/update"} {
+				if resp, _ := request(t, http.MethodPost, server.URL+path, tt.token); resp.StatusCode != tt.status {
+					t.Errorf("expected status %d for %s, got %d", tt.status, path, resp.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func TestJobEndpoints(t *testing.T) {
+	server, _ := newTestServer(t, "secret")
+
+	resp, queued := request(t, http.MethodPost, server.URL+"/apps/web-uuid/update?tag=1.1.0&instance=prod", "secret")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", resp.StatusCode)
+	}
+	if queued.ID == "" || queued.Kind != types.JobUpdateApp || queued.UUID != "web-uuid" || queued.Instance != "prod" ||
+		queued.Tag != "1.1.0" || queued.Status != types.JobQueued || queued.Actor != types.ActorAPI {
+		t.Errorf("unexpected job %+v", queued)
+	}
+	if location := resp.Header.Get("Location"); location != "/jobs/"+queued.ID {
+		t.Errorf("expected location of the job, got '%s'", location)
+	}
+
+	// A second update of the app points to the first one
+	resp, _ = request(t, http.MethodPost, server.URL+"/apps/web-uuid/update", "secret")
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status 409, got %d", resp.StatusCode)
+	}
+	if location := resp.Header.Get("Location"); location != "/jobs/"+queued.ID {
+		t.Errorf("expected location of the queued job, got '%s'", location)
+	}
+
+	resp, job := request(t, http.MethodGet, server.URL+"/jobs/"+queued.ID, "")
+	if resp.StatusCode != http.StatusOK || job.ID != queued.ID || job.Status != types.JobQueued {
+		t.Errorf("expected the queued job, got %d %+v", resp.StatusCode, job)
+	}
+
+	if resp, _ := request(t, http.MethodGet, server.URL+"/jobs/unknown", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown job, got %d", resp.StatusCode)
+	}
+}
+
+func TestPollJob(t *testing.T) {
+	server, w := newTestServer(t, "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Start(ctx, false) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	resp, queued := request(t, http.MethodPost, server.URL+"/check", "secret")
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", resp.StatusCode)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, job := request(t, http.MethodGet, server.URL+resp.Header.Get("Location"), "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if job.FinishedAt != nil {
+			if job.Status != types.JobSucceeded || job.Result != "0 updates applied" || job.StartedAt == nil {
+				t.Errorf("unexpected job outcome %+v", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s didn't finish", queued.ID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,61 @@
+package state
+
+import (
+	"slices"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Breaker counts the consecutive failed updates of an app, or of all apps,
+// and records when they opened its circuit breaker
+type Breaker struct {
+	Failures  []time.Time `json:"failures,omitempty"` // Within the breaker window, oldest first
+	LastError string      `json:"last_error,omitempty"`
+	OpenedAt  *time.Time  `json:"opened_at,omitempty"`
+	ClosesAt  *time.Time  `json:"closes_at,omitempty"` // Set together with OpenedAt
+}
+
+// Open reports whether the breaker stops updates at t. A nil breaker is closed.
+func (b *Breaker) Open(t time.Time) bool {
+	return b != nil && b.ClosesAt != nil && t.Before(*b.ClosesAt)
+}
+
+// Fail records an update that failed at t with message and reports whether
+// it opened the breaker. Failures older than window are forgotten. Without a
+// success in between, a breaker that closed after its timeout still holds its
+// failures, so the next failure opens it again.
+func (b *Breaker) Fail(t time.Time, message string, threshold int, window, timeout time.Duration) bool {
+	b.Failures = slices.DeleteFunc(b.Failures, func(failure time.Time) bool {
+		return t.Sub(failure) >= window
+	})
+	b.Failures = append(b.Failures, t)
+	if excess := len(b.Failures) - threshold; excess > 0 {
+		b.Failures = slices.Clone(b.Failures[excess:])
+	}
+	b.LastError = message
+
+	if b.Open(t) || len(b.Failures) < threshold {
+		return false
+	}
+	closesAt := t.Add(timeout)
+	b.OpenedAt, b.ClosesAt = &t, &closesAt
+	return true
+}
+
+// Status describes the breaker at t, nil if it has nothing to report
+func (b *Breaker) Status(t time.Time) *types.BreakerStatus {
+	if b == nil || (len(b.Failures) == 0 && b.OpenedAt == nil) {
+		return nil
+	}
+	status := &types.BreakerStatus{
+		Open:      b.Open(t),
+		Failures:  len(b.Failures),
+		LastError: b.LastError,
+	}
+	if status.Open {
+		openedAt, closesAt := *b.OpenedAt, *b.ClosesAt
+		status.OpenedAt, status.ClosesAt = &openedAt, &closesAt
+	}
+	return status
+}
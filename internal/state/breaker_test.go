@@ -0,0 +1,49 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var breaker Breaker
+
+	if breaker.Fail(now, "first", 3, time.Hour, 6*time.Hour) {
+		t.Fatal("expected breaker to stay closed after one failure")
+	}
+	// Too old to count with the next failures
+	now = now.Add(2 * time.Hour)
+	breaker.Fail(now, "second", 3, time.Hour, 6*time.Hour)
+	if breaker.Fail(now.Add(time.Minute), "third", 3, time.Hour, 6*time.Hour) {
+		t.Fatal("expected failures outside the window not to count")
+	}
+	if !breaker.Fail(now.Add(2*time.Minute), "fourth", 3, time.Hour, 6*time.Hour) {
+		t.Fatal("expected breaker to open after three failures within the window")
+	}
+	if !breaker.Open(now.Add(time.Hour)) {
+		t.Error("expected breaker to be open before its timeout")
+	}
+
+	status := breaker.Status(now.Add(time.Hour))
+	if status == nil || !status.Open || status.Failures != 3 || status.LastError != "fourth" || status.ClosesAt == nil {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// Closed after the timeout, the next failure opens it again
+	later := now.Add(7 * time.Hour)
+	if breaker.Open(later) {
+		t.Error("expected breaker to close after its timeout")
+	}
+	if status := breaker.Status(later); status.Open || status.ClosesAt != nil {
+		t.Errorf("expected closed status, got %+v", status)
+	}
+	if !breaker.Fail(later.Add(-30*time.Minute), "fifth", 3, 24*time.Hour, 6*time.Hour) {
+		t.Error("expected the first failure after the timeout to open the breaker again")
+	}
+
+	var closed *Breaker
+	if closed.Open(now) || closed.Status(now) != nil {
+		t.Error("expected nil breaker to be closed without status")
+	}
+}
@@ -0,0 +1,168 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// History retention: routine checks and skips are dropped after a week,
+// updates, failures and decisions after 90 days. Beyond MaxHistoryEntries the
+// oldest checks and skips go first.
+const (
+	RoutineHistoryRetention = 7 * 24 * time.Hour
+	HistoryRetention        = 90 * 24 * time.Hour
+	MaxHistoryEntries       = 5000
+)
+
+// AddHistory appends an entry to the history and drops the entries beyond the
+// retention. A check or skip identical to the app's previous entry only
+// updates that entry's LastSeen.
+func (s *State) AddHistory(entry types.HistoryEntry) {
+	if previous := s.lastHistoryOf(entry.Instance, entry.UUID); previous != nil && repeats(*previous, entry) {
+		seen := entry.Time
+		previous.LastSeen = &seen
+		previous.Repeats++
+	} else {
+		s.HistorySeq++
+		entry.ID = s.HistorySeq
+		s.History = append(s.History, entry)
+	}
+
+	// Expiry is checked hourly, the cap always
+	if len(s.History) > MaxHistoryEntries || entry.Time.Sub(s.prunedAt) >= time.Hour {
+		s.PruneHistory(entry.Time)
+	}
+}
+
+// PruneHistory drops the entries beyond the retention at now
+func (s *State) PruneHistory(now time.Time) {
+	s.prunedAt = now
+	kept := make([]types.HistoryEntry, 0, len(s.History))
+	routine := 0
+	for _, entry := range s.History {
+		retention := HistoryRetention
+		if isRoutine(entry.Event) {
+			retention = RoutineHistoryRetention
+		}
+		if lastSeen(entry).Before(now.Add(-retention)) {
+			continue
+		}
+		if isRoutine(entry.Event) {
+			routine++
+		}
+		kept = append(kept, entry)
+	}
+
+	// Over the cap, the oldest checks and skips go first, then the oldest of the rest
+	excess := len(kept) - MaxHistoryEntries
+	if excess > 0 && routine > 0 {
+		dropped := min(excess, routine)
+		trimmed := kept[:0]
+		for _, entry := range kept {
+			if dropped > 0 && isRoutine(entry.Event) {
+				dropped--
+				continue
+			}
+			trimmed = append(trimmed, entry)
+		}
+		kept = trimmed
+		excess = len(kept) - MaxHistoryEntries
+	}
+	if excess > 0 {
+		kept = kept[excess:]
+	}
+	s.History = kept
+}
+
+// numberHistory gives entries recorded before entries had IDs one
+func (s *State) numberHistory() {
+	for i := range s.History {
+		if s.History[i].ID == 0 {
+			s.HistorySeq++
+			s.History[i].ID = s.HistorySeq
+		}
+		s.HistorySeq = max(s.HistorySeq, s.History[i].ID)
+	}
+}
+
+// lastHistoryOf returns the most recent entry of an app, or nil
+func (s *State) lastHistoryOf(instance, uuid string) *types.HistoryEntry {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Instance == instance && s.History[i].UUID == uuid {
+			return &s.History[i]
+		}
+	}
+	return nil
+}
+
+// repeats reports whether entry only repeats previous, a check or skip with
+// the same outcome
+func repeats(previous, entry types.HistoryEntry) bool {
+	switch entry.Event {
+	case types.EventCheck, types.EventCheckFailed, types.EventSkip:
+	default:
+		return false
+	}
+	return previous.Event == entry.Event &&
+		previous.FromTag == entry.FromTag &&
+		previous.ToTag == entry.ToTag &&
+		previous.Policy == entry.Policy &&
+		previous.Reason == entry.Reason &&
+		previous.Actor == entry.Actor &&
+		previous.DryRun == entry.DryRun
+}
+
+// isRoutine reports whether event is a routine check or skip, which is kept
+// for a shorter time
+func isRoutine(event types.HistoryEvent) bool {
+	return event == types.EventCheck || event == types.EventSkip
+}
+
+// lastSeen returns the last occurrence of entry
+func lastSeen(entry types.HistoryEntry) time.Time {
+	if entry.LastSeen != nil {
+		return *entry.LastSeen
+	}
+	return entry.Time
+}
+
+// QueryHistory returns the entries matching query, newest first. Collapsed
+// entries match since with their last occurrence.
+func (s *State) QueryHistory(query types.HistoryQuery) []types.HistoryEntry {
+	entries := []types.HistoryEntry{}
+	for i := len(s.History) - 1; i >= 0; i-- {
+		entry := s.History[i]
+		if lastSeen(entry).Before(query.Since) {
+			continue
+		}
+		if query.App != "" && entry.App != query.App && entry.UUID != query.App {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if query.Limit > 0 && len(entries) == query.Limit {
+			break
+		}
+	}
+	return entries
+}
+
+// ParseSince parses the start of a history query: a duration before now like
+// "24h", a date like "2025-01-31" or an RFC 3339 timestamp
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since '%s': use a duration (24h), a date (2006-01-02) or an RFC 3339 time", value)
+}
@@ -0,0 +1,190 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestQueryHistory(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	state := New()
+	state.AddHistory(types.HistoryEntry{Time: base, App: "n8n", UUID: "n8n-uuid", Event: types.EventCheck})
+	state.AddHistory(types.HistoryEntry{Time: base.Add(time.Hour), App: "umami", UUID: "umami-uuid", Event: types.EventCheck})
+	state.AddHistory(types.HistoryEntry{Time: base.Add(2 * time.Hour), App: "n8n", UUID: "n8n-uuid", Event: types.EventUpdateStarted})
+	state.AddHistory(types.HistoryEntry{Time: base.Add(2 * time.Hour), App: "n8n", UUID: "n8n-uuid", Event: types.EventUpdateSucceeded})
+
+	tests := []struct {
+		name     string
+		query    types.HistoryQuery
+		expected []types.HistoryEvent
+	}{
+		{
+			name:     "everything newest first",
+			query:    types.HistoryQuery{},
+			expected: []types.HistoryEvent{types.EventUpdateSucceeded, types.EventUpdateStarted, types.EventCheck, types.EventCheck},
+		},
+		{
+			name:     "by app name",
+			query:    types.HistoryQuery{App: "umami"},
+			expected: []types.HistoryEvent{types.EventCheck},
+		},
+		{
+			name:     "by uuid with limit",
+			query:    types.HistoryQuery{App: "n8n-uuid", Limit: 2},
+			expected: []types.HistoryEvent{types.EventUpdateSucceeded, types.EventUpdateStarted},
+		},
+		{
+			name:     "since",
+			query:    types.HistoryQuery{Since: base.Add(90 * time.Minute)},
+			expected: []types.HistoryEvent{types.EventUpdateSucceeded, types.EventUpdateStarted},
+		},
+		{
+			name:     "no match",
+			query:    types.HistoryQuery{App: "grafana"},
+			expected: []types.HistoryEvent{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := state.QueryHistory(tt.query)
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("expected %d entries, got %d", len(tt.expected), len(entries))
+			}
+			for i, entry := range entries {
+				if entry.Event != tt.expected[i] {
+					t.Errorf("entry %d: expected event '%s', got '%s'", i, tt.expected[i], entry.Event)
+				}
+			}
+		})
+	}
+}
+
+func TestAddHistoryCapsEntries(t *testing.T) {
+	state := New()
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxHistoryEntries+10; i++ {
+		state.AddHistory(types.HistoryEntry{Time: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	if len(state.History) != MaxHistoryEntries {
+		t.Fatalf("expected %d entries, got %d", MaxHistoryEntries, len(state.History))
+	}
+	if first := state.History[0].Time; !first.Equal(base.Add(10 * time.Second)) {
+		t.Errorf("expected oldest entries to be dropped, first entry is from %v", first)
+	}
+}
+
+func TestAddHistoryCollapsesRepeats(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	check := func(app string, minutes int) types.HistoryEntry {
+		return types.HistoryEntry{Time: base.Add(time.Duration(minutes) * time.Minute), App: app, UUID: app + "-uuid", Event: types.EventCheck, FromTag: "1.0"}
+	}
+
+	state := New()
+	state.AddHistory(check("n8n", 0))
+	state.AddHistory(check("umami", 0))
+	state.AddHistory(check("n8n", 15))
+	state.AddHistory(check("n8n", 30))
+
+	if len(state.History) != 2 {
+		t.Fatalf("expected repeated checks to be collapsed, got %d entries", len(state.History))
+	}
+	first := state.History[0]
+	if first.Repeats != 2 || first.LastSeen == nil || !first.LastSeen.Equal(base.Add(30*time.Minute)) {
+		t.Errorf("expected 2 repeats last seen at 12:30, got %+v", first)
+	}
+	if entries := state.QueryHistory(types.HistoryQuery{App: "n8n", Since: base.Add(20 * time.Minute)}); len(entries) != 1 {
+		t.Errorf("expected collapsed entry to match by its last occurrence, got %d entries", len(entries))
+	}
+
+	// A different outcome or anything in between starts a new entry
+	state.AddHistory(types.HistoryEntry{Time: base.Add(45 * time.Minute), App: "n8n", UUID: "n8n-uuid", Event: types.EventUpdateSucceeded})
+	state.AddHistory(check("n8n", 60))
+	updated := check("n8n", 75)
+	updated.FromTag = "1.1"
+	state.AddHistory(updated)
+	if len(state.History) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(state.History))
+	}
+	for i, entry := range state.History {
+		if entry.ID != int64(i+1) {
+			t.Errorf("expected entry %d to have ID %d, got %d", i, i+1, entry.ID)
+		}
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	state := New()
+	state.History = []types.HistoryEntry{
+		{ID: 1, Time: now.Add(-100 * 24 * time.Hour), Event: types.EventUpdateSucceeded},
+		{ID: 2, Time: now.Add(-30 * 24 * time.Hour), Event: types.EventUpdateFailed},
+		{ID: 3, Time: now.Add(-8 * 24 * time.Hour), Event: types.EventCheck},
+		{ID: 4, Time: now.Add(-8 * 24 * time.Hour), Event: types.EventSkip, LastSeen: &now},
+		{ID: 5, Time: now.Add(-time.Hour), Event: types.EventApproved},
+	}
+
+	state.PruneHistory(now)
+	var ids []int64
+	for _, entry := range state.History {
+		ids = append(ids, entry.ID)
+	}
+	if fmt.Sprint(ids) != "[2 4 5]" {
+		t.Errorf("expected entries 2, 4 and 5 to be kept, got %v", ids)
+	}
+
+	// Over the cap, checks go before older updates
+	state.History = nil
+	for i := 0; i < MaxHistoryEntries; i++ {
+		state.History = append(state.History, types.HistoryEntry{ID: int64(i + 1), Time: now, Event: types.EventUpdateSucceeded})
+	}
+	state.History = append(state.History, types.HistoryEntry{ID: MaxHistoryEntries + 1, Time: now, Event: types.EventCheck})
+	state.History = append(state.History, types.HistoryEntry{ID: MaxHistoryEntries + 2, Time: now, Event: types.EventUpdateFailed})
+	state.PruneHistory(now)
+	if len(state.History) != MaxHistoryEntries || state.History[0].ID != 2 {
+		t.Fatalf("expected the check and the oldest update to be dropped, got %d entries from %d", len(state.History), state.History[0].ID)
+	}
+	for _, entry := range state.History {
+		if entry.Event == types.EventCheck {
+			t.Error("expected the check to be dropped first")
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value     string
+		expected  time.Time
+		expectErr bool
+	}{
+		{value: "", expected: time.Time{}},
+		{value: "24h", expected: now.Add(-24 * time.Hour)},
+		{value: "2026-03-03", expected: time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)},
+		{value: "2026-03-03T08:30:00Z", expected: time.Date(2026, 3, 3, 8, 30, 0, 0, time.UTC)},
+		{value: "last tuesday", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseSince(tt.value, now)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// MaxPending caps the pending updates and decisions kept in the state, the
+// oldest are dropped first
+const MaxPending = 1000
+
+// PendingID identifies the update of an app to a tag. It is derived from
+// both, so the same update always gets the same ID.
+func PendingID(instance, uuid, toTag string) string {
+	sum := sha256.Sum256([]byte(instance + "/" + uuid + "@" + toTag))
+	return hex.EncodeToString(sum[:6])
+}
+
+// FindPending returns the record with the given ID, or nil
+func (s *State) FindPending(id string) *types.PendingUpdate {
+	for i := range s.Pending {
+		if s.Pending[i].ID == id {
+			return &s.Pending[i]
+		}
+	}
+	return nil
+}
+
+// ApprovedFor returns the approved update of an app, or nil
+func (s *State) ApprovedFor(instance, uuid string) *types.PendingUpdate {
+	for i := range s.Pending {
+		record := &s.Pending[i]
+		if record.Instance == instance && record.UUID == uuid && record.Status == types.ApprovalApproved {
+			return record
+		}
+	}
+	return nil
+}
+
+// RequestApproval records an update waiting for approval, replacing other
+// undecided updates of the same app. An existing record for the same update
+// is returned unchanged, so rejected versions stay rejected, except that an
+// undecided one takes the current digest of its tag.
+func (s *State) RequestApproval(update types.PendingUpdate) types.PendingUpdate {
+	update.ID = PendingID(update.Instance, update.UUID, update.ToTag)
+	if existing := s.FindPending(update.ID); existing != nil {
+		if existing.Status == types.ApprovalPending && update.Digest != "" {
+			existing.Digest = update.Digest
+		}
+		return *existing
+	}
+
+	s.Pending = slices.DeleteFunc(s.Pending, func(record types.PendingUpdate) bool {
+		return record.Instance == update.Instance && record.UUID == update.UUID && record.Status == types.ApprovalPending
+	})
+
+	update.Status = types.ApprovalPending
+	s.Pending = append(s.Pending, update)
+	if excess := len(s.Pending) - MaxPending; excess > 0 {
+		s.Pending = slices.Clone(s.Pending[excess:])
+	}
+	return update
+}
+
+// Decide approves or rejects a recorded update. Approving an update of an app
+// withdraws the approval of any other update of it.
+func (s *State) Decide(id string, status types.ApprovalStatus, actor string, now time.Time) (types.PendingUpdate, bool) {
+	record := s.FindPending(id)
+	if record == nil {
+		return types.PendingUpdate{}, false
+	}
+
+	if status == types.ApprovalApproved {
+		if approved := s.ApprovedFor(record.Instance, record.UUID); approved != nil && approved.ID != id {
+			approved.Status = types.ApprovalPending
+			approved.DecidedAt = nil
+			approved.DecidedBy = ""
+		}
+	}
+
+	record.Status = status
+	record.DecidedAt = &now
+	record.DecidedBy = actor
+	return *record, true
+}
+
+// Reopen returns an approved update to pending with the new digest of its
+// tag, which was pushed again after the approval
+func (s *State) Reopen(id, digest string, now time.Time) (types.PendingUpdate, bool) {
+	record := s.FindPending(id)
+	if record == nil || record.Status != types.ApprovalApproved {
+		return types.PendingUpdate{}, false
+	}
+
+	record.Status = types.ApprovalPending
+	record.Digest = digest
+	record.CreatedAt = now
+	record.DecidedAt = nil
+	record.DecidedBy = ""
+	return *record, true
+}
+
+// SettlePending drops the undecided and approved updates of an app, once it
+// was updated. Rejections are kept.
+func (s *State) SettlePending(instance, uuid string) {
+	s.Pending = slices.DeleteFunc(s.Pending, func(record types.PendingUpdate) bool {
+		return record.Instance == instance && record.UUID == uuid && record.Status != types.ApprovalRejected
+	})
+}
+
+// ListPending returns all recorded updates, newest first
+func (s *State) ListPending() []types.PendingUpdate {
+	updates := slices.Clone(s.Pending)
+	slices.Reverse(updates)
+	if updates == nil {
+		updates = []types.PendingUpdate{}
+	}
+	return updates
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestPendingLifecycle(t *testing.T) {
+	s := New()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", App: "postgres", FromTag: "16.4", ToTag: "17.0", CreatedAt: now})
+	if first.ID != PendingID("default", "pg", "17.0") || first.Status != types.ApprovalPending {
+		t.Fatalf("unexpected pending update %+v", first)
+	}
+
+	// A newer version replaces the undecided one
+	second := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", App: "postgres", FromTag: "16.4", ToTag: "17.1", CreatedAt: now})
+	if len(s.Pending) != 1 || s.Pending[0].ID != second.ID {
+		t.Fatalf("expected only the newer update to be pending, got %+v", s.Pending)
+	}
+
+	rejected, ok := s.Decide(second.ID, types.ApprovalRejected, "alice", now)
+	if !ok || rejected.Status != types.ApprovalRejected || rejected.DecidedBy != "alice" {
+		t.Fatalf("unexpected rejection %+v", rejected)
+	}
+
+	// The rejected version isn't proposed again
+	if again := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", ToTag: "17.1"}); again.Status != types.ApprovalRejected {
+		t.Errorf("expected rejected update to stay rejected, got %+v", again)
+	}
+
+	third := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", App: "postgres", FromTag: "16.4", ToTag: "17.2", CreatedAt: now})
+	if _, ok := s.Decide(third.ID, types.ApprovalApproved, "bob", now); !ok {
+		t.Fatal("expected approval to succeed")
+	}
+	if approved := s.ApprovedFor("default", "pg"); approved == nil || approved.ToTag != "17.2" {
+		t.Errorf("expected 17.2 to be approved, got %+v", approved)
+	}
+	if _, ok := s.Decide("unknown", types.ApprovalApproved, "bob", now); ok {
+		t.Error("expected unknown ID to fail")
+	}
+
+	if list := s.ListPending(); len(list) != 2 || list[0].ToTag != "17.2" {
+		t.Errorf("expected 2 records newest first, got %+v", list)
+	}
+
+	// Once updated, only the rejection is remembered
+	s.SettlePending("default", "pg")
+	if len(s.Pending) != 1 || s.Pending[0].Status != types.ApprovalRejected {
+		t.Errorf("expected only the rejection to remain, got %+v", s.Pending)
+	}
+}
+
+func TestPendingDigest(t *testing.T) {
+	s := New()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	update := types.PendingUpdate{Instance: "default", UUID: "pg", App: "postgres", FromTag: "16.4", ToTag: "17.0", Digest: "sha256:aaa", CreatedAt: now}
+
+	// Undecided updates follow their tag
+	first := s.RequestApproval(update)
+	update.Digest = "sha256:bbb"
+	if again := s.RequestApproval(update); again.ID != first.ID || again.Digest != "sha256:bbb" {
+		t.Fatalf("expected pending update to take the new digest, got %+v", again)
+	}
+
+	// Approved ones keep the approved image until reopened
+	if _, ok := s.Decide(first.ID, types.ApprovalApproved, "alice", now); !ok {
+		t.Fatal("expected approval to succeed")
+	}
+	update.Digest = "sha256:ccc"
+	if again := s.RequestApproval(update); again.Digest != "sha256:bbb" {
+		t.Errorf("expected approved digest to be kept, got %+v", again)
+	}
+	if _, ok := s.Reopen("unknown", "sha256:ccc", now); ok {
+		t.Error("expected unknown ID to fail")
+	}
+	reopened, ok := s.Reopen(first.ID, "sha256:ccc", now.Add(time.Hour))
+	if !ok || reopened.Status != types.ApprovalPending || reopened.Digest != "sha256:ccc" || reopened.DecidedAt != nil {
+		t.Errorf("expected update to await approval again, got %+v", reopened)
+	}
+	if s.ApprovedFor("default", "pg") != nil {
+		t.Error("expected no approved update after reopening")
+	}
+}
+
+func TestApproveWithdrawsOtherApproval(t *testing.T) {
+	s := New()
+	now := time.Now()
+
+	old := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", ToTag: "17.0"})
+	s.Decide(old.ID, types.ApprovalApproved, "alice", now)
+
+	// A newer version appears while the approved one wasn't applied yet
+	newer := s.RequestApproval(types.PendingUpdate{Instance: "default", UUID: "pg", ToTag: "17.1"})
+	if len(s.Pending) != 2 {
+		t.Fatalf("expected the approval to be kept next to the new request, got %+v", s.Pending)
+	}
+
+	s.Decide(newer.ID, types.ApprovalApproved, "alice", now)
+	if approved := s.ApprovedFor("default", "pg"); approved.ID != newer.ID {
+		t.Errorf("expected the newer update to be approved, got %+v", approved)
+	}
+	if record := s.FindPending(old.ID); record.Status != types.ApprovalPending || record.DecidedAt != nil {
+		t.Errorf("expected the older approval to be withdrawn, got %+v", record)
+	}
+}
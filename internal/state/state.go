@@ -0,0 +1,85 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// State is everything patrol remembers between restarts
+type State struct {
+	LastCheck     time.Time             `json:"last_check"`
+	Apps          map[string]*AppState  `json:"apps"`                     // Keyed by instance/uuid
+	ResolvedUUIDs map[string]string     `json:"resolved_uuids,omitempty"` // UUIDs of apps configured by name
+	History       []types.HistoryEntry  `json:"history,omitempty"`        // Oldest first, a FileStore keeps it in its own file
+	HistorySeq    int64                 `json:"history_seq,omitempty"`    // ID of the last history entry
+	Pause         *types.PauseInfo      `json:"pause,omitempty"`          // Set while updates are paused
+	Pending       []types.PendingUpdate `json:"pending,omitempty"`        // Updates awaiting approval and decisions, see MaxPending
+	Breaker       *Breaker              `json:"breaker,omitempty"`        // Failed updates of any apps
+	Queue         []types.QueuedUpdate  `json:"queue,omitempty"`          // Updates held back by the update limits, in order
+
+	prunedAt time.Time // Last time the history was pruned
+}
+
+// AppState is what patrol remembers about a single app
+type AppState struct {
+	LastCheck      time.Time        `json:"last_check"`
+	NextCheck      time.Time        `json:"next_check"`
+	LastUpdate     *time.Time       `json:"last_update,omitempty"`
+	CurrentTag     string           `json:"current_tag,omitempty"`
+	PreviousTag    string           `json:"previous_tag,omitempty"`    // Tag before the last update, for rollbacks
+	DeployedDigest string           `json:"deployed_digest,omitempty"` // Registry digest of the tag patrol last deployed
+	RebuiltFor     string           `json:"rebuilt_for,omitempty"`     // Base images the last rebuild was triggered for
+	Failures       int              `json:"failures,omitempty"`        // Consecutive failed checks or updates
+	LastError      string           `json:"last_error,omitempty"`
+	Status         *types.AppStatus `json:"status,omitempty"`  // Result of the last check
+	Breaker        *Breaker         `json:"breaker,omitempty"` // Failed updates of this app
+}
+
+// New returns an empty state
+func New() *State {
+	return &State{
+		Apps:          make(map[string]*AppState),
+		ResolvedUUIDs: make(map[string]string),
+	}
+}
+
+// App returns the state of an app, creating it if needed
+func (s *State) App(key string) *AppState {
+	app, ok := s.Apps[key]
+	if !ok {
+		app = &AppState{}
+		s.Apps[key] = app
+	}
+	return app
+}
+
+// Clone returns a deep copy of the state, which can be saved while the
+// original keeps changing
+func (s *State) Clone() (*State, error) {
+	withoutHistory := *s
+	withoutHistory.History = nil
+	data, err := json.Marshal(&withoutHistory)
+	if err != nil {
+		return nil, fmt.Errorf("encoding state: %w", err)
+	}
+	clone, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	clone.History = slices.Clone(s.History)
+	return clone, nil
+}
+
+// ensureMaps initializes maps missing from a decoded state
+func (s *State) ensureMaps() {
+	if s.Apps == nil {
+		s.Apps = make(map[string]*AppState)
+	}
+	if s.ResolvedUUIDs == nil {
+		s.ResolvedUUIDs = make(map[string]string)
+	}
+}
@@ -0,0 +1,295 @@
+package state
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// FileName is the name of the state file inside the state directory
+const FileName = "state.json"
+
+// Store loads and saves patrol's state
+type Store interface {
+	Load() (*State, error)
+	Save(state *State) error
+}
+
+// NewStore returns a file store in dir, or a memory store if dir is empty
+func NewStore(dir string) Store {
+	if dir == "" {
+		return NewMemoryStore()
+	}
+	return NewFileStore(filepath.Join(dir, FileName))
+}
+
+// FileStore keeps the state in a JSON file. Saves write a temporary file and
+// rename it, so a crash never leaves a half-written state behind. The history
+// goes to a separate file, see HistoryFileName.
+type FileStore struct {
+	path        string
+	historyPath string
+	mu          sync.Mutex
+
+	savedRepeats map[int64]int // Repeats of the history entries last written, by ID
+	historyLines int           // Lines in the history file, which is compacted when far above the entries
+}
+
+// HistoryFileName is the name of the history file inside the state directory.
+// It holds one JSON entry per line. New entries and updates of collapsed ones
+// are appended, later lines replace earlier ones with the same ID, and the
+// file is rewritten once most of its lines are outdated.
+const HistoryFileName = "history.jsonl"
+
+// minHistoryCompaction is the least number of outdated lines that makes the
+// history file worth rewriting
+const minHistoryCompaction = 1000
+
+// NewFileStore creates a store backed by the file at path, with the history
+// next to it
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		path:         path,
+		historyPath:  filepath.Join(filepath.Dir(path), HistoryFileName),
+		savedRepeats: make(map[int64]int),
+	}
+}
+
+// Path returns the location of the state file
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Load reads the state file, returning an empty state if it doesn't exist yet
+func (s *FileStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := New()
+	data, err := os.ReadFile(s.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+	if err == nil {
+		if state, err = decode(data); err != nil {
+			return nil, err
+		}
+	}
+
+	// States saved before the history had its own file carry it inline
+	history, lines, err := readHistory(s.historyPath)
+	if err != nil {
+		return nil, err
+	}
+	if lines > 0 {
+		state.History = history
+	}
+	state.numberHistory()
+	state.PruneHistory(time.Now())
+
+	s.savedRepeats = make(map[int64]int, len(history))
+	for _, entry := range history {
+		s.savedRepeats[entry.ID] = entry.Repeats
+	}
+	s.historyLines = lines
+	return state, nil
+}
+
+// Save writes the history changed since the last save, then atomically
+// replaces the state file
+func (s *FileStore) Save(state *State) error {
+	withoutHistory := *state
+	withoutHistory.History = nil
+	data, err := json.MarshalIndent(&withoutHistory, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := s.saveHistory(state.History); err != nil {
+		return err
+	}
+	return writeFile(s.path, data)
+}
+
+// saveHistory appends new and changed entries to the history file, or
+// rewrites it if most of its lines are outdated. Appends aren't synced, a
+// crash may lose the last entries but never the state.
+func (s *FileStore) saveHistory(history []types.HistoryEntry) error {
+	if outdated := s.historyLines - len(history); outdated >= minHistoryCompaction && outdated > len(history) {
+		return s.rewriteHistory(history)
+	}
+
+	// Entries only change when a repeat is collapsed into them
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	appended := 0
+	for _, entry := range history {
+		if repeats, ok := s.savedRepeats[entry.ID]; ok && repeats == entry.Repeats {
+			continue
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("encoding history: %w", err)
+		}
+		s.savedRepeats[entry.ID] = entry.Repeats
+		appended++
+	}
+	if appended == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(s.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("writing history: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing history: %w", err)
+	}
+	s.historyLines += appended
+	return nil
+}
+
+// rewriteHistory atomically replaces the history file by the given entries
+func (s *FileStore) rewriteHistory(history []types.HistoryEntry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range history {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("encoding history: %w", err)
+		}
+	}
+	if err := writeFile(s.historyPath, buf.Bytes()); err != nil {
+		return fmt.Errorf("compacting history: %w", err)
+	}
+
+	s.savedRepeats = make(map[int64]int, len(history))
+	for _, entry := range history {
+		s.savedRepeats[entry.ID] = entry.Repeats
+	}
+	s.historyLines = len(history)
+	return nil
+}
+
+// readHistory reads a history file, returning its entries in order of their
+// IDs and its number of lines. A missing file has neither.
+func readHistory(path string) ([]types.HistoryEntry, int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading history: %w", err)
+	}
+	defer file.Close()
+
+	byID := make(map[int64]types.HistoryEntry)
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lines++
+		var entry types.HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // A crash may leave a torn last line
+		}
+		byID[entry.ID] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("reading history: %w", err)
+	}
+
+	history := make([]types.HistoryEntry, 0, len(byID))
+	for _, entry := range byID {
+		history = append(history, entry)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].ID < history[j].ID })
+	return history, lines, nil
+}
+
+// writeFile atomically replaces the file at path by data
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// MemoryStore keeps the state in memory only, it is lost on restart
+type MemoryStore struct {
+	data []byte
+	mu   sync.Mutex
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load returns a copy of the last saved state
+func (s *MemoryStore) Load() (*State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data == nil {
+		return New(), nil
+	}
+	return decode(s.data)
+}
+
+// Save stores a copy of state
+func (s *MemoryStore) Save(state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	return nil
+}
+
+// decode parses an encoded state
+func decode(data []byte) (*State, error) {
+	state := New()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("decoding state: %w", err)
+	}
+	state.ensureMaps()
+	return state, nil
+}
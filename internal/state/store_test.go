@@ -0,0 +1,239 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	store := NewFileStore(filepath.Join(dir, FileName))
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading missing state: %v", err)
+	}
+	if len(state.Apps) != 0 {
+		t.Fatalf("expected empty state, got %d apps", len(state.Apps))
+	}
+
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	app := state.App("default/app-uuid")
+	app.LastUpdate = &updated
+	app.CurrentTag = "1.2.4"
+	app.PreviousTag = "1.2.3"
+	app.DeployedDigest = "sha256:abc"
+	app.Failures = 2
+	app.Status = &types.AppStatus{Name: "app", UUID: "app-uuid", CurrentTag: "1.2.4"}
+	state.ResolvedUUIDs["default///app"] = "app-uuid"
+
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error saving state: %v", err)
+	}
+
+	loaded, err := NewFileStore(store.Path()).Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading state: %v", err)
+	}
+
+	got := loaded.Apps["default/app-uuid"]
+	if got == nil {
+		t.Fatal("expected app state to be persisted")
+	}
+	if got.LastUpdate == nil || !got.LastUpdate.Equal(updated) {
+		t.Errorf("expected last update %v, got %v", updated, got.LastUpdate)
+	}
+	if got.PreviousTag != "1.2.3" || got.DeployedDigest != "sha256:abc" || got.Failures != 2 {
+		t.Errorf("unexpected app state: %+v", got)
+	}
+	if got.Status == nil || got.Status.CurrentTag != "1.2.4" {
+		t.Errorf("expected persisted status, got %+v", got.Status)
+	}
+	if loaded.ResolvedUUIDs["default///app"] != "app-uuid" {
+		t.Errorf("expected resolved uuid to be persisted, got %v", loaded.ResolvedUUIDs)
+	}
+
+	// Only the state file remains, no temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read state dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != FileName {
+		t.Errorf("expected only %s in state dir, got %v", FileName, entries)
+	}
+}
+
+func TestFileStoreHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, FileName))
+	base := time.Now().Add(-time.Hour)
+	check := func(minutes int) types.HistoryEntry {
+		return types.HistoryEntry{Time: base.Add(time.Duration(minutes) * time.Minute), App: "n8n", UUID: "n8n-uuid", Event: types.EventCheck}
+	}
+	lines := func() int {
+		data, err := os.ReadFile(filepath.Join(dir, HistoryFileName))
+		if err != nil {
+			t.Fatalf("failed to read history: %v", err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	state, _ := store.Load()
+	state.AddHistory(check(0))
+	state.AddHistory(types.HistoryEntry{Time: base.Add(time.Minute), App: "n8n", UUID: "n8n-uuid", Event: types.EventUpdateSucceeded})
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error saving state: %v", err)
+	}
+
+	// Saving again appends nothing, a collapsed repeat appends its entry once more
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error saving state: %v", err)
+	}
+	if n := lines(); n != 2 {
+		t.Errorf("expected 2 history lines, got %d", n)
+	}
+	state.AddHistory(check(15))
+	state.AddHistory(check(30))
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error saving state: %v", err)
+	}
+	if n := lines(); n != 3 {
+		t.Errorf("expected a line for the new check, got %d", n)
+	}
+
+	// The state file doesn't carry the history
+	data, err := os.ReadFile(store.Path())
+	if err != nil {
+		t.Fatalf("failed to read state: %v", err)
+	}
+	if strings.Contains(string(data), `"history"`) {
+		t.Error("expected history to be kept out of the state file")
+	}
+
+	loaded, err := NewFileStore(store.Path()).Load()
+	if err != nil {
+		t.Fatalf("unexpected error loading state: %v", err)
+	}
+	if len(loaded.History) != 3 || loaded.HistorySeq != 3 {
+		t.Fatalf("expected 3 history entries up to ID 3, got %d up to %d", len(loaded.History), loaded.HistorySeq)
+	}
+	if entry := loaded.History[2]; entry.Repeats != 1 || entry.LastSeen == nil {
+		t.Errorf("expected the latest version of the collapsed entry, got %+v", entry)
+	}
+
+	// Mostly outdated lines are compacted
+	for i := 0; i < minHistoryCompaction+1; i++ {
+		loaded.AddHistory(check(45))
+		store.Save(loaded)
+	}
+	if n := lines(); n != 3 {
+		t.Errorf("expected history to be compacted to 3 lines, got %d", n)
+	}
+}
+
+func TestFileStoreMigratesInlineHistory(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	legacy := `{"apps": {}, "history": [{"time": "` + time.Now().Format(time.RFC3339) + `", "app": "n8n", "event": "update_succeeded"}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatalf("failed to write state file: %v", err)
+	}
+
+	store := NewFileStore(path)
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(state.History) != 1 || state.History[0].ID != 1 {
+		t.Fatalf("expected the inline entry with an ID, got %+v", state.History)
+	}
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error saving state: %v", err)
+	}
+
+	loaded, err := NewFileStore(path).Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.History) != 1 || loaded.History[0].App != "n8n" {
+		t.Errorf("expected history to move to its own file, got %+v", loaded.History)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatalf("failed to write state file: %v", err)
+	}
+
+	if _, err := NewFileStore(path).Load(); err == nil {
+		t.Error("expected error for corrupt state file")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state.App("default/app-uuid").CurrentTag = "1.0.0"
+	if err := store.Save(state); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Changes after saving must not leak into the store
+	state.App("default/app-uuid").CurrentTag = "2.0.0"
+
+	loaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tag := loaded.Apps["default/app-uuid"].CurrentTag; tag != "1.0.0" {
+		t.Errorf("expected tag '1.0.0', got '%s'", tag)
+	}
+}
+
+func TestStateClone(t *testing.T) {
+	state := New()
+	state.App("default/app-uuid").Status = &types.AppStatus{Name: "app", BaseImages: []types.BaseImageStatus{{Image: "node"}}}
+	state.AddHistory(types.HistoryEntry{Instance: "default", UUID: "app-uuid", Event: types.EventCheck})
+
+	clone, err := state.Clone()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Changes of the original must not leak into the copy
+	state.App("default/app-uuid").Status.BaseImages[0].Image = "python"
+	state.App("other/app-uuid").CurrentTag = "1.0.0"
+	state.History[0].Repeats = 5
+
+	if image := clone.Apps["default/app-uuid"].Status.BaseImages[0].Image; image != "node" {
+		t.Errorf("expected base image 'node', got '%s'", image)
+	}
+	if len(clone.Apps) != 1 || len(clone.History) != 1 || clone.History[0].Repeats != 0 || clone.HistorySeq != state.HistorySeq {
+		t.Errorf("expected an unchanged copy, got %+v", clone)
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	if _, ok := NewStore("").(*MemoryStore); !ok {
+		t.Error("expected memory store without a directory")
+	}
+
+	dir := t.TempDir()
+	store, ok := NewStore(dir).(*FileStore)
+	if !ok {
+		t.Fatal("expected file store with a directory")
+	}
+	if store.Path() != filepath.Join(dir, FileName) {
+		t.Errorf("unexpected state path '%s'", store.Path())
+	}
+}
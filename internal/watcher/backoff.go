@@ -0,0 +1,30 @@
+package watcher
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+)
+
+// checkBackoff returns the next check after from of an app whose last
+// failures checks or updates failed. The regular wait of its schedule doubles
+// with every failure after the first, up to limit, and the result stays
+// aligned to the schedule. A limit below the regular wait disables backoff.
+func checkBackoff(schedule cron.Schedule, from time.Time, failures int, limit time.Duration) time.Time {
+	regular := schedule.Next(from).Sub(from)
+	wait := regular
+	for i := 1; i < failures && wait < limit; i++ {
+		wait *= 2
+	}
+	wait = min(wait, max(limit, regular))
+	return schedule.Next(from.Add(wait - regular))
+}
+
+// resetBackoff forgets the failures of an app, so its checks follow the
+// regular schedule again
+func resetBackoff(appState *state.AppState) {
+	appState.Failures = 0
+	appState.LastError = ""
+}
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestCheckBackoff(t *testing.T) {
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	daily, err := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow).Parse("0 3 * * *")
+	if err != nil {
+		t.Fatalf("failed to parse schedule: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule cron.Schedule
+		failures int
+		limit    time.Duration
+		expected time.Time
+	}{
+		{name: "first failure", schedule: cron.Every(15 * time.Minute), failures: 1, limit: 24 * time.Hour, expected: from.Add(15 * time.Minute)},
+		{name: "doubled", schedule: cron.Every(15 * time.Minute), failures: 3, limit: 24 * time.Hour, expected: from.Add(time.Hour)},
+		{name: "capped", schedule: cron.Every(15 * time.Minute), failures: 40, limit: 6 * time.Hour, expected: from.Add(6 * time.Hour)},
+		{name: "limit below interval", schedule: cron.Every(time.Hour), failures: 5, limit: 0, expected: from.Add(time.Hour)},
+		{name: "aligned to cron schedule", schedule: daily, failures: 2, limit: 72 * time.Hour, expected: time.Date(2026, 3, 3, 3, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if next := checkBackoff(tt.schedule, from, tt.failures, tt.limit); !next.Equal(tt.expected) {
+				t.Errorf("expected next check at %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
+
+func TestFailingAppBacksOff(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Defaults.Interval = "15m"
+	w.config.Defaults.MaxBackoff = "24h"
+
+	// Without a client for its instance every check fails
+	app := types.AppConfig{Name: "n8n", UUID: "n8n-uuid", Instance: "missing", Image: "n8nio/n8n"}
+	w.known = map[string]types.AppConfig{appKey(app): app}
+	cycleStart := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if _, err := w.checkAll(context.Background(), []types.AppConfig{app}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.scheduleChecks([]types.AppConfig{app}, cycleStart)
+	}
+
+	if next := w.appState(appKey(app)).NextCheck; !next.Equal(cycleStart.Add(time.Hour)) {
+		t.Errorf("expected third failure to wait four intervals, got %v", next)
+	}
+	status := w.GetStatus()
+	if len(status.Apps) != 1 {
+		t.Fatalf("expected the failing app in the status, got %+v", status.Apps)
+	}
+	if got := status.Apps[0]; !got.Failing || got.Failures != 3 || !strings.Contains(got.LastError, "missing") {
+		t.Errorf("expected app to be failing with its last error, got %+v", got)
+	}
+
+	// A changed config may have fixed it
+	reloaded := &types.Config{Defaults: types.DefaultsConfig{Interval: "15m", Cooldown: "1h", MaxBackoff: "12h"}}
+	if _, err := w.Reload(reloaded, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	appState := w.appState(appKey(app))
+	if appState.Failures != 0 || appState.LastError != "" {
+		t.Errorf("expected reload to reset the failures, got %d: %s", appState.Failures, appState.LastError)
+	}
+	if !appState.NextCheck.Equal(appState.LastCheck.Add(15 * time.Minute).Truncate(time.Second)) {
+		t.Errorf("expected regular check after the reload, got %v", appState.NextCheck)
+	}
+	if status := w.GetStatus().Apps[0]; status.Failing {
+		t.Errorf("expected app not to be failing after the reload, got %+v", status)
+	}
+}
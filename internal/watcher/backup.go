@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+const (
+	// defaultBackupTimeout limits the wait for a backup without backup_timeout
+	defaultBackupTimeout = 30 * time.Minute
+
+	// Backup execution statuses reported by Coolify
+	backupRunning = "running"
+	backupSuccess = "success"
+	backupFailed  = "failed"
+)
+
+// backupPollInterval is how often a running backup is checked, a variable for tests
+var backupPollInterval = 5 * time.Second
+
+// backupDatabase runs the scheduled backup of the app's backup database and
+// waits until it succeeded. It returns the backup's file name.
+func (w *Watcher) backupDatabase(ctx context.Context, client *coolify.Client, app types.AppConfig) (string, error) {
+	timeout := defaultBackupTimeout
+	if app.BackupTimeout != "" {
+		if parsed, err := time.ParseDuration(app.BackupTimeout); err == nil {
+			timeout = parsed
+		}
+	}
+	database := app.BackupDatabase
+
+	backups, err := client.ListBackups(ctx, database)
+	if err != nil {
+		return "", fmt.Errorf("listing backups: %w", err)
+	}
+	var backup *coolify.BackupResponse
+	for i := range backups {
+		if backups[i].Enabled {
+			backup = &backups[i]
+			break
+		}
+	}
+	if backup == nil {
+		return "", fmt.Errorf("database %s has no enabled scheduled backup, add one in Coolify", database)
+	}
+
+	// Our execution is the one that wasn't there before
+	executions, err := client.ListBackupExecutions(ctx, database, backup.UUID)
+	if err != nil {
+		return "", fmt.Errorf("listing backup executions: %w", err)
+	}
+	previous := make(map[string]bool, len(executions))
+	for _, execution := range executions {
+		previous[execution.UUID] = true
+	}
+
+	if err := client.TriggerBackup(ctx, database, backup.UUID); err != nil {
+		return "", fmt.Errorf("triggering backup: %w", err)
+	}
+	w.logger.Info("Backup triggered, waiting for it to finish",
+		"instance", app.Instance,
+		"app", app.Name,
+		"database", database,
+		"timeout", timeout,
+	)
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(backupPollInterval)
+	defer ticker.Stop()
+	reported := make(map[string]bool) // Unknown statuses already logged
+	for {
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", fmt.Errorf("backup of database %s did not finish within %v", database, timeout)
+		case <-ticker.C:
+		}
+
+		executions, err := client.ListBackupExecutions(waitCtx, database, backup.UUID)
+		if err != nil {
+			if waitCtx.Err() != nil {
+				continue // Reported as timeout or cancellation above
+			}
+			return "", fmt.Errorf("checking backup: %w", err)
+		}
+		for _, execution := range executions {
+			if previous[execution.UUID] {
+				continue
+			}
+			switch execution.Status {
+			case backupSuccess:
+				return execution.Filename, nil
+			case backupFailed:
+				return "", fmt.Errorf("backup of database %s failed: %s", database, execution.Message)
+			case backupRunning:
+			default:
+				// Waited for like a running backup, until it finishes or times out
+				if !reported[execution.Status] {
+					reported[execution.Status] = true
+					w.logger.Warn("Unknown backup status, still waiting",
+						"instance", app.Instance,
+						"app", app.Name,
+						"database", database,
+						"status", execution.Status,
+					)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// fakeBackups serves the backup endpoints of a Coolify database. A triggered
+// backup finishes with the given status.
+func fakeBackups(t *testing.T, enabled bool, status string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	executions := `{"uuid": "old-run", "status": "success", "filename": "/backups/old.dmp"}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/databases/db-uuid/backups":
+			if r.Method == http.MethodPatch {
+				t.Errorf("expected the scheduled backup to be triggered, not the database")
+			}
+			fmt.Fprintf(w, `[{"uuid": "backup-uuid", "enabled": %t}]`, enabled)
+		case "/api/v1/databases/db-uuid/backups/backup-uuid":
+			executions += fmt.Sprintf(`, {"uuid": "new-run", "status": %q, "message": "disk full", "filename": "/backups/new.dmp"}`, status)
+		case "/api/v1/databases/db-uuid/backups/backup-uuid/executions":
+			fmt.Fprintf(w, `{"executions": [%s]}`, executions)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBackupDatabase(t *testing.T) {
+	interval := backupPollInterval
+	backupPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { backupPollInterval = interval })
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		status   string
+		expected string // File name, or a part of the error
+		wantErr  bool
+	}{
+		{name: "success", enabled: true, status: "success", expected: "/backups/new.dmp"},
+		{name: "failed", enabled: true, status: "failed", expected: "failed: disk full", wantErr: true},
+		{name: "timeout", enabled: true, status: "running", expected: "did not finish within 100ms", wantErr: true},
+		{name: "no scheduled backup", enabled: false, expected: "no enabled scheduled backup", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWatcher(t)
+			server := fakeBackups(t, tt.enabled, tt.status)
+			app := types.AppConfig{Name: "postgres", Instance: "default", BackupDatabase: "db-uuid", BackupTimeout: "100ms"}
+
+			file, err := w.backupDatabase(context.Background(), coolify.NewClient(server.URL, "token"), app)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Fatalf("expected error containing '%s', got %v", tt.expected, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if file != tt.expected {
+				t.Errorf("expected backup file '%s', got '%s'", tt.expected, file)
+			}
+		})
+	}
+}
+
+func TestFailedBackupAbortsUpdate(t *testing.T) {
+	interval := backupPollInterval
+	backupPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { backupPollInterval = interval })
+
+	w := newTestWatcher(t)
+	server := fakeBackups(t, true, "failed")
+	app := types.AppConfig{
+		Name:               "postgres",
+		UUID:               "pg-uuid",
+		Instance:           "default",
+		Type:               types.ResourceService,
+		TagEnv:             "PG_VERSION",
+		BackupBeforeUpdate: true,
+		BackupDatabase:     "db-uuid",
+	}
+	app.Hooks.OnFailure = []types.Hook{{Name: "alert", Command: `echo "$PATROL_ERROR"`}}
+	plan := &plannedUpdate{
+		app:     app,
+		client:  coolify.NewClient(server.URL, "token"),
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+		fromTag: "16.3",
+		toTag:   "16.4",
+	}
+
+	applied, err := w.applyUpdate(context.Background(), plan)
+	if err != nil || applied {
+		t.Fatalf("expected update to be aborted without ending the cycle, got %v, %v", applied, err)
+	}
+
+	status := w.GetStatus().Apps[0]
+	if !strings.Contains(status.Deferred, "backup failed") || status.CurrentTag == "16.4" {
+		t.Errorf("expected update to be blocked by the backup, got %+v", status)
+	}
+	entries := w.History(types.HistoryQuery{})
+	if len(entries) < 2 || entries[0].Event != types.EventUpdateFailed || entries[1].Hook != "on_failure: alert" {
+		t.Fatalf("expected failure hook and failed update, got %+v", entries)
+	}
+	if !strings.Contains(entries[1].Output, "disk full") {
+		t.Errorf("expected failure hook to get the backup error, got '%s'", entries[1].Output)
+	}
+}
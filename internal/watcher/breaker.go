@@ -0,0 +1,161 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// ErrBreakerNotFound is returned when resetting the breaker of an app without one
+var ErrBreakerNotFound = errors.New("no circuit breaker for app")
+
+// breakerSettings are the circuit breaker settings of a config, thresholds of
+// 0 disable a breaker
+type breakerSettings struct {
+	threshold, globalThreshold int
+	window, timeout            time.Duration
+}
+
+// breakerSettingsOf reads the breaker settings, validated by config.Load
+func breakerSettingsOf(cfg types.BreakerConfig) breakerSettings {
+	var settings breakerSettings
+	if cfg.Threshold != nil {
+		settings.threshold = *cfg.Threshold
+	}
+	if cfg.GlobalThreshold != nil {
+		settings.globalThreshold = *cfg.GlobalThreshold
+	}
+	settings.window, _ = time.ParseDuration(cfg.Window)
+	settings.timeout, _ = time.ParseDuration(cfg.Timeout)
+	return settings
+}
+
+// breakerFailure counts a failed update in the breakers of the app and of
+// all apps. A breaker it opens is logged, recorded in the history and runs
+// the on_breaker_open hooks.
+func (w *Watcher) breakerFailure(ctx context.Context, plan *plannedUpdate, updateErr error) {
+	settings := breakerSettingsOf(w.config.Breaker)
+	key := appKey(plan.app)
+	now := time.Now()
+
+	var appOpened, globalOpened bool
+	w.update(func(s *state.State) {
+		if settings.threshold > 0 {
+			appState := s.App(key)
+			if appState.Breaker == nil {
+				appState.Breaker = &state.Breaker{}
+			}
+			appOpened = appState.Breaker.Fail(now, updateErr.Error(), settings.threshold, settings.window, settings.timeout)
+		}
+		if settings.globalThreshold > 0 {
+			if s.Breaker == nil {
+				s.Breaker = &state.Breaker{}
+			}
+			globalOpened = s.Breaker.Fail(now, updateErr.Error(), settings.globalThreshold, settings.window, settings.timeout)
+		}
+	})
+
+	if appOpened {
+		reason := fmt.Sprintf("circuit breaker opened after %d failed updates, updates of %s stop until %s",
+			settings.threshold, plan.app.Name, now.Add(settings.timeout).Format(time.RFC3339))
+		w.breakerOpened(ctx, plan, reason, updateErr)
+	}
+	if globalOpened {
+		reason := fmt.Sprintf("global circuit breaker opened after %d failed updates, all updates stop until %s",
+			settings.globalThreshold, now.Add(settings.timeout).Format(time.RFC3339))
+		w.breakerOpened(ctx, plan, reason, updateErr)
+	}
+}
+
+// breakerOpened alerts about a breaker opened by the failed update of plan
+func (w *Watcher) breakerOpened(ctx context.Context, plan *plannedUpdate, reason string, updateErr error) {
+	app := plan.app
+	w.logger.Error("Circuit breaker opened, updates stop until it closes or is reset",
+		"instance", app.Instance,
+		"app", app.Name,
+		"uuid", app.UUID,
+		"reason", reason,
+		"error", updateErr,
+	)
+	w.addHistory(app, types.HistoryEntry{
+		Event:   types.EventBreakerOpened,
+		FromTag: plan.fromTag,
+		ToTag:   plan.toTag,
+		Reason:  reason,
+		Actor:   plan.actor,
+	})
+	w.runHooks(ctx, plan, types.HookBreakerOpen, fmt.Errorf("%s: %w", reason, updateErr))
+}
+
+// breakerSuccess closes the breakers of the app and of all apps after a
+// successful update, which ends the streak of failures
+func (w *Watcher) breakerSuccess(key string) {
+	w.mu.RLock()
+	appState, ok := w.state.Apps[key]
+	tracked := w.state.Breaker != nil || (ok && appState.Breaker != nil)
+	w.mu.RUnlock()
+	if !tracked {
+		return
+	}
+
+	w.update(func(s *state.State) {
+		s.App(key).Breaker = nil
+		s.Breaker = nil
+	})
+}
+
+// breakerReason returns why an open breaker stops the updates of an app at
+// t, or an empty string
+func (w *Watcher) breakerReason(key string, t time.Time) string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if breaker := w.state.Breaker; breaker.Open(t) {
+		return fmt.Sprintf("global circuit breaker open until %s", breaker.ClosesAt.Format(time.RFC3339))
+	}
+	if appState, ok := w.state.Apps[key]; ok && appState.Breaker.Open(t) {
+		return fmt.Sprintf("circuit breaker open until %s", appState.Breaker.ClosesAt.Format(time.RFC3339))
+	}
+	return ""
+}
+
+// ResetBreaker closes circuit breakers and forgets their failures, so
+// updates resume with the next cycle. An app name or UUID only resets the
+// breaker of that app, otherwise all breakers are reset.
+func (w *Watcher) ResetBreaker(app, actor string) error {
+	var reset []types.AppConfig // Apps whose breaker was reset, an empty one for the global breaker
+	w.update(func(s *state.State) {
+		if app == "" && s.Breaker != nil {
+			s.Breaker = nil
+			reset = append(reset, types.AppConfig{})
+		}
+		for _, appState := range s.Apps {
+			status := appState.Status
+			if appState.Breaker == nil || status == nil {
+				continue
+			}
+			if app != "" && status.Name != app && status.UUID != app {
+				continue
+			}
+			appState.Breaker = nil
+			reset = append(reset, types.AppConfig{Name: status.Name, UUID: status.UUID, Instance: status.Instance})
+		}
+	})
+	if app != "" && len(reset) == 0 {
+		return fmt.Errorf("%w '%s'", ErrBreakerNotFound, app)
+	}
+
+	for _, resetApp := range reset {
+		reason := "circuit breaker reset"
+		if resetApp.Name == "" {
+			reason = "global circuit breaker reset"
+		}
+		w.logger.Info("Circuit breaker reset", "instance", resetApp.Instance, "app", resetApp.Name, "actor", actor)
+		w.addHistory(resetApp, types.HistoryEntry{Event: types.EventBreakerReset, Reason: reason, Actor: actor})
+	}
+	return nil
+}
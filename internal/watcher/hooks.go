@@ -0,0 +1,82 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/chrisdietr/coolify-patrol/internal/hook"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// runHooks runs the global hooks of a stage and then those of the app,
+// recording each in the history. Pre-update hooks stop at the first failure,
+// which is returned and aborts the update; the other stages run all hooks
+// and only report the first failure. updateErr is passed to on_failure hooks.
+func (w *Watcher) runHooks(ctx context.Context, plan *plannedUpdate, stage types.HookStage, updateErr error) error {
+	app := plan.app
+	hooks := append(slices.Clone(hook.Stage(w.config.Hooks, stage)), hook.Stage(app.Hooks, stage)...)
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	data := hook.Data{
+		Stage:    stage,
+		App:      app.Name,
+		UUID:     app.UUID,
+		Instance: app.Instance,
+		Image:    app.Image,
+		FromTag:  plan.fromTag,
+		ToTag:    plan.toTag,
+	}
+	if updateErr != nil {
+		data.Error = updateErr.Error()
+	}
+	logger := w.logger.With("instance", app.Instance, "app", app.Name, "uuid", app.UUID, "stage", stage)
+
+	var firstErr error
+	for _, h := range hooks {
+		name := hook.Name(h)
+		output, err := hook.Run(ctx, h, data)
+		entry := types.HistoryEntry{
+			Event:   types.EventHook,
+			FromTag: plan.fromTag,
+			ToTag:   plan.toTag,
+			Actor:   plan.actor,
+			Hook:    fmt.Sprintf("%s: %s", stage, name),
+			Output:  output,
+		}
+		if err == nil {
+			logger.Info("Hook succeeded", "hook", name)
+			w.addHistory(app, entry)
+			continue
+		}
+
+		logger.Error("Hook failed", "hook", name, "error", err, "output", output)
+		entry.Event, entry.Reason = types.EventHookFailed, err.Error()
+		w.addHistory(app, entry)
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s hook '%s' failed: %w", stage, name, err)
+		}
+		if stage == types.HookPreUpdate {
+			break
+		}
+	}
+	return firstErr
+}
+
+// failUpdate runs the on_failure hooks of an update that failed with err,
+// counts it in the circuit breakers and returns err
+func (w *Watcher) failUpdate(ctx context.Context, plan *plannedUpdate, err *updateError) error {
+	// The new leader takes over, this instance must not even record the failure
+	if errors.Is(context.Cause(ctx), ErrNotLeader) {
+		return ErrNotLeader
+	}
+	w.runHooks(ctx, plan, types.HookOnFailure, err.err)
+	// Interrupted by shutdown, not a failure
+	if ctx.Err() == nil {
+		w.breakerFailure(ctx, plan, err.err)
+	}
+	return err
+}
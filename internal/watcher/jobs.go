@@ -0,0 +1,325 @@
+package watcher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/config"
+	"github.com/chrisdietr/coolify-patrol/internal/registry"
+	"github.com/chrisdietr/coolify-patrol/internal/semver"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// maxJobs caps the jobs kept in memory, both waiting and finished ones
+const maxJobs = 100
+
+var (
+	// ErrJobNotFound is returned for unknown or expired job IDs
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobQueueFull is returned when too many jobs are waiting to run
+	ErrJobQueueFull = errors.New("too many queued jobs")
+	// ErrJobConflict is returned for an update of an app that already has one
+	// queued or running
+	ErrJobConflict = errors.New("an update of the app is already queued")
+)
+
+// Enqueue queues a check or update requested through the API and returns it.
+// Jobs run one at a time between scheduled cycles, never overlapping them.
+// A standby instance refuses jobs with ErrNotLeader, and a second update of
+// an app is refused with ErrJobConflict, returning the first one.
+func (w *Watcher) Enqueue(job types.Job) (types.Job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return types.Job{}, fmt.Errorf("generating job id: %w", err)
+	}
+	job.ID = hex.EncodeToString(id)
+	job.Status = types.JobQueued
+	job.CreatedAt = time.Now()
+
+	w.jobsMu.Lock()
+	// Checked under jobsMu, failQueuedJobs then sees every job accepted before losing the lock
+	if w.Standby() {
+		w.jobsMu.Unlock()
+		return types.Job{}, ErrNotLeader
+	}
+	queuedJobs := 0
+	for _, existing := range w.jobs {
+		if existing.FinishedAt != nil {
+			continue
+		}
+		queuedJobs++
+		// The second update would overrule the first one right after it
+		if job.Kind == types.JobUpdateApp && existing.Kind == types.JobUpdateApp && existing.UUID == job.UUID &&
+			(existing.Instance == "" || job.Instance == "" || existing.Instance == job.Instance) {
+			conflicting := *existing
+			w.jobsMu.Unlock()
+			return conflicting, fmt.Errorf("%w: job %s", ErrJobConflict, conflicting.ID)
+		}
+	}
+	if queuedJobs >= maxJobs {
+		w.jobsMu.Unlock()
+		return types.Job{}, ErrJobQueueFull
+	}
+	w.jobs = append(w.jobs, &job)
+	if excess := len(w.jobs) - maxJobs; excess > 0 {
+		w.dropFinishedJobs(excess)
+	}
+	queued := job
+	w.jobsMu.Unlock()
+
+	w.wakeUp()
+
+	w.logger.Info("Job queued", "job", job.ID, "kind", job.Kind, "uuid", job.UUID, "tag", job.Tag, "actor", job.Actor)
+	return queued, nil
+}
+
+// dropFinishedJobs removes up to n of the oldest finished jobs. Must be
+// called with jobsMu held.
+func (w *Watcher) dropFinishedJobs(n int) {
+	w.jobs = slices.DeleteFunc(w.jobs, func(job *types.Job) bool {
+		if n == 0 || job.FinishedAt == nil {
+			return false
+		}
+		n--
+		return true
+	})
+}
+
+// failQueuedJobs fails all jobs still waiting to run with err
+func (w *Watcher) failQueuedJobs(err error) {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+
+	finished := time.Now()
+	for _, job := range w.jobs {
+		if job.Status != types.JobQueued {
+			continue
+		}
+		job.Status = types.JobFailed
+		job.Error = err.Error()
+		job.FinishedAt = &finished
+		w.logger.Info("Job finished", "job", job.ID, "kind", job.Kind, "status", job.Status, "error", err)
+	}
+}
+
+// Job returns a copy of a queued, running or recently finished job
+func (w *Watcher) Job(id string) (types.Job, error) {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+
+	for _, job := range w.jobs {
+		if job.ID == id {
+			return *job, nil
+		}
+	}
+	return types.Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+}
+
+// runJobs runs all queued jobs, oldest first
+func (w *Watcher) runJobs(ctx context.Context) {
+	for ctx.Err() == nil {
+		job := w.nextJob()
+		if job == nil {
+			return
+		}
+
+		var result string
+		w.cycleMu.Lock()
+		err := w.whileLeader(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = w.runJob(ctx, job)
+			return err
+		})
+		w.cycleMu.Unlock()
+
+		finished := time.Now()
+		w.jobsMu.Lock()
+		job.FinishedAt = &finished
+		job.Result = result
+		job.Status = types.JobSucceeded
+		if err != nil {
+			job.Status = types.JobFailed
+			job.Error = err.Error()
+		}
+		w.jobsMu.Unlock()
+
+		w.logger.Info("Job finished", "job", job.ID, "kind", job.Kind, "status", job.Status, "result", result, "error", err)
+	}
+}
+
+// nextJob marks the oldest queued job as running and returns it, or nil
+func (w *Watcher) nextJob() *types.Job {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+
+	for _, job := range w.jobs {
+		if job.Status == types.JobQueued {
+			started := time.Now()
+			job.Status = types.JobRunning
+			job.StartedAt = &started
+			return job
+		}
+	}
+	return nil
+}
+
+// runJob does the work of a job and describes the outcome
+func (w *Watcher) runJob(ctx context.Context, job *types.Job) (string, error) {
+	switch job.Kind {
+	case types.JobCheck:
+		updates, err := w.runCycle(ctx, func(apps []types.AppConfig, _ time.Time) []types.AppConfig {
+			return apps
+		})
+		return fmt.Sprintf("%d updates applied", updates), err
+
+	case types.JobCheckApp:
+		found := false
+		updates, err := w.runCycle(ctx, func(apps []types.AppConfig, _ time.Time) []types.AppConfig {
+			selected := slices.DeleteFunc(apps, func(app types.AppConfig) bool {
+				return !jobTargets(job, app)
+			})
+			found = found || len(selected) > 0
+			return selected
+		})
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("app %s is not watched", job.UUID)
+		}
+		return fmt.Sprintf("%d updates applied", updates), nil
+
+	case types.JobUpdateApp:
+		return w.runUpdateJob(ctx, job)
+
+	default:
+		return "", fmt.Errorf("unknown job kind '%s'", job.Kind)
+	}
+}
+
+// runUpdateJob updates an app to the job's tag, or to the newest tag if none
+// is given. The newest tag must be allowed by the app's policy and pin, while
+// a tag given explicitly bypasses them. The cooldown is bypassed either way,
+// maintenance windows, freezes, pauses and running deployments still hold it
+// back.
+func (w *Watcher) runUpdateJob(ctx context.Context, job *types.Job) (string, error) {
+	apps, err := w.getApplicationsToCheck(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting applications: %w", err)
+	}
+	index := slices.IndexFunc(apps, func(app types.AppConfig) bool {
+		return jobTargets(job, app)
+	})
+	if index < 0 {
+		return "", fmt.Errorf("app %s is not watched", job.UUID)
+	}
+	app := apps[index]
+	if app.WatchBaseImages {
+		return "", fmt.Errorf("app %s is watched for base images and has no tag to update", app.Name)
+	}
+
+	client, err := w.clientFor(app)
+	if err != nil {
+		return "", err
+	}
+	currentTag, err := w.currentTag(ctx, client, app)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := w.findTag(ctx, app, job.Tag)
+	if err != nil {
+		return "", err
+	}
+	if target.Name == currentTag {
+		return fmt.Sprintf("already at %s", currentTag), nil
+	}
+	policy := config.GetUpdatePolicy(&app, w.defaultsFor(app))
+	if job.Tag == "" {
+		if allowed, reason := semver.IsUpdateAllowed(currentTag, target.Name, policy, app.Pin); !allowed {
+			return fmt.Sprintf("not updated to %s: %s", target.Name, reason), nil
+		}
+	}
+
+	key := appKey(app)
+	status := &types.AppStatus{
+		Name:         app.Name,
+		UUID:         app.UUID,
+		Instance:     app.Instance,
+		Image:        app.Image,
+		CurrentTag:   currentTag,
+		LatestTag:    target.Name,
+		Policy:       string(policy),
+		UpdateNeeded: true,
+		LastCheck:    time.Now(),
+		LastUpdate:   w.appState(key).LastUpdate,
+	}
+	plan := &plannedUpdate{
+		app:     app,
+		client:  client,
+		status:  status,
+		fromTag: currentTag,
+		toTag:   target.Name,
+		digest:  target.Digest,
+		reason:  fmt.Sprintf("requested by %s", job.Actor),
+		actor:   job.Actor,
+	}
+
+	done := w.markInProgress(app)
+	applied, err := w.applyPlan(ctx, plan)
+	done()
+	if err != nil {
+		w.finishApp(app, err)
+		return "", err
+	}
+
+	switch {
+	case applied:
+		return fmt.Sprintf("updated from %s to %s", currentTag, target.Name), nil
+	case w.dryRun:
+		return fmt.Sprintf("dry run, would update from %s to %s", currentTag, target.Name), nil
+	default:
+		return "", fmt.Errorf("update to %s not applied: %s", target.Name, status.Deferred)
+	}
+}
+
+// findTag looks up tag of the app's image in the registry, or its newest tag
+// if tag is empty
+func (w *Watcher) findTag(ctx context.Context, app types.AppConfig, tag string) (types.RegistryTag, error) {
+	if tag == "" {
+		latest, err := w.latest(ctx, app.Image, w.defaultsFor(app).ExcludePatterns)
+		if err != nil {
+			return types.RegistryTag{}, fmt.Errorf("getting latest tag for %s: %w", app.Image, err)
+		}
+		return latest, nil
+	}
+
+	sem := w.registrySemaphore(registry.Host(app.Image))
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return types.RegistryTag{}, ctx.Err()
+	}
+	defer func() { <-sem }()
+
+	tags, err := w.registryClient.GetTags(ctx, app.Image)
+	if err != nil {
+		return types.RegistryTag{}, fmt.Errorf("listing tags of %s: %w", app.Image, err)
+	}
+	for _, candidate := range tags {
+		if candidate.Name == tag {
+			return candidate, nil
+		}
+	}
+	return types.RegistryTag{}, fmt.Errorf("tag %s of %s not found in the registry", tag, app.Image)
+}
+
+// jobTargets reports whether app is the target of an app job
+func jobTargets(job *types.Job, app types.AppConfig) bool {
+	return app.UUID == job.UUID && (job.Instance == "" || app.Instance == job.Instance)
+}
@@ -0,0 +1,185 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestRunJobs(t *testing.T) {
+	w := newTestWatcher(t)
+
+	// Without instances or apps nothing is watched
+	tests := []struct {
+		name     string
+		job      types.Job
+		status   types.JobStatus
+		result   string
+		errorMsg string
+	}{
+		{name: "full check", job: types.Job{Kind: types.JobCheck}, status: types.JobSucceeded, result: "0 updates applied"},
+		{name: "unknown app check", job: types.Job{Kind: types.JobCheckApp, UUID: "missing"}, status: types.JobFailed, errorMsg: "app missing is not watched"},
+		{name: "unknown app update", job: types.Job{Kind: types.JobUpdateApp, UUID: "missing", Tag: "1.0.1"}, status: types.JobFailed, errorMsg: "app missing is not watched"},
+		{name: "unknown kind", job: types.Job{Kind: "restart"}, status: types.JobFailed, errorMsg: "unknown job kind 'restart'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queued, err := w.Enqueue(tt.job)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queued.ID == "" || queued.Status != types.JobQueued {
+				t.Fatalf("expected a queued job with an ID, got %+v", queued)
+			}
+
+			w.runJobs(context.Background())
+
+			job, err := w.Job(queued.ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if job.Status != tt.status || job.Result != tt.result || job.Error != tt.errorMsg {
+				t.Errorf("expected %s with result '%s' and error '%s', got %+v", tt.status, tt.result, tt.errorMsg, job)
+			}
+			if job.StartedAt == nil || job.FinishedAt == nil {
+				t.Errorf("expected start and finish times, got %+v", job)
+			}
+		})
+	}
+
+	if _, err := w.Job("unknown"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestJobQueueLimit(t *testing.T) {
+	w := newTestWatcher(t)
+
+	for i := 0; i < maxJobs; i++ {
+		if _, err := w.Enqueue(types.Job{Kind: types.JobCheck}); err != nil {
+			t.Fatalf("unexpected error queuing job %d: %v", i, err)
+		}
+	}
+	if _, err := w.Enqueue(types.Job{Kind: types.JobCheck}); !errors.Is(err, ErrJobQueueFull) {
+		t.Fatalf("expected ErrJobQueueFull, got %v", err)
+	}
+
+	// Finished jobs make room, the oldest are dropped
+	w.runJobs(context.Background())
+	first := w.jobs[0].ID
+	if _, err := w.Enqueue(types.Job{Kind: types.JobCheck}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(w.jobs) != maxJobs {
+		t.Errorf("expected %d jobs kept, got %d", maxJobs, len(w.jobs))
+	}
+	if _, err := w.Job(first); err == nil {
+		t.Error("expected the oldest finished job to be dropped")
+	}
+}
+
+func TestRunLoopRunsJobs(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Defaults.Interval = "1h"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.run(ctx) }()
+
+	queued, err := w.Enqueue(types.Job{Kind: types.JobCheck, Actor: "chatops"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := w.Job(queued.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.FinishedAt != nil {
+			if job.Status != types.JobSucceeded || !strings.HasPrefix(job.Result, "0 updates") {
+				t.Errorf("unexpected job outcome %+v", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job didn't run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestUpdateJobConflict(t *testing.T) {
+	w := newTestWatcher(t)
+
+	first, err := w.Enqueue(types.Job{Kind: types.JobUpdateApp, UUID: "web-uuid", Tag: "1.1.0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conflicting, err := w.Enqueue(types.Job{Kind: types.JobUpdateApp, UUID: "web-uuid", Instance: "prod"})
+	if !errors.Is(err, ErrJobConflict) || conflicting.ID != first.ID {
+		t.Fatalf("expected conflict with job %s, got %+v, %v", first.ID, conflicting, err)
+	}
+
+	// Other apps and checks of the app are fine
+	if _, err := w.Enqueue(types.Job{Kind: types.JobUpdateApp, UUID: "api-uuid"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := w.Enqueue(types.Job{Kind: types.JobCheckApp, UUID: "web-uuid"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	w.runJobs(context.Background())
+	if _, err := w.Enqueue(types.Job{Kind: types.JobUpdateApp, UUID: "web-uuid"}); err != nil {
+		t.Errorf("expected a new update once the first finished, got %v", err)
+	}
+}
+
+func TestUpdateJobPolicy(t *testing.T) {
+	coolifyInstance := newFakeCoolify(t, coolify.ApplicationResponse{UUID: "web-uuid", Name: "web", DockerImage: "acme/web:1.0.0"})
+	cfg := &types.Config{Apps: []types.AppConfig{{Name: "web", UUID: "web-uuid", Instance: "default", Image: "acme/web", Pin: "1"}}}
+	w := newCycleWatcher(t, cfg, map[string]*fakeCoolify{"default": coolifyInstance},
+		map[string][]string{"acme/web": {"1.0.0", "1.1.0", "2.0.0"}})
+
+	run := func(tag string) types.Job {
+		t.Helper()
+		queued, err := w.Enqueue(types.Job{Kind: types.JobUpdateApp, UUID: "web-uuid", Tag: tag})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.runJobs(context.Background())
+		job, _ := w.Job(queued.ID)
+		return job
+	}
+
+	// The newest tag crosses the pin
+	job := run("")
+	if job.Status != types.JobSucceeded || !strings.HasPrefix(job.Result, "not updated to 2.0.0") {
+		t.Errorf("expected the pin to hold the update back, got %+v", job)
+	}
+	if changes := coolifyInstance.recorded(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+
+	// Naming the tag overrides pin and policy
+	job = run("2.0.0")
+	if job.Status != types.JobSucceeded || job.Result != "updated from 1.0.0 to 2.0.0" {
+		t.Errorf("expected update to 2.0.0, got %+v", job)
+	}
+	if changes := fmt.Sprint(coolifyInstance.recorded()); changes != "[image web-uuid acme/web:2.0.0 restart applications/web-uuid]" {
+		t.Errorf("unexpected changes %s", changes)
+	}
+}
@@ -0,0 +1,250 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/config"
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// updateLimits holds back the updates of a cycle beyond max_updates_per_cycle
+// and batch_size, and those of apps whose server already runs
+// max_concurrent_per_server deployments. Held back updates are queued and
+// applied first by the following cycles.
+type updateLimits struct {
+	w         *Watcher
+	limit     int                           // Updates per cycle, 0 without a limit
+	perServer int                           // Deployments running at once per server, 0 without a limit
+	applied   int                           // Updates applied in this cycle
+	restarted map[string]int                // Services restarted in this cycle per instance/server
+	servers   map[string]string             // Server names of apps, keyed by appKey
+	lookedUp  map[string]bool               // Instances whose servers were looked up in this cycle
+	queued    map[string]types.QueuedUpdate // Queued by previous cycles, keyed by appKey
+	handled   map[string]bool               // Apps whose previous queue entry is outdated
+	queue     []types.QueuedUpdate          // Held back in this cycle
+}
+
+// newUpdateLimits returns the limits of a cycle, the batch size is a share of
+// the watched apps
+func (w *Watcher) newUpdateLimits(watched int) *updateLimits {
+	l := &updateLimits{
+		w:         w,
+		limit:     w.config.MaxUpdatesPerCycle,
+		perServer: w.config.MaxConcurrentPerServer,
+		restarted: make(map[string]int),
+		servers:   make(map[string]string),
+		lookedUp:  make(map[string]bool),
+		queued:    make(map[string]types.QueuedUpdate),
+		handled:   make(map[string]bool),
+	}
+	if percent, _ := config.ParseBatchSize(w.config.BatchSize); percent > 0 {
+		batch := max(int(math.Ceil(float64(watched)*percent/100)), 1)
+		if l.limit == 0 || batch < l.limit {
+			l.limit = batch
+		}
+	}
+
+	w.mu.RLock()
+	for _, queued := range w.state.Queue {
+		l.queued[queued.Instance+"/"+queued.UUID] = queued
+	}
+	for key, server := range w.servers {
+		l.servers[key] = server
+	}
+	w.mu.RUnlock()
+	return l
+}
+
+// order sorts plans so that updates queued by previous cycles come first,
+// oldest first. Patrol's own update stays last, see runCycle.
+func (l *updateLimits) order(plans []*plannedUpdate) {
+	rank := func(plan *plannedUpdate) (bool, bool, time.Time) {
+		queued, ok := l.queued[appKey(plan.app)]
+		return config.IsSelf(&l.w.config.Self, plan.app.UUID, plan.app.Image), !ok, queued.QueuedAt
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		selfI, newI, queuedI := rank(plans[i])
+		selfJ, newJ, queuedJ := rank(plans[j])
+		if selfI != selfJ {
+			return selfJ
+		}
+		if newI != newJ {
+			return newJ
+		}
+		return queuedI.Before(queuedJ)
+	})
+}
+
+// checked marks apps checked without finding an update, they no longer need
+// their queued update
+func (l *updateLimits) checked(apps []types.AppConfig, plans []*plannedUpdate) {
+	for _, app := range apps {
+		l.handled[appKey(app)] = true
+	}
+	for _, plan := range plans {
+		delete(l.handled, appKey(plan.app))
+	}
+}
+
+// holdReason returns why plan exceeds the limits, or "" if it may be applied
+func (l *updateLimits) holdReason(ctx context.Context, plan *plannedUpdate) (string, error) {
+	if l.limit > 0 && l.applied >= l.limit {
+		return fmt.Sprintf("limit of %d updates per cycle reached", l.limit), nil
+	}
+	if l.perServer > 0 {
+		server := l.server(ctx, plan)
+		running, err := l.running(ctx, plan, server)
+		if err != nil {
+			return "", err
+		}
+		if running >= l.perServer {
+			return fmt.Sprintf("limit of %d concurrent deployments on server %s reached", l.perServer, serverName(server)), nil
+		}
+	}
+	return "", nil
+}
+
+// running counts the deployments queued or in progress on server, including
+// those of earlier cycles and those started outside patrol. Service restarts
+// don't go through Coolify's deployment queue, the ones of this cycle count
+// instead.
+func (l *updateLimits) running(ctx context.Context, plan *plannedUpdate, server string) (int, error) {
+	deployments, err := plan.client.ListActiveDeployments(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("counting deployments on server %s: %w", serverName(server), err)
+	}
+	running := l.restarted[plan.app.Instance+"/"+server]
+	for _, deployment := range deployments {
+		// An unknown server stands for the whole instance
+		if coolify.IsActiveDeploymentStatus(deployment.Status) && (server == "" || deployment.ServerName == server) {
+			running++
+		}
+	}
+	return running, nil
+}
+
+// wait gives Coolify a break after the previous update of the cycle, checks
+// and held back updates don't need one
+func (l *updateLimits) wait(ctx context.Context, plan *plannedUpdate) error {
+	if l.applied == 0 {
+		return nil
+	}
+	delay := l.w.updateDelay(plan.app)
+	l.w.logger.Debug("Waiting before next update", "delay", delay)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
+// done counts a plan that was handled, and applied if Coolify was changed
+func (l *updateLimits) done(ctx context.Context, plan *plannedUpdate, applied bool) {
+	l.handled[appKey(plan.app)] = true
+	if !applied {
+		return
+	}
+	l.applied++
+	if l.perServer > 0 && plan.app.Type == types.ResourceService {
+		l.restarted[plan.app.Instance+"/"+l.server(ctx, plan)]++
+	}
+}
+
+// hold queues plan for the next cycle, keeping its place in the queue
+func (l *updateLimits) hold(plan *plannedUpdate, reason string) {
+	app := plan.app
+	key := appKey(app)
+	l.w.logger.Info("Update limit reached, update queued for next cycle",
+		"instance", app.Instance,
+		"app", app.Name,
+		"uuid", app.UUID,
+		"to_tag", plan.toTag,
+		"reason", reason,
+	)
+
+	queued := types.QueuedUpdate{
+		Instance: app.Instance,
+		App:      app.Name,
+		UUID:     app.UUID,
+		FromTag:  plan.fromTag,
+		ToTag:    plan.toTag,
+		QueuedAt: time.Now(),
+		Reason:   reason,
+	}
+	if previous, ok := l.queued[key]; ok {
+		queued.QueuedAt = previous.QueuedAt
+	}
+	if l.perServer > 0 {
+		queued.Server = l.servers[key]
+	}
+	l.queue = append(l.queue, queued)
+	l.handled[key] = true
+
+	plan.status.Deferred = "queued: " + reason
+	l.w.addHistory(app, types.HistoryEntry{
+		Event:   types.EventSkip,
+		FromTag: plan.fromTag,
+		ToTag:   plan.toTag,
+		Policy:  plan.status.Policy,
+		Actor:   plan.actor,
+		Reason:  plan.status.Deferred,
+	})
+	l.w.setStatus(key, plan.status)
+}
+
+// save replaces the queued updates of handled apps and of apps no longer
+// watched by the ones held back in this cycle
+func (l *updateLimits) save() {
+	l.w.update(func(s *state.State) {
+		var queue []types.QueuedUpdate
+		for _, queued := range s.Queue {
+			key := queued.Instance + "/" + queued.UUID
+			if _, watched := l.w.known[key]; watched && !l.handled[key] {
+				queue = append(queue, queued)
+			}
+		}
+		s.Queue = append(queue, l.queue...)
+	})
+}
+
+// server returns the name of the Coolify server plan's app runs on. Servers
+// resolved with the app's location are reused, the others are looked up at most
+// once per instance and cycle. If that fails, the instance counts as a single
+// server.
+func (l *updateLimits) server(ctx context.Context, plan *plannedUpdate) string {
+	key := appKey(plan.app)
+	if server, ok := l.servers[key]; ok {
+		return server
+	}
+
+	instance := plan.app.Instance
+	if !l.lookedUp[instance] {
+		l.lookedUp[instance] = true
+		servers, err := plan.client.ServerNames(ctx)
+		if err != nil {
+			l.w.logger.Warn("Failed to look up servers, limiting updates per instance",
+				"instance", instance,
+				"error", err,
+			)
+		}
+		for uuid, server := range servers {
+			l.servers[instance+"/"+uuid] = server
+		}
+	}
+	return l.servers[key]
+}
+
+// serverName names a server for messages, an unknown one is the whole instance
+func serverName(server string) string {
+	if server == "" {
+		return "(unknown)"
+	}
+	return server
+}
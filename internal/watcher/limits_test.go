@@ -0,0 +1,212 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// fakeServers serves a Coolify instance with services a and b on server alpha
+// and c and d on server beta, recording the services restarted. deployments
+// returns the running deployments as JSON, none if it is nil.
+func fakeServers(t *testing.T, deployments func() string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu        sync.Mutex
+		restarted []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/api/v1/servers":
+			fmt.Fprint(w, `[{"uuid": "alpha-uuid", "name": "alpha"}, {"uuid": "beta-uuid", "name": "beta"}]`)
+		case r.URL.Path == "/api/v1/servers/alpha-uuid/resources":
+			fmt.Fprint(w, `[{"uuid": "a"}, {"uuid": "b"}]`)
+		case r.URL.Path == "/api/v1/servers/beta-uuid/resources":
+			fmt.Fprint(w, `[{"uuid": "c"}, {"uuid": "d"}]`)
+		case r.URL.Path == "/api/v1/deployments":
+			if deployments == nil {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			fmt.Fprint(w, deployments())
+		case r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/envs"):
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restart"):
+			restarted = append(restarted, strings.Split(r.URL.Path, "/")[4])
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), restarted...)
+	}
+}
+
+func TestUpdateLimits(t *testing.T) {
+	var running atomic.Value
+	running.Store(`[]`)
+	server, restarted := fakeServers(t, func() string { return running.Load().(string) })
+	client := coolify.NewClient(server.URL, "token")
+
+	w := newTestWatcher(t)
+	apps := make(map[string]types.AppConfig)
+	w.known = make(map[string]types.AppConfig)
+	for _, uuid := range []string{"a", "b", "c", "d"} {
+		app := types.AppConfig{Name: "app-" + uuid, UUID: uuid, Instance: "default", Type: types.ResourceService, TagEnv: "VERSION"}
+		apps[uuid] = app
+		w.known[appKey(app)] = app
+	}
+	plansFor := func(uuids ...string) []*plannedUpdate {
+		var plans []*plannedUpdate
+		for _, uuid := range uuids {
+			app := apps[uuid]
+			plans = append(plans, &plannedUpdate{
+				app:     app,
+				client:  client,
+				status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+				fromTag: "1.0",
+				toTag:   "1.1",
+			})
+		}
+		return plans
+	}
+	appsFor := func(uuids ...string) []types.AppConfig {
+		var checked []types.AppConfig
+		for _, uuid := range uuids {
+			checked = append(checked, apps[uuid])
+		}
+		return checked
+	}
+	queued := func() []string {
+		var uuids []string
+		for _, update := range w.GetStatus().Queue {
+			uuids = append(uuids, update.UUID)
+		}
+		return uuids
+	}
+
+	// d was queued by an earlier cycle and goes first, then one update per server
+	earlier := time.Now().Add(-time.Hour)
+	w.update(func(s *state.State) {
+		s.Queue = []types.QueuedUpdate{{Instance: "default", App: "app-d", UUID: "d", QueuedAt: earlier}}
+	})
+	w.config.MaxUpdatesPerCycle, w.config.MaxConcurrentPerServer = 3, 1
+	updates, err := w.applyAll(context.Background(), appsFor("a", "b", "c", "d"), plansFor("a", "b", "c", "d"))
+	if err != nil || updates != 2 {
+		t.Fatalf("expected 2 updates, got %d, %v", updates, err)
+	}
+	if got := fmt.Sprint(restarted()); got != "[d a]" {
+		t.Errorf("expected queued update first, got restarts %s", got)
+	}
+	if got := fmt.Sprint(queued()); got != "[b c]" {
+		t.Fatalf("expected b and c to be queued, got %s", got)
+	}
+	queue := w.GetStatus().Queue
+	if queue[0].Server != "alpha" || queue[0].Reason != "limit of 1 concurrent deployments on server alpha reached" {
+		t.Errorf("expected b to be held back by its server, got %+v", queue[0])
+	}
+	if status := w.appState("default/c").Status; status == nil || !strings.HasPrefix(status.Deferred, "queued: ") {
+		t.Errorf("expected queued update in the status, got %+v", status)
+	}
+
+	// Queued apps are due, c no longer needs its update and leaves the queue
+	w.update(func(s *state.State) {
+		s.App("default/b").NextCheck = time.Now().Add(time.Hour)
+	})
+	if due := w.dueApps(appsFor("b"), time.Now()); len(due) != 1 {
+		t.Errorf("expected queued app to be due, got %v", due)
+	}
+	if _, err := w.applyAll(context.Background(), appsFor("c"), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := fmt.Sprint(queued()); got != "[b]" {
+		t.Fatalf("expected only b to stay queued, got %s", got)
+	}
+	queuedAt := w.GetStatus().Queue[0].QueuedAt
+
+	// A batch of 25% of four apps allows one update, b goes before a
+	w.config.MaxUpdatesPerCycle, w.config.MaxConcurrentPerServer, w.config.BatchSize = 0, 0, "25%"
+	if updates, err := w.applyAll(context.Background(), appsFor("a", "b"), plansFor("a", "b")); err != nil || updates != 1 {
+		t.Fatalf("expected 1 update, got %d, %v", updates, err)
+	}
+	if got := fmt.Sprint(restarted()); got != "[d a b]" {
+		t.Errorf("expected b to be updated, got restarts %s", got)
+	}
+	queue = w.GetStatus().Queue
+	if len(queue) != 1 || queue[0].UUID != "a" || queue[0].Reason != "limit of 1 updates per cycle reached" {
+		t.Errorf("expected a to be queued by the batch size, got %+v", queue)
+	}
+	if !queue[0].QueuedAt.After(queuedAt) {
+		t.Errorf("expected a to be queued now, got %v", queue[0].QueuedAt)
+	}
+
+	// Outside its maintenance window a is neither queued nor counted, c goes ahead
+	w.config.MaxUpdatesPerCycle, w.config.BatchSize = 1, ""
+	plans := plansFor("a", "c")
+	start := time.Now().UTC().Add(2 * time.Hour)
+	plans[0].app.MaintenanceWindows = []types.MaintenanceWindow{{
+		Start:    start.Format("15:04"),
+		End:      start.Add(time.Hour).Format("15:04"),
+		Timezone: "UTC",
+	}}
+	if updates, err := w.applyAll(context.Background(), appsFor("a", "c"), plans); err != nil || updates != 1 {
+		t.Fatalf("expected 1 update, got %d, %v", updates, err)
+	}
+	if got := fmt.Sprint(restarted()); got != "[d a b c]" {
+		t.Errorf("expected c to be updated, got restarts %s", got)
+	}
+	if queue := w.GetStatus().Queue; len(queue) != 0 {
+		t.Errorf("expected no queued updates, got %+v", queue)
+	}
+	if status := w.appState("default/a").Status; status == nil || status.Deferred != "outside maintenance window" {
+		t.Errorf("expected a to wait for its window, got %+v", status)
+	}
+
+	// A deployment still running on alpha from an earlier cycle holds a back, c goes ahead
+	w.config.MaxUpdatesPerCycle, w.config.MaxConcurrentPerServer = 0, 1
+	running.Store(`[{"deployment_uuid": "earlier", "status": "in_progress", "server_name": "alpha"}]`)
+	if updates, err := w.applyAll(context.Background(), appsFor("a", "c"), plansFor("a", "c")); err != nil || updates != 1 {
+		t.Fatalf("expected 1 update, got %d, %v", updates, err)
+	}
+	if got := fmt.Sprint(restarted()); got != "[d a b c c]" {
+		t.Errorf("expected only c to be updated, got restarts %s", got)
+	}
+	if queue := w.GetStatus().Queue; len(queue) != 1 || queue[0].UUID != "a" || queue[0].Server != "alpha" {
+		t.Errorf("expected a to be queued behind the running deployment, got %+v", queue)
+	}
+}
+
+func TestUpdateLimitsReuseResolvedServers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	w := newTestWatcher(t)
+	w.config.MaxConcurrentPerServer = 1
+	w.rememberServers("default", []types.CoolifyApplication{{UUID: "a", Server: "alpha"}})
+
+	// Resolved with the locations of discovery, the server isn't looked up again
+	app := types.AppConfig{Name: "app-a", UUID: "a", Instance: "default"}
+	plan := &plannedUpdate{app: app, client: coolify.NewClient(server.URL, "token")}
+	limits := w.newUpdateLimits(1)
+	if got := limits.server(context.Background(), plan); got != "alpha" {
+		t.Errorf("expected server alpha, got '%s'", got)
+	}
+}
@@ -0,0 +1,142 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/leader"
+)
+
+// ErrNotLeader is returned when a change is attempted without holding the
+// leader lock
+var ErrNotLeader = errors.New("another patrol instance holds the leader lock")
+
+// UseLock makes the watcher hold lock while it checks and updates apps.
+// Until it gets the lock, the watcher stands by: it follows the state saved
+// by the leader for its status but checks and changes nothing. The lock is
+// renewed, or retried, every renew. Must be called before Start.
+func (w *Watcher) UseLock(lock leader.Lock, renew time.Duration) {
+	w.lock, w.lockRenew = lock, renew
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.standby = true
+}
+
+// Standby reports whether the watcher waits for another instance to give up
+// the leader lock
+func (w *Watcher) Standby() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.standby
+}
+
+// holdLock takes or renews the leader lock and reports whether this instance
+// may change anything. Without a lock it always may.
+func (w *Watcher) holdLock(ctx context.Context) bool {
+	if w.lock == nil {
+		return true
+	}
+	// Renewed by a running cycle and by applyPlan at the same time
+	w.lockMu.Lock()
+	defer w.lockMu.Unlock()
+
+	held, err := w.lock.Acquire(ctx)
+	if ctx.Err() != nil {
+		// Shutting down, the lock is released or expires
+		return false
+	}
+	if err != nil {
+		w.logger.Error("Failed to acquire leader lock", "error", err)
+		held = false
+	}
+
+	w.mu.Lock()
+	wasStandby := w.standby
+	w.standby = !held
+	w.mu.Unlock()
+
+	switch {
+	case held && wasStandby:
+		// Continue where the previous leader stopped
+		w.logger.Info("Acquired leader lock")
+		w.refreshState()
+	case !held && !wasStandby:
+		w.logger.Warn("Lost the leader lock, standing by")
+		// The new leader doesn't know them, they would only run once this instance leads again
+		w.failQueuedJobs(ErrNotLeader)
+	case !held:
+		w.logger.Debug("Another patrol instance holds the leader lock, standing by")
+		w.refreshState()
+	}
+	return held
+}
+
+// whileLeader runs fn, renewing the leader lock every lockRenew meanwhile.
+// Losing the lock cancels the context of fn, which then fails with
+// ErrNotLeader.
+func (w *Watcher) whileLeader(ctx context.Context, fn func(ctx context.Context) error) error {
+	if w.lock == nil {
+		return fn(ctx)
+	}
+
+	fnCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(w.lockRenew)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// Renewed with ctx, ending fn must not look like a lost lock
+				if !w.holdLock(ctx) {
+					cancel(ErrNotLeader)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(fnCtx)
+	close(stop)
+	<-stopped
+	if err != nil && errors.Is(context.Cause(fnCtx), ErrNotLeader) {
+		return ErrNotLeader
+	}
+	return err
+}
+
+// releaseLock gives the leader lock up, letting a waiting instance take over
+func (w *Watcher) releaseLock() {
+	if w.lock == nil || w.Standby() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.lock.Release(ctx); err != nil {
+		w.logger.Error("Failed to release leader lock", "error", err)
+		return
+	}
+	w.logger.Info("Released leader lock")
+}
+
+// refreshState replaces the state in memory by the saved one, which only the
+// leader writes
+func (w *Watcher) refreshState() {
+	saved, err := w.store.Load()
+	if err != nil {
+		w.logger.Error("Failed to load state", "error", err)
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.state = saved
+}
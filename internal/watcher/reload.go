@@ -0,0 +1,131 @@
+package watcher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/config"
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Reload swaps in a newly loaded configuration together with the Coolify
+// clients of its instances, keeping all state. It waits for a running cycle or
+// job to finish and returns the changes, see config.Diff. Apps whose schedule
+// or interval changed are rescheduled from their last check, as are failing
+// apps if anything changed, which may have fixed them.
+func (w *Watcher) Reload(cfg *types.Config, coolifyClients map[string]*coolify.Client) ([]string, error) {
+	w.cycleMu.Lock()
+	defer w.cycleMu.Unlock()
+
+	if cfg.StateDir != w.config.StateDir {
+		err := fmt.Errorf("state_dir can't be changed without a restart")
+		w.ReloadFailed(err)
+		return nil, err
+	}
+	if cfg.Leader != w.config.Leader {
+		err := fmt.Errorf("leader can't be changed without a restart")
+		w.ReloadFailed(err)
+		return nil, err
+	}
+	for _, instance := range cfg.Instances {
+		if _, ok := coolifyClients[instance.Name]; !ok {
+			err := fmt.Errorf("no Coolify client for instance '%s'", instance.Name)
+			w.ReloadFailed(err)
+			return nil, err
+		}
+	}
+
+	previous := w.config
+	changes := config.Diff(previous, cfg)
+	now := time.Now()
+
+	w.mu.Lock()
+	w.config, w.coolifyClients = cfg, coolifyClients
+	known := make(map[string]types.AppConfig, len(w.known))
+	next := make(map[string]time.Time)
+	var failing []string
+	for key, app := range w.known {
+		reloaded, ok := reloadedApp(cfg, app)
+		if !ok {
+			continue // Removed from the config
+		}
+		known[key] = reloaded
+
+		appState, ok := w.state.Apps[key]
+		if !ok || appState.LastCheck.IsZero() {
+			continue
+		}
+		oldDefaults, newDefaults := appDefaults(previous, app), appDefaults(cfg, reloaded)
+		backedOff := appState.Failures > 0 && len(changes) > 0
+		if !backedOff && oldDefaults.Schedule == newDefaults.Schedule && oldDefaults.Interval == newDefaults.Interval {
+			continue
+		}
+		if backedOff {
+			failing = append(failing, key)
+		}
+		if schedule, err := config.ParseSchedule(newDefaults); err == nil {
+			next[key] = schedule.Next(appState.LastCheck)
+		}
+	}
+	w.known = known
+	w.reloadedAt = now
+	w.reloadErr = ""
+	standby := w.standby
+	w.mu.Unlock()
+
+	// Only the leader writes the state
+	if len(next) > 0 && !standby {
+		w.update(func(s *state.State) {
+			for key, t := range next {
+				s.App(key).NextCheck = t
+			}
+			for _, key := range failing {
+				resetBackoff(s.App(key))
+			}
+		})
+	}
+
+	// Picks up a changed registry_concurrency
+	w.semMu.Lock()
+	w.registrySems = make(map[string]chan struct{})
+	w.semMu.Unlock()
+
+	// The next cycle may have moved
+	w.wakeUp()
+	return changes, nil
+}
+
+// ReloadFailed records why a configuration couldn't be reloaded. The error is
+// shown in the status until the next successful reload.
+func (w *Watcher) ReloadFailed(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reloadErr = err.Error()
+}
+
+// reloadedApp returns the configuration of a watched app under cfg, and false
+// if it is no longer configured. Discovered apps keep their configuration,
+// it comes from their annotations.
+func reloadedApp(cfg *types.Config, app types.AppConfig) (types.AppConfig, bool) {
+	if len(cfg.Apps) == 0 {
+		return app, true
+	}
+	for _, configured := range cfg.Apps {
+		if configured.Instance == app.Instance && configured.Name == app.Name {
+			configured.UUID = app.UUID // May have been looked up by name
+			return configured, true
+		}
+	}
+	return types.AppConfig{}, false
+}
+
+// wakeUp makes the loop run queued jobs and reconsider when the next cycle
+// starts. A pending wake-up covers later ones as well.
+func (w *Watcher) wakeUp() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
@@ -2,296 +2,1780 @@ package watcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/robfig/cron/v3"
+	"github.com/chrisdietr/coolify-patrol/internal/config"
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/dockerfile"
+	"github.com/chrisdietr/coolify-patrol/internal/leader"
+	"github.com/chrisdietr/coolify-patrol/internal/registry"
+	"github.com/chrisdietr/coolify-patrol/internal/semver"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/internal/window"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Watcher monitors applications and handles updates
+type Watcher struct {
+	// Swapped by Reload with both cycleMu and mu held, so cycles and jobs
+	// may read them freely and everything else under mu
+	config         *types.Config
+	coolifyClients map[string]*coolify.Client // Keyed by instance name
+	registryClient *registry.Client
+	logger         *slog.Logger
+	dryRun         bool
+	
+	// State tracking, persisted through store after every change. mu guards
+	// state and inProgress, which HTTP handlers read while a cycle runs.
+	mu             sync.RWMutex
+	store          state.Store
+	state          *state.State
+	changes        uint64                     // Changes of the state so far
+	inProgress     map[string]types.AppConfig // Apps being checked right now, keyed by appKey
+	known          map[string]types.AppConfig // Apps seen in the last cycle, keyed by appKey
+	servers        map[string]string          // Servers of the apps whose locations the last cycle resolved, keyed by appKey
+	rejected       map[string]bool            // Instances that rejected their API token in the running cycle
+	reloadedAt     time.Time                  // Last successful config reload
+	reloadErr      string                     // Why the last config reload failed, cleared by a successful one
+	standby        bool                       // Another instance holds the leader lock
+
+	cycleMu        sync.Mutex // Held while a check cycle or job runs
+	saveMu         sync.Mutex // Held while the state is written, outside mu
+	savedChanges   uint64     // Changes of the state written so far, guarded by saveMu
+	semMu          sync.Mutex
+	registrySems   map[string]chan struct{} // Limits parallel requests per registry host
+
+	jobsMu         sync.Mutex
+	jobs           []*types.Job  // Queued, running and recently finished jobs, oldest first
+	wake           chan struct{} // Wakes the loop when a job is queued or the config reloaded
+
+	lock           leader.Lock // Held while changing anything, see UseLock
+	lockRenew      time.Duration
+	lockMu         sync.Mutex // Serializes renewals of the lock
+}
+
+// NewWatcher creates a new watcher instance. coolifyClients must hold a client
+// for every instance in cfg.Instances, keyed by instance name. The state saved
+// by a previous run is loaded from store.
+func NewWatcher(cfg *types.Config, coolifyClients map[string]*coolify.Client, registryClient *registry.Client, store state.Store, logger *slog.Logger, dryRun bool) *Watcher {
+	saved, err := store.Load()
+	if err != nil {
+		logger.Error("Failed to load state, starting with an empty one", "error", err)
+		saved = state.New()
+	}
+
+	return &Watcher{
+		config:         cfg,
+		coolifyClients: coolifyClients,
+		registryClient: registryClient,
+		logger:         logger,
+		dryRun:         dryRun,
+		store:          store,
+		state:          saved,
+		inProgress:     make(map[string]types.AppConfig),
+		registrySems:   make(map[string]chan struct{}),
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// update changes the state under the lock and flushes it to the store. The
+// store writes a copy outside the lock, so readers aren't held up by the disk,
+// and changes made while a write is running are saved together by the next
+// one. Save failures are only logged, the in-memory state stays authoritative
+// until the next save.
+func (w *Watcher) update(change func(s *state.State)) {
+	w.mu.Lock()
+	change(w.state)
+	w.changes++
+	changed := w.changes
+	w.mu.Unlock()
+
+	w.saveMu.Lock()
+	defer w.saveMu.Unlock()
+	if w.savedChanges >= changed {
+		return
+	}
+
+	w.mu.RLock()
+	snapshot, err := w.state.Clone()
+	w.savedChanges = w.changes
+	w.mu.RUnlock()
+	if err == nil {
+		err = w.store.Save(snapshot)
+	}
+	if err != nil {
+		w.logger.Error("Failed to save state", "error", err)
+	}
+}
+
+// appState returns a copy of the state of an app
+func (w *Watcher) appState(key string) state.AppState {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if appState, ok := w.state.Apps[key]; ok {
+		return *appState
+	}
+	return state.AppState{}
+}
+
+// Start begins the watcher loop
+func (w *Watcher) Start(ctx context.Context, runOnce bool) error {
+	w.mu.RLock()
+	scheduleInfo := w.config.Defaults.Interval
+	if w.config.Defaults.Schedule != "" {
+		scheduleInfo = fmt.Sprintf("cron: %s", w.config.Defaults.Schedule)
+	}
+	w.mu.RUnlock()
+	
+	w.logger.Info("Starting coolify-patrol watcher",
+		"dry_run", w.dryRun,
+		"schedule", scheduleInfo,
+		"run_once", runOnce,
+	)
+
+	defer w.releaseLock()
+
+	// Initial check, of every app when running once and otherwise of the
+	// apps due according to the saved state
+	if w.holdLock(ctx) {
+		if err := w.checkApplications(ctx, runOnce); err != nil {
+			w.logger.Error("Initial check failed", "error", err)
+			if runOnce {
+				return err
+			}
+		}
+	} else if runOnce {
+		return ErrNotLeader
+	}
+
+	if runOnce {
+		return nil
+	}
+
+	return w.run(ctx)
+}
+
+// run starts a check cycle whenever an app is due. Every app follows its own
+// schedule or interval, the default schedules of the instances also start
+// cycles so that new apps are picked up. Jobs queued through the API run in
+// between. With a leader lock, cycles and jobs only run while it is held, and
+// it is renewed or retried in between.
+func (w *Watcher) run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		cycleAt := w.nextCycle(now)
+		w.logger.Debug("Next check cycle scheduled", "at", cycleAt)
+		next := cycleAt
+		if renewAt := now.Add(w.lockRenew); w.lock != nil && renewAt.Before(next) {
+			next = renewAt
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			w.logger.Info("Watcher stopped")
+			return ctx.Err()
+		case <-timer.C:
+			if !w.holdLock(ctx) || time.Now().Before(cycleAt) {
+				continue
+			}
+			if err := w.checkApplications(ctx, false); err != nil {
+				w.logger.Error("Check cycle failed", "error", err)
+			}
+		case <-w.wake:
+			timer.Stop()
+			if !w.Standby() {
+				w.runJobs(ctx)
+			}
+		}
+	}
+}
+
+// nextCycle returns when the next check cycle should start: the earliest next
+// check of the apps seen in the last cycle or of the default schedules
+func (w *Watcher) nextCycle(now time.Time) time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	w.mu.RLock()
+	defaults := []*types.DefaultsConfig{&w.config.Defaults}
+	for _, instance := range w.config.Instances {
+		if instance.Defaults != nil {
+			defaults = append(defaults, instance.Defaults)
+		}
+	}
+	for _, d := range defaults {
+		if schedule, err := config.ParseSchedule(d); err == nil {
+			consider(schedule.Next(now))
+		}
+	}
+
+	for key := range w.known {
+		if appState, ok := w.state.Apps[key]; ok {
+			consider(appState.NextCheck)
+		}
+	}
+	w.mu.RUnlock()
+
+	// Never spin on a check that is overdue but wasn't run
+	if earliest := now.Add(time.Second); next.Before(earliest) {
+		next = earliest
+	}
+	return next
+}
+
+// checkApplications performs one complete check cycle: all due apps, or all
+// apps if all is set, are checked in parallel, then the updates found are
+// applied one at a time
+func (w *Watcher) checkApplications(ctx context.Context, all bool) error {
+	// A slow cycle can outlast the interval, never run two at once
+	if !w.cycleMu.TryLock() {
+		w.logger.Warn("Previous check cycle is still running, skipping this one")
+		return nil
+	}
+	defer w.cycleMu.Unlock()
+
+	return w.whileLeader(ctx, func(ctx context.Context) error {
+		_, err := w.runCycle(ctx, func(apps []types.AppConfig, now time.Time) []types.AppConfig {
+			if all {
+				return apps
+			}
+			return w.dueApps(apps, now)
+		})
+		return err
+	})
+}
+
+// runCycle checks the apps chosen by selectApps from all watched apps and
+// applies the updates found. It returns the number of updates applied and
+// must be called with cycleMu held.
+func (w *Watcher) runCycle(ctx context.Context, selectApps func(apps []types.AppConfig, now time.Time) []types.AppConfig) (int, error) {
+	cycleStart := time.Now()
+	w.update(func(s *state.State) {
+		s.LastCheck = cycleStart
+	})
+	w.logger.Info("Starting check cycle")
+
+	apps, err := w.getApplicationsToCheck(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("getting applications: %w", err)
+	}
+
+	known := make(map[string]types.AppConfig, len(apps))
+	for _, app := range apps {
+		known[appKey(app)] = app
+	}
+	w.mu.Lock()
+	// Apps of an instance that couldn't be listed are still watched
+	for key, app := range w.known {
+		if _, ok := known[key]; !ok && w.rejected[app.Instance] {
+			known[key] = app
+		}
+	}
+	w.known = known
+	w.mu.Unlock()
+
+	apps, selfApps := w.splitSelf(apps)
+	apps, selfApps = selectApps(apps, cycleStart), selectApps(selfApps, cycleStart)
+
+	w.logger.Info("Found applications to check", "count", len(apps))
+	for _, app := range selfApps {
+		w.logger.Info("Checking patrol's own deployment",
+			"instance", app.Instance,
+			"app", app.Name,
+			"uuid", app.UUID,
+		)
+	}
+
+	// Updating patrol restarts this very process, so its own update comes
+	// last, once every other app has been handled and its status recorded
+	checked := append(apps, selfApps...)
+	plans, err := w.checkAll(ctx, checked)
+	w.scheduleChecks(checked, cycleStart)
+	if err != nil {
+		return 0, err
+	}
+
+	updates, err := w.applyAll(ctx, checked, plans)
+	if err != nil {
+		return updates, err
+	}
+
+	w.logger.Info("Check cycle completed", "duration", time.Since(cycleStart), "updates", updates)
+	return updates, nil
+}
+
+// applyAll applies the updates found by checking apps one at a time, within
+// the update limits, and returns the number applied. Updates beyond the limits
+// are queued for the next cycles.
+func (w *Watcher) applyAll(ctx context.Context, apps []types.AppConfig, plans []*plannedUpdate) (int, error) {
+	limits := w.newUpdateLimits(len(w.known))
+	defer limits.save()
+	limits.checked(apps, plans)
+	limits.order(plans)
+
+	updates := 0
+	for _, plan := range plans {
+		if err := ctx.Err(); err != nil {
+			return updates, err
+		}
+
+		plan.limits = limits
+		applied, err := w.applyUpdate(ctx, plan)
+		if err != nil {
+			return updates, err
+		}
+		limits.done(ctx, plan, applied)
+		if applied {
+			updates++
+		}
+	}
+	return updates, nil
+}
+
+// checkAll checks apps with a bounded pool of workers and returns the updates
+// to apply, in the order of apps. It only fails if the cycle should be aborted.
+func (w *Watcher) checkAll(ctx context.Context, apps []types.AppConfig) ([]*plannedUpdate, error) {
+	checkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		plans    = make([]*plannedUpdate, len(apps))
+		jobs     = make(chan int)
+		wg       sync.WaitGroup
+		errMu    sync.Mutex
+		abortErr error
+	)
+
+	workers := min(max(w.config.CheckConcurrency, 1), len(apps))
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				plan, err := w.checkApp(checkCtx, apps[i])
+				if err != nil {
+					errMu.Lock()
+					if abortErr == nil {
+						abortErr = err
+					}
+					errMu.Unlock()
+					cancel()
+					continue
+				}
+				plans[i] = plan
+			}
+		}()
+	}
+
+feed:
+	for i := range apps {
+		select {
+		case jobs <- i:
+		case <-checkCtx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if abortErr != nil {
+		return nil, abortErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var pending []*plannedUpdate
+	for _, plan := range plans {
+		if plan != nil {
+			pending = append(pending, plan)
+		}
+	}
+	return pending, nil
+}
+
+// dueApps returns the apps whose next check is at or before now. Apps never
+// checked before and apps with a queued update are always due.
+func (w *Watcher) dueApps(apps []types.AppConfig, now time.Time) []types.AppConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	queued := make(map[string]bool, len(w.state.Queue))
+	for _, update := range w.state.Queue {
+		queued[update.Instance+"/"+update.UUID] = true
+	}
+
+	var due []types.AppConfig
+	for _, app := range apps {
+		key := appKey(app)
+		appState, ok := w.state.Apps[key]
+		if !ok || !appState.NextCheck.After(now) || queued[key] {
+			due = append(due, app)
+		}
+	}
+	return due
+}
+
+// scheduleChecks sets the next check of apps checked in the cycle started at
+// cycleStart, following each app's schedule or interval. Failing apps back
+// off, see checkBackoff.
+func (w *Watcher) scheduleChecks(apps []types.AppConfig, cycleStart time.Time) {
+	next := make(map[string]time.Time, len(apps))
+	for _, app := range apps {
+		defaults := w.defaultsFor(app)
+		schedule, err := config.ParseSchedule(defaults)
+		if err != nil {
+			w.logger.Error("Invalid check schedule", "instance", app.Instance, "app", app.Name, "error", err)
+			continue
+		}
+		key := appKey(app)
+		next[key] = schedule.Next(cycleStart)
+
+		if failures := w.appState(key).Failures; failures > 1 {
+			limit, _ := time.ParseDuration(defaults.MaxBackoff)
+			next[key] = checkBackoff(schedule, cycleStart, failures, limit)
+			w.logger.Info("Backing off checks of failing application",
+				"instance", app.Instance,
+				"app", app.Name,
+				"failures", failures,
+				"next_check", next[key],
+			)
+		}
+	}
+
+	w.update(func(s *state.State) {
+		for key, t := range next {
+			s.App(key).NextCheck = t
+		}
+	})
+}
+
+// updateDelay returns the pause before updating app
+func (w *Watcher) updateDelay(app types.AppConfig) time.Duration {
+	delay, _ := time.ParseDuration(w.defaultsFor(app).UpdateDelay)
+	return delay
+}
+
+// checkApp checks a single app and returns the update to apply, if any.
+// Failures are logged, an error is only returned if the rest of the cycle
+// should be aborted.
+func (w *Watcher) checkApp(ctx context.Context, app types.AppConfig) (*plannedUpdate, error) {
+	if w.instanceRejected(app.Instance) {
+		return nil, nil
+	}
+
+	done := w.markInProgress(app)
+	plan, err := w.planUpdate(ctx, app)
+	done()
+
+	return plan, w.finishApp(app, err)
+}
+
+// applyUpdate applies an update found in the check phase and reports whether
+// Coolify was actually changed. Like checkApp, it only returns an error if the
+// rest of the cycle should be aborted.
+func (w *Watcher) applyUpdate(ctx context.Context, plan *plannedUpdate) (bool, error) {
+	if w.instanceRejected(plan.app.Instance) {
+		return false, nil
+	}
+
+	done := w.markInProgress(plan.app)
+	applied, err := w.applyPlan(ctx, plan)
+	done()
+
+	return applied, w.finishApp(plan.app, err)
+}
+
+// markInProgress flags app as being worked on until the returned func is called
+func (w *Watcher) markInProgress(app types.AppConfig) func() {
+	key := appKey(app)
+	w.mu.Lock()
+	w.inProgress[key] = app
+	w.mu.Unlock()
+
+	return func() {
+		w.mu.Lock()
+		delete(w.inProgress, key)
+		w.mu.Unlock()
+	}
+}
+
+// finishApp records the outcome of checking or updating an app and logs
+// failures. It returns an error only if the rest of the cycle should be aborted.
+func (w *Watcher) finishApp(app types.AppConfig, err error) error {
+	key := appKey(app)
+
+	// Cancelled by shutdown or by another app aborting the cycle, not a failure of this app
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	// The new leader takes over, this instance must not even record the failure
+	if errors.Is(err, ErrNotLeader) {
+		return err
+	}
+
+	if err == nil {
+		if previous := w.appState(key); previous.Failures > 0 || previous.LastError != "" {
+			w.update(func(s *state.State) {
+				appState := s.App(key)
+				appState.Failures = 0
+				appState.LastError = ""
+			})
+		}
+		return nil
+	}
+
+	var updateErr *updateError
+	failedUpdate := errors.As(err, &updateErr)
+	now := time.Now()
+	w.update(func(s *state.State) {
+		appState := s.App(key)
+		appState.Failures++
+		appState.LastError = err.Error()
+		if failedUpdate {
+			return
+		}
+		// Apps that never passed a check still show up in the status, as failing
+		appState.LastCheck = now
+		if appState.Status == nil {
+			appState.Status = &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, Image: app.Image}
+		}
+		appState.Status.LastCheck = now
+	})
+
+	if failedUpdate {
+		w.addHistory(app, types.HistoryEntry{
+			Event:   types.EventUpdateFailed,
+			FromTag: updateErr.fromTag,
+			ToTag:   updateErr.toTag,
+			Reason:  updateErr.err.Error(),
+		})
+	} else {
+		w.addHistory(app, types.HistoryEntry{Event: types.EventCheckFailed, Reason: err.Error()})
+	}
+
+	// A rejected token fails every remaining app of the instance the same way,
+	// the other instances go on
+	if errors.Is(err, coolify.ErrUnauthorized) {
+		w.rejectInstance(app.Instance, err)
+		return nil
+	}
+	if errors.Is(err, coolify.ErrNotFound) && updateErr == nil {
+		w.logger.Warn("Application not found in Coolify, skipping; if it was recreated, remove its uuid from the config to look it up by name",
+			"instance", app.Instance,
+			"app", app.Name,
+			"uuid", app.UUID,
+			"error", err,
+		)
+		return nil
+	}
+	w.logger.Error("Failed to check application",
+		"instance", app.Instance,
+		"app", app.Name,
+		"uuid", app.UUID,
+		"error", err,
+	)
+	return nil
+}
+
+// rejectInstance skips the remaining apps of an instance that rejected its
+// API token for the running cycle
+func (w *Watcher) rejectInstance(instance string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.rejected[instance] {
+		return
+	}
+	if w.rejected == nil {
+		w.rejected = make(map[string]bool)
+	}
+	w.rejected[instance] = true
+	w.logger.Error("Coolify instance rejected the API token, skipping its apps in this cycle",
+		"instance", instance,
+		"error", err,
+	)
+}
+
+// instanceRejected reports whether an instance rejected its API token in the
+// running cycle
+func (w *Watcher) instanceRejected(instance string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.rejected[instance]
+}
+
+// updateError marks a failure of the update itself, as opposed to the check
+type updateError struct {
+	fromTag, toTag string
+	err            error
+}
+
+// Error implements the error interface
+func (e *updateError) Error() string {
+	return "performing update: " + e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *updateError) Unwrap() error {
+	return e.err
+}
+
+// addHistory records a decision about an app
+func (w *Watcher) addHistory(app types.AppConfig, entry types.HistoryEntry) {
+	entry.Time = time.Now()
+	entry.Instance = app.Instance
+	entry.App = app.Name
+	entry.UUID = app.UUID
+	if entry.Actor == "" {
+		entry.Actor = types.ActorScheduler
+	}
+	entry.DryRun = w.dryRun
+	w.update(func(s *state.State) {
+		s.AddHistory(entry)
+	})
+}
+
+// History returns the recorded decisions matching query, newest first
+func (w *Watcher) History(query types.HistoryQuery) []types.HistoryEntry {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state.QueryHistory(query)
+}
+
+// splitSelf separates patrol's own deployment from the other apps. It is only
+// returned when self-updates are enabled, with the self policy applied.
+func (w *Watcher) splitSelf(apps []types.AppConfig) ([]types.AppConfig, []types.AppConfig) {
+	var others, self []types.AppConfig
+	for _, app := range apps {
+		if !config.IsSelf(&w.config.Self, app.UUID, app.Image) {
+			others = append(others, app)
+			continue
+		}
+
+		if !w.config.Self.Update {
+			w.logger.Info("Skipping patrol's own deployment, self-update is disabled",
+				"instance", app.Instance,
+				"app", app.Name,
+				"uuid", app.UUID,
+			)
+			continue
+		}
+
+		if w.config.Self.Policy != "" {
+			app.Policy = w.config.Self.Policy
+		}
+		self = append(self, app)
+	}
+	return others, self
+}
+
+// getApplicationsToCheck returns the list of applications to check
+func (w *Watcher) getApplicationsToCheck(ctx context.Context) ([]types.AppConfig, error) {
+	w.mu.Lock()
+	w.servers = nil
+	w.rejected = nil
+	w.mu.Unlock()
+
+	if len(w.config.Apps) > 0 {
+		// Use configured apps
+		return w.resolveAppUUIDs(ctx, w.config.Apps), nil
+	}
+
+	// Auto-discovery mode
+	w.logger.Info("No configured apps, using auto-discovery")
+	
+	coolifyApps, err := w.discoverApps(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Project, environment and server names cost extra API calls, so only
+	// look them up when a filter needs them
+	if config.DiscoveryNeedsLocations(&w.config.Discovery) {
+		if err := w.resolveLocations(ctx, coolifyApps); err != nil {
+			return nil, err
+		}
+	}
+
+	var apps []types.AppConfig
+	for _, coolifyApp := range coolifyApps {
+		if coolify.IsCoolifyInternal(coolifyApp) {
+			w.logger.Debug("Skipping Coolify internal service",
+				"instance", coolifyApp.Instance,
+				"app", coolifyApp.Name,
+				"image", coolifyApp.DockerImage,
+			)
+			continue
+		}
+
+		image, tag := coolify.ExtractImageAndTag(coolifyApp.DockerImage)
+
+		filter, selected := config.SelectDiscovered(&w.config.Discovery, coolifyApp, image)
+		if !selected {
+			w.logger.Debug("Skipping app excluded by discovery filters",
+				"instance", coolifyApp.Instance,
+				"app", coolifyApp.Name,
+				"project", coolifyApp.Project,
+				"environment", coolifyApp.Environment,
+				"server", coolifyApp.Server,
+			)
+			continue
+		}
+
+		app := types.AppConfig{
+			Name:     coolifyApp.Name,
+			UUID:     coolifyApp.UUID,
+			Image:    image,
+			Instance: coolifyApp.Instance,
+			// Policy will be inherited from defaults unless a filter or annotation sets one
+		}
+		if filter != nil {
+			app.Policy = filter.Policy
+			app.Pin = filter.Pin
+		}
+
+		// Annotations set on the resource in Coolify take precedence over filters
+		enable, err := config.ApplyAnnotations(&app, coolifyApp.Annotations)
+		if err != nil {
+			w.logger.Warn("Ignoring invalid patrol annotations",
+				"instance", coolifyApp.Instance,
+				"app", coolifyApp.Name,
+				"error", err,
+			)
+		}
+		if enable != nil && !*enable {
+			w.logger.Debug("Skipping app opted out via annotation",
+				"instance", coolifyApp.Instance,
+				"app", coolifyApp.Name,
+			)
+			continue
+		}
+		if w.config.Discovery.RequireOptIn && enable == nil {
+			continue
+		}
+
+		// Skip apps with 'latest' tag
+		if tag == "latest" {
+			w.logger.Warn("Skipping app with 'latest' tag",
+				"instance", coolifyApp.Instance,
+				"app", coolifyApp.Name,
+				"image", coolifyApp.DockerImage,
+			)
+			continue
+		}
+
+		apps = append(apps, app)
+	}
+
+	w.logger.Info("Auto-discovered applications", "count", len(apps))
+	return apps, nil
+}
+
+// resolveAppUUIDs fills in the UUID of apps configured by name only. Names are
+// looked up on every cycle so that an app recreated in Coolify (and thereby
+// given a new UUID) is followed automatically. Apps that can't be resolved are
+// left out of the cycle.
+func (w *Watcher) resolveAppUUIDs(ctx context.Context, apps []types.AppConfig) []types.AppConfig {
+	listed := make(map[string][]types.CoolifyApplication)
+	listFailed := make(map[string]bool)
+
+	var resolved []types.AppConfig
+	for _, app := range apps {
+		if app.UUID != "" {
+			resolved = append(resolved, app)
+			continue
+		}
+
+		logger := w.logger.With("instance", app.Instance, "app", app.Name, "project", app.Project, "environment", app.Environment)
+		key := nameKey(app)
+
+		candidates, ok := listed[app.Instance]
+		if !ok && !listFailed[app.Instance] {
+			var err error
+			candidates, err = w.listForNameLookup(ctx, app.Instance, apps)
+			if err != nil {
+				logger.Error("Failed to list applications for name lookup", "error", err)
+				listFailed[app.Instance] = true
+			} else {
+				listed[app.Instance] = candidates
+			}
+		}
+
+		if listFailed[app.Instance] {
+			if uuid, ok := w.resolvedUUID(key); ok {
+				logger.Warn("Using cached UUID for app configured by name", "uuid", uuid)
+				app.UUID = uuid
+				resolved = append(resolved, app)
+			}
+			continue
+		}
+
+		matches := coolify.FindByName(candidates, app.Name, app.Project, app.Environment)
+		switch len(matches) {
+		case 0:
+			logger.Warn("No Coolify application matches the configured name, skipping app")
+			// Deleted or renamed in Coolify, its status would linger in /status otherwise
+			if previous, ok := w.resolvedUUID(key); ok {
+				old := app
+				old.UUID = previous
+				w.update(func(s *state.State) {
+					delete(s.Apps, appKey(old))
+					delete(s.ResolvedUUIDs, key)
+				})
+			}
+			continue
+		case 1:
+			// Found exactly one, handled below
+		default:
+			var uuids []string
+			for _, match := range matches {
+				uuids = append(uuids, match.UUID)
+			}
+			logger.Warn("Configured name matches several Coolify applications, skipping app; set uuid, project or environment to disambiguate",
+				"candidates", uuids,
+			)
+			continue
+		}
+
+		uuid := matches[0].UUID
+		previous, ok := w.resolvedUUID(key)
+		if ok && previous != uuid {
+			logger.Warn("Application was recreated in Coolify, following its new UUID",
+				"old_uuid", previous,
+				"new_uuid", uuid,
+			)
+		}
+		if previous != uuid {
+			old := app
+			old.UUID = previous
+			w.update(func(s *state.State) {
+				if ok {
+					delete(s.Apps, appKey(old))
+				}
+				s.ResolvedUUIDs[key] = uuid
+			})
+		}
+
+		app.UUID = uuid
+		resolved = append(resolved, app)
+	}
+
+	return resolved
+}
+
+// resolvedUUID returns the UUID last resolved for an app configured by name
+func (w *Watcher) resolvedUUID(key string) (string, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	uuid, ok := w.state.ResolvedUUIDs[key]
+	return uuid, ok
+}
+
+// listForNameLookup lists an instance's applications, resolving their
+// locations if any app configured by name on that instance is scoped by
+// project or environment
+func (w *Watcher) listForNameLookup(ctx context.Context, instance string, apps []types.AppConfig) ([]types.CoolifyApplication, error) {
+	client, ok := w.coolifyClients[instance]
+	if !ok {
+		return nil, fmt.Errorf("no Coolify client for instance '%s'", instance)
+	}
+
+	candidates, err := client.ListApplications(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, app := range apps {
+		if app.Instance == instance && app.UUID == "" && (app.Project != "" || app.Environment != "") {
+			if err := client.ResolveLocations(ctx, candidates); err != nil {
+				return nil, err
+			}
+			w.rememberServers(instance, candidates)
+			break
+		}
+	}
+
+	return candidates, nil
+}
+
+// nameKey identifies an app configured by name for the UUID cache
+func nameKey(app types.AppConfig) string {
+	return strings.Join([]string{app.Instance, app.Project, app.Environment, app.Name}, "/")
+}
+
+// resolveLocations fills in project, environment and server names of
+// discovered apps using the client of each app's instance
+func (w *Watcher) resolveLocations(ctx context.Context, apps []types.CoolifyApplication) error {
+	byInstance := make(map[string][]int)
+	for i, app := range apps {
+		byInstance[app.Instance] = append(byInstance[app.Instance], i)
+	}
+
+	for name, indexes := range byInstance {
+		client, ok := w.coolifyClients[name]
+		if !ok {
+			return fmt.Errorf("no Coolify client for instance '%s'", name)
+		}
+
+		group := make([]types.CoolifyApplication, len(indexes))
+		for j, i := range indexes {
+			group[j] = apps[i]
+		}
+		if err := client.ResolveLocations(ctx, group); err != nil {
+			return fmt.Errorf("resolving locations on instance %s: %w", name, err)
+		}
+		w.rememberServers(name, group)
+		for j, i := range indexes {
+			apps[i] = group[j]
+		}
+	}
+	return nil
+}
+
+// rememberServers keeps the servers of an instance's apps whose locations were
+// resolved, so the update limits of the cycle needn't look them up again
+func (w *Watcher) rememberServers(instance string, apps []types.CoolifyApplication) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.servers == nil {
+		w.servers = make(map[string]string)
+	}
+	for _, app := range apps {
+		w.servers[instance+"/"+app.UUID] = app.Server
+	}
+}
+
+// clientFor returns the Coolify client of the app's instance
+func (w *Watcher) clientFor(app types.AppConfig) (*coolify.Client, error) {
+	client, ok := w.coolifyClients[app.Instance]
+	if !ok {
+		return nil, fmt.Errorf("no Coolify client for instance '%s'", app.Instance)
+	}
+	return client, nil
+}
+
+// defaultsFor returns the effective defaults of an app: those of its instance
+// with the app's own overrides applied
+func (w *Watcher) defaultsFor(app types.AppConfig) *types.DefaultsConfig {
+	return appDefaults(w.config, app)
+}
+
+// appDefaults returns the effective defaults of an app under cfg
+func appDefaults(cfg *types.Config, app types.AppConfig) *types.DefaultsConfig {
+	defaults := &cfg.Defaults
+	if instance := config.GetInstance(cfg, app.Instance); instance != nil && instance.Defaults != nil {
+		defaults = instance.Defaults
+	}
+	merged := config.AppDefaults(&app, defaults)
+	return &merged
+}
+
+// appKey identifies an app across instances, since UUIDs are only unique per Coolify server
+func appKey(app types.AppConfig) string {
+	return app.Instance + "/" + app.UUID
+}
+
+// plannedUpdate is an update found in the check phase of a cycle, applied in
+// its update phase
+type plannedUpdate struct {
+	app     types.AppConfig
+	client  *coolify.Client
+	status  *types.AppStatus
+	fromTag string
+	toTag   string // For rebuilds, the newer base images
+	digest  string
+	reason  string
+	rebuild bool                 // Rebuild for newer base images instead of changing the tag
+	actor   string               // Who requested the update (default: the scheduler)
+	limits  *updateLimits        // Limits of the cycle applying the update, nil for jobs
+	approve *types.PendingUpdate // Approval the update goes ahead with, nil if the policy allows it
+}
+
+// planUpdate checks a single application and returns the update to apply,
+// or nil if there is none
+func (w *Watcher) planUpdate(ctx context.Context, app types.AppConfig) (*plannedUpdate, error) {
+	logger := w.logger.With("instance", app.Instance, "app", app.Name, "uuid", app.UUID)
+	defaults := w.defaultsFor(app)
+
+	coolifyClient, err := w.clientFor(app)
+	if err != nil {
+		return nil, err
+	}
+
+	if app.WatchBaseImages {
+		return w.checkBaseImages(ctx, coolifyClient, app, defaults, logger)
+	}
+
+	currentTag, err := w.currentTag(ctx, coolifyClient, app)
+	if err != nil {
+		return nil, err
+	}
+	
+	logger = logger.With("current_tag", currentTag, "image", app.Image)
+	
+	key := appKey(app)
+	appState := w.appState(key)
+
+	// Check cooldown
+	if inCooldown(appState, defaults) {
+		logger.Debug("App in cooldown period, skipping", "last_update", appState.LastUpdate)
+		w.addHistory(app, types.HistoryEntry{Event: types.EventSkip, FromTag: currentTag, Reason: "cooldown after last update"})
+		return nil, nil
+	}
+
+	// Get latest tag from registry
+	latest, err := w.latest(ctx, app.Image, defaults.ExcludePatterns)
+	if err != nil {
+		return nil, fmt.Errorf("getting latest tag for %s: %w", app.Image, err)
+	}
+	latestTag := latest.Name
+
+	logger = logger.With("latest_tag", latestTag)
+
+	// Update status tracking
+	status := &types.AppStatus{
+		Name:       app.Name,
+		UUID:       app.UUID,
+		Instance:   app.Instance,
+		Image:      app.Image,
+		CurrentTag: currentTag,
+		LatestTag:  latestTag,
+		Policy:     string(config.GetUpdatePolicy(&app, defaults)),
+		LastCheck:  time.Now(),
+		LastUpdate: appState.LastUpdate,
+	}
+
+	// Check if update is needed and allowed
+	policy := config.GetUpdatePolicy(&app, defaults)
+	updateAllowed, reason := semver.IsUpdateAllowed(currentTag, latestTag, policy, app.Pin)
+	status.UpdateNeeded = updateAllowed
+
+	logger.Info("Version check completed",
+		"update_needed", updateAllowed,
+		"policy", policy,
+		"reason", reason,
+	)
+
+	if !updateAllowed {
+		// An approved update goes ahead although the policy holds it back
+		if approved := w.approvedUpdate(app, currentTag); approved != nil {
+			target := latest
+			if approved.ToTag != latestTag {
+				if target, err = w.findTag(ctx, app, approved.ToTag); err != nil {
+					return nil, err
+				}
+			}
+
+			// Only the image the approver saw is applied, a tag pushed again needs a new approval
+			if approved.Digest != "" && target.Digest != "" && target.Digest != approved.Digest {
+				reopened := w.reopenApproval(app, *approved, target.Digest)
+				status.PendingID = reopened.ID
+				reason = fmt.Sprintf("image of %s changed since its approval, awaiting approval", approved.ToTag)
+				w.setStatus(key, status)
+				w.addHistory(app, types.HistoryEntry{Event: types.EventSkip, FromTag: currentTag, ToTag: approved.ToTag, Policy: status.Policy, Reason: reason})
+				return nil, nil
+			}
+
+			logger.Info("Applying approved update", "to_tag", approved.ToTag, "approved_by", approved.DecidedBy)
+			status.UpdateNeeded = true
+			w.setStatus(key, status)
+			return &plannedUpdate{
+				app:     app,
+				client:  coolifyClient,
+				status:  status,
+				fromTag: currentTag,
+				toTag:   approved.ToTag,
+				digest:  target.Digest,
+				reason:  fmt.Sprintf("approved by %s", approved.DecidedBy),
+				approve: approved,
+			}, nil
+		}
+
+		if semver.NeedsApproval(currentTag, latestTag, policy, app.Pin) {
+			pending := w.requestApproval(app, currentTag, latestTag, latest.Digest, reason)
+			switch pending.Status {
+			case types.ApprovalPending:
+				status.PendingID = pending.ID
+				reason = fmt.Sprintf("%s, awaiting approval", reason)
+			case types.ApprovalRejected:
+				reason = fmt.Sprintf("rejected by %s", pending.DecidedBy)
+			}
+		}
+
+		w.setStatus(key, status)
+		logger.Info("Update not allowed or not needed", "reason", reason)
+		event := types.EventCheck
+		if latestTag != currentTag {
+			event = types.EventSkip
+		}
+		w.addHistory(app, types.HistoryEntry{Event: event, FromTag: currentTag, ToTag: latestTag, Policy: status.Policy, Reason: reason})
+		return nil, nil
+	}
+	w.setStatus(key, status)
+
+	return &plannedUpdate{
+		app:     app,
+		client:  coolifyClient,
+		status:  status,
+		fromTag: currentTag,
+		toTag:   latestTag,
+		digest:  latest.Digest,
+		reason:  reason,
+	}, nil
+}
+
+// applyPlan applies a planned update unless it is held back by a maintenance
+// window, pause, freeze, running deployment or the limits of the cycle, or
+// patrol runs dry, and reports whether Coolify was changed
+func (w *Watcher) applyPlan(ctx context.Context, plan *plannedUpdate) (bool, error) {
+	app := plan.app
+	key := appKey(app)
+	status := plan.status
+	logger := w.logger.With("instance", app.Instance, "app", app.Name, "uuid", app.UUID)
+	entry := types.HistoryEntry{FromTag: plan.fromTag, ToTag: plan.toTag, Policy: status.Policy, Actor: plan.actor}
+
+	// Cycles can be long, make sure no other instance took over meanwhile
+	if !w.holdLock(ctx) {
+		return false, ErrNotLeader
+	}
+
+	// Outside its maintenance windows the update stays pending, it is applied
+	// by the first cycle inside one
+	windows, err := window.ParseAll(config.GetMaintenanceWindows(&app, w.defaultsFor(app)))
+	if err != nil {
+		return false, err
+	}
+	if now := time.Now(); !windows.Open(now) {
+		next := windows.NextStart(now)
+		logger.Info("Outside maintenance window, update pending",
+			"to_tag", plan.toTag,
+			"next_window", next,
+		)
+		status.Deferred = "outside maintenance window"
+		status.NextWindow = &next
+		entry.Event, entry.Reason = types.EventSkip, status.Deferred
+		w.addHistory(app, entry)
+		w.setStatus(key, status)
+		return false, nil
+	}
+
+	// Paused or frozen, patrol keeps checking but changes nothing
+	if reason, err := w.holdReason(app, time.Now()); err != nil {
+		return false, err
+	} else if reason != "" {
+		logger.Info("Updates on hold, update pending", "to_tag", plan.toTag, "reason", reason)
+		status.Deferred = reason
+		entry.Event, entry.Reason = types.EventSkip, reason
+		w.addHistory(app, entry)
+		w.setStatus(key, status)
+		return false, nil
+	}
+
+	// Don't race a deployment that was started manually or by a git push.
+	// Coolify only keeps a deployment queue for applications.
+	if app.Type != types.ResourceService {
+		deploying, err := plan.client.HasActiveDeployment(ctx, app.UUID)
+		if err != nil {
+			return false, fmt.Errorf("checking deployment queue: %w", err)
+		}
+		if deploying {
+			logger.Info("Deployment in progress, deferring update to next cycle",
+				"to_tag", plan.toTag,
+			)
+			status.Deferred = "deployment in progress"
+			entry.Event, entry.Reason = types.EventSkip, status.Deferred
+			w.addHistory(app, entry)
+			w.setStatus(key, status)
+			return false, nil
+		}
+	}
 
-	"github.com/chrisdietr/coolify-patrol/internal/config"
-	"github.com/chrisdietr/coolify-patrol/internal/coolify"
-	"github.com/chrisdietr/coolify-patrol/internal/registry"
-	"github.com/chrisdietr/coolify-patrol/internal/semver"
-	"github.com/chrisdietr/coolify-patrol/pkg/types"
-)
+	// Only updates that could go ahead count against the limits of the cycle,
+	// beyond them they are queued
+	if limits := plan.limits; limits != nil {
+		reason, err := limits.holdReason(ctx, plan)
+		if err != nil {
+			return false, err
+		}
+		if reason != "" {
+			limits.hold(plan, reason)
+			return false, nil
+		}
+		if err := limits.wait(ctx, plan); err != nil {
+			return false, err
+		}
+	}
 
-// Watcher monitors applications and handles updates
-type Watcher struct {
-	config         *types.Config
-	coolifyClient  *coolify.Client
-	registryClient *registry.Client
-	logger         *slog.Logger
-	dryRun         bool
-	
-	// State tracking
-	appStatuses    map[string]*types.AppStatus
-	lastUpdates    map[string]time.Time
-	lastCheck      time.Time
-}
+	if w.dryRun {
+		if plan.rebuild {
+			logger.Info("DRY RUN: Would rebuild application for newer base images", "base_images", plan.toTag)
+		} else {
+			logger.Info("DRY RUN: Would update application",
+				"from_tag", plan.fromTag,
+				"to_tag", plan.toTag,
+			)
+		}
+		entry.Event, entry.Reason = types.EventSkip, "dry run"
+		w.addHistory(app, entry)
+		return false, nil
+	}
 
-// NewWatcher creates a new watcher instance
-func NewWatcher(cfg *types.Config, coolifyClient *coolify.Client, registryClient *registry.Client, logger *slog.Logger, dryRun bool) *Watcher {
-	return &Watcher{
-		config:         cfg,
-		coolifyClient:  coolifyClient,
-		registryClient: registryClient,
-		logger:         logger,
-		dryRun:         dryRun,
-		appStatuses:    make(map[string]*types.AppStatus),
-		lastUpdates:    make(map[string]time.Time),
+	entry.Event, entry.Reason = types.EventUpdateStarted, plan.reason
+	w.addHistory(app, entry)
+
+	// A failing pre-update hook, like a backup, aborts the update
+	if err := w.runHooks(ctx, plan, types.HookPreUpdate, nil); err != nil {
+		return false, w.failUpdate(ctx, plan, &updateError{fromTag: plan.fromTag, toTag: plan.toTag, err: err})
 	}
-}
 
-// Start begins the watcher loop
-func (w *Watcher) Start(ctx context.Context, runOnce bool) error {
-	scheduleInfo := w.config.Defaults.Interval
-	if w.config.Defaults.Schedule != "" {
-		scheduleInfo = fmt.Sprintf("cron: %s", w.config.Defaults.Schedule)
+	// So does a failed backup of the app's database, which stays visible in the status
+	if app.BackupBeforeUpdate {
+		file, err := w.backupDatabase(ctx, plan.client, app)
+		if err != nil {
+			if ctx.Err() == nil {
+				status.Deferred = fmt.Sprintf("backup failed: %v", err)
+				w.setStatus(key, status)
+			}
+			return false, w.failUpdate(ctx, plan, &updateError{fromTag: plan.fromTag, toTag: plan.toTag, err: fmt.Errorf("backup before update: %w", err)})
+		}
+		logger.Info("Database backed up", "database", app.BackupDatabase, "file", file)
+		entry.Event, entry.Reason = types.EventBackup, fmt.Sprintf("database %s backed up to %s", app.BackupDatabase, file)
+		w.addHistory(app, entry)
+	}
+
+	// Hooks and backups can take long, the lock must still be ours
+	if !w.holdLock(ctx) {
+		return false, ErrNotLeader
+	}
+
+	if plan.rebuild {
+		logger.Info("Rebuilding application for newer base images", "base_images", plan.toTag)
+		if err := plan.client.DeployApplication(ctx, app.UUID, true); err != nil {
+			return false, w.failUpdate(ctx, plan, &updateError{toTag: plan.toTag, err: fmt.Errorf("triggering rebuild: %w", err)})
+		}
+
+		entry.Event, entry.Reason = types.EventRebuild, "rebuild triggered"
+		w.addHistory(app, entry)
+		w.breakerSuccess(key)
+
+		updateTime := time.Now()
+		status.LastUpdate = &updateTime
+		saved := *status
+		w.update(func(s *state.State) {
+			appState := s.App(key)
+			appState.RebuiltFor = plan.toTag
+			appState.LastUpdate = &updateTime
+			appState.Status = &saved
+		})
+		w.runHooks(ctx, plan, types.HookPostUpdate, nil)
+		return true, nil
 	}
-	
-	w.logger.Info("Starting coolify-patrol watcher",
-		"dry_run", w.dryRun,
-		"schedule", scheduleInfo,
-		"run_once", runOnce,
-	)
 
-	// Initial check
-	if err := w.checkApplications(ctx); err != nil {
-		w.logger.Error("Initial check failed", "error", err)
-		if runOnce {
-			return err
+	// The tag may have been pushed again during hooks, backup and delay. Only
+	// the image the approver saw is applied, otherwise the new digest is recorded.
+	if plan.digest != "" {
+		target, err := w.findTag(ctx, app, plan.toTag)
+		if err != nil {
+			return false, w.failUpdate(ctx, plan, &updateError{fromTag: plan.fromTag, toTag: plan.toTag, err: fmt.Errorf("checking image digest: %w", err)})
+		}
+		if target.Digest != "" && target.Digest != plan.digest {
+			if plan.approve != nil && plan.approve.Digest != "" {
+				reopened := w.reopenApproval(app, *plan.approve, target.Digest)
+				status.PendingID = reopened.ID
+				status.Deferred = fmt.Sprintf("image of %s changed since its approval, awaiting approval", plan.toTag)
+				entry.Event, entry.Reason = types.EventSkip, status.Deferred
+				w.addHistory(app, entry)
+				w.setStatus(key, status)
+				return false, nil
+			}
+			logger.Info("Image changed since the check", "to_tag", plan.toTag, "digest", target.Digest)
+			plan.digest = target.Digest
 		}
 	}
 
-	if runOnce {
-		return nil
+	logger = logger.With("current_tag", plan.fromTag, "image", app.Image, "latest_tag", plan.toTag)
+	if err := w.performUpdate(ctx, plan.client, app, plan.toTag, logger); err != nil {
+		return false, w.failUpdate(ctx, plan, &updateError{fromTag: plan.fromTag, toTag: plan.toTag, err: err})
 	}
 
-	// Use cron scheduler if schedule is configured, otherwise use interval ticker
-	if w.config.Defaults.Schedule != "" {
-		return w.startWithCron(ctx)
-	} else {
-		return w.startWithInterval(ctx)
+	// Record successful update right away, a self-update may end this process
+	entry.Event, entry.Reason = types.EventUpdateSucceeded, ""
+	if isRollback(w.appState(key).PreviousTag, plan.fromTag, plan.toTag) {
+		logger.Info("Application rolled back", "from_tag", plan.fromTag, "to_tag", plan.toTag)
+		entry.Event = types.EventRollback
 	}
+	w.addHistory(app, entry)
+	w.breakerSuccess(key)
+	status.LastUpdate = w.recordUpdate(key, plan.fromTag, plan.toTag, plan.digest)
+	w.update(func(s *state.State) {
+		s.SettlePending(app.Instance, app.UUID)
+	})
+	status.PendingID = ""
+	status.CurrentTag, status.UpdateNeeded = plan.toTag, false
+	w.setStatus(key, status)
+
+	// Failed post-update hooks, like a migration check, are recorded but can't undo the update
+	w.runHooks(ctx, plan, types.HookPostUpdate, nil)
+	return true, nil
 }
 
-// startWithCron starts the watcher using cron scheduling
-func (w *Watcher) startWithCron(ctx context.Context) error {
-	c := cron.New(cron.WithParser(cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)))
-	
-	_, err := c.AddFunc(w.config.Defaults.Schedule, func() {
-		if err := w.checkApplications(ctx); err != nil {
-			w.logger.Error("Check cycle failed", "error", err)
+// ErrPendingNotFound is returned when deciding on an unknown pending update
+var ErrPendingNotFound = errors.New("pending update not found")
+
+// requestApproval records an update held back by the policy and returns its
+// record, which may already be decided
+func (w *Watcher) requestApproval(app types.AppConfig, fromTag, toTag, digest, reason string) types.PendingUpdate {
+	var pending types.PendingUpdate
+	created := false
+	w.update(func(s *state.State) {
+		created = s.FindPending(state.PendingID(app.Instance, app.UUID, toTag)) == nil
+		pending = s.RequestApproval(types.PendingUpdate{
+			Instance:  app.Instance,
+			App:       app.Name,
+			UUID:      app.UUID,
+			FromTag:   fromTag,
+			ToTag:     toTag,
+			Digest:    digest,
+			Reason:    reason,
+			CreatedAt: time.Now(),
+		})
+	})
+	if created {
+		w.logger.Info("Update awaiting approval",
+			"instance", app.Instance,
+			"app", app.Name,
+			"to_tag", toTag,
+			"pending_id", pending.ID,
+		)
+	}
+	return pending
+}
+
+// reopenApproval asks again for approval of an approved update whose tag now
+// points to another image
+func (w *Watcher) reopenApproval(app types.AppConfig, approved types.PendingUpdate, digest string) types.PendingUpdate {
+	reopened := approved
+	w.update(func(s *state.State) {
+		if record, ok := s.Reopen(approved.ID, digest, time.Now()); ok {
+			reopened = record
 		}
 	})
-	if err != nil {
-		return fmt.Errorf("failed to add cron job: %w", err)
+	w.logger.Warn("Image changed since the update was approved, awaiting approval again",
+		"instance", app.Instance,
+		"app", app.Name,
+		"to_tag", approved.ToTag,
+		"approved_digest", approved.Digest,
+		"digest", digest,
+		"pending_id", reopened.ID,
+	)
+	return reopened
+}
+
+// approvedUpdate returns the approved update of an app, or nil. Approvals of
+// tags that are no longer newer than currentTag are dropped.
+func (w *Watcher) approvedUpdate(app types.AppConfig, currentTag string) *types.PendingUpdate {
+	w.mu.RLock()
+	record := w.state.ApprovedFor(app.Instance, app.UUID)
+	var approved *types.PendingUpdate
+	if record != nil {
+		copied := *record
+		approved = &copied
 	}
+	w.mu.RUnlock()
 
-	w.logger.Info("Starting cron scheduler", "schedule", w.config.Defaults.Schedule)
-	c.Start()
-	defer c.Stop()
+	if approved == nil {
+		return nil
+	}
+	if newer, _ := semver.IsUpdateAllowed(currentTag, approved.ToTag, types.AutoAll, ""); !newer {
+		w.update(func(s *state.State) {
+			s.SettlePending(app.Instance, app.UUID)
+		})
+		return nil
+	}
+	return approved
+}
 
-	// Wait for context cancellation
-	<-ctx.Done()
-	w.logger.Info("Watcher stopped")
-	return ctx.Err()
+// Pending returns the updates awaiting approval and the decisions taken,
+// newest first
+func (w *Watcher) Pending() []types.PendingUpdate {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.state.ListPending()
 }
 
-// startWithInterval starts the watcher using interval-based ticker
-func (w *Watcher) startWithInterval(ctx context.Context) error {
-	// Parse interval
-	interval, err := time.ParseDuration(w.config.Defaults.Interval)
-	if err != nil {
-		return fmt.Errorf("invalid interval: %w", err)
-	}
+// Approve lets the next cycle apply a pending update, still respecting
+// maintenance windows, freezes and pauses
+func (w *Watcher) Approve(id, actor string) (types.PendingUpdate, error) {
+	return w.decide(id, types.ApprovalApproved, types.EventApproved, actor)
+}
 
-	w.logger.Info("Starting interval scheduler", "interval", interval)
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+// Reject declines a pending update, the version isn't proposed again
+func (w *Watcher) Reject(id, actor string) (types.PendingUpdate, error) {
+	return w.decide(id, types.ApprovalRejected, types.EventRejected, actor)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			w.logger.Info("Watcher stopped")
-			return ctx.Err()
-		case <-ticker.C:
-			if err := w.checkApplications(ctx); err != nil {
-				w.logger.Error("Check cycle failed", "error", err)
-			}
-		}
+// decide records a decision on a pending update
+func (w *Watcher) decide(id string, status types.ApprovalStatus, event types.HistoryEvent, actor string) (types.PendingUpdate, error) {
+	var (
+		decided types.PendingUpdate
+		ok      bool
+	)
+	w.update(func(s *state.State) {
+		decided, ok = s.Decide(id, status, actor, time.Now())
+	})
+	if !ok {
+		return types.PendingUpdate{}, fmt.Errorf("%w: %s", ErrPendingNotFound, id)
 	}
+
+	w.logger.Info("Pending update decided",
+		"instance", decided.Instance,
+		"app", decided.App,
+		"to_tag", decided.ToTag,
+		"status", decided.Status,
+		"actor", actor,
+	)
+	app := types.AppConfig{Name: decided.App, UUID: decided.UUID, Instance: decided.Instance}
+	w.addHistory(app, types.HistoryEntry{Event: event, FromTag: decided.FromTag, ToTag: decided.ToTag, Actor: actor})
+	return decided, nil
 }
 
-// checkApplications performs one complete check cycle
-func (w *Watcher) checkApplications(ctx context.Context) error {
-	w.lastCheck = time.Now()
-	w.logger.Info("Starting check cycle")
+// holdReason explains why updates of app are held back at t, or returns an
+// empty string if they may go ahead
+func (w *Watcher) holdReason(app types.AppConfig, t time.Time) (string, error) {
+	if w.Paused() != nil {
+		return "paused", nil
+	}
+	if reason := w.breakerReason(appKey(app), t); reason != "" {
+		return reason, nil
+	}
 
-	apps, err := w.getApplicationsToCheck(ctx)
+	freezes, err := window.ParseFreezes(w.config.Freezes)
 	if err != nil {
-		return fmt.Errorf("getting applications: %w", err)
+		return "", err
 	}
+	freeze := window.ActiveFreeze(freezes, app, t)
+	if freeze == nil {
+		return "", nil
+	}
+	if freeze.Name == "" {
+		return fmt.Sprintf("change freeze until %s", freeze.End().Format(time.RFC3339)), nil
+	}
+	return fmt.Sprintf("change freeze '%s' until %s", freeze.Name, freeze.End().Format(time.RFC3339)), nil
+}
 
-	w.logger.Info("Found applications to check", "count", len(apps))
-
-	updateDelay := 30 * time.Second
-	for i, app := range apps {
-		if err := w.checkAndUpdateApp(ctx, app); err != nil {
-			w.logger.Error("Failed to check application",
-				"app", app.Name,
-				"uuid", app.UUID,
-				"error", err,
-			)
-			continue
+// Pause stops all updates until Resume is called, checks keep running. The
+// pause survives restarts. Pausing again keeps the original pause.
+func (w *Watcher) Pause(reason, actor string) types.PauseInfo {
+	var pause types.PauseInfo
+	w.update(func(s *state.State) {
+		if s.Pause == nil {
+			s.Pause = &types.PauseInfo{Since: time.Now(), Reason: reason, Actor: actor}
 		}
+		pause = *s.Pause
+	})
+	w.logger.Info("Updates paused", "reason", pause.Reason, "actor", pause.Actor)
+	return pause
+}
 
-		// Add delay between updates to avoid overwhelming Coolify
-		if i < len(apps)-1 {
-			time.Sleep(updateDelay)
-		}
+// Resume lifts a pause and reports whether updates were paused
+func (w *Watcher) Resume(actor string) bool {
+	paused := false
+	w.update(func(s *state.State) {
+		paused = s.Pause != nil
+		s.Pause = nil
+	})
+	if paused {
+		w.logger.Info("Updates resumed", "actor", actor)
 	}
+	return paused
+}
 
-	w.logger.Info("Check cycle completed", "duration", time.Since(w.lastCheck))
-	return nil
+// Paused returns the current pause, or nil if updates aren't paused
+func (w *Watcher) Paused() *types.PauseInfo {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.state.Pause == nil {
+		return nil
+	}
+	pause := *w.state.Pause
+	return &pause
 }
 
-// getApplicationsToCheck returns the list of applications to check
-func (w *Watcher) getApplicationsToCheck(ctx context.Context) ([]types.AppConfig, error) {
-	if len(w.config.Apps) > 0 {
-		// Use configured apps
-		return w.config.Apps, nil
+// latest returns the newest tag of an image, limiting parallel requests to
+// each registry
+func (w *Watcher) latest(ctx context.Context, image string, excludePatterns []string) (types.RegistryTag, error) {
+	sem := w.registrySemaphore(registry.Host(image))
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		return types.RegistryTag{}, ctx.Err()
 	}
+	defer func() { <-sem }()
 
-	// Auto-discovery mode
-	w.logger.Info("No configured apps, using auto-discovery")
-	
-	coolifyApps, err := w.coolifyClient.ListApplications(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("listing Coolify applications: %w", err)
+	return w.registryClient.GetLatest(ctx, image, excludePatterns)
+}
+
+// registrySemaphore returns the semaphore limiting requests to a registry
+func (w *Watcher) registrySemaphore(host string) chan struct{} {
+	w.semMu.Lock()
+	defer w.semMu.Unlock()
+
+	sem, ok := w.registrySems[host]
+	if !ok {
+		sem = make(chan struct{}, max(w.config.RegistryConcurrency, 1))
+		w.registrySems[host] = sem
 	}
+	return sem
+}
 
-	var apps []types.AppConfig
-	for _, coolifyApp := range coolifyApps {
-		// Skip apps with 'latest' tag
-		_, tag := coolify.ExtractImageAndTag(coolifyApp.DockerImage)
-		if tag == "latest" {
-			w.logger.Warn("Skipping app with 'latest' tag",
-				"app", coolifyApp.Name,
-				"image", coolifyApp.DockerImage,
-			)
-			continue
+// setStatus records the result of a check. The state keeps its own copy, so
+// status may be changed and set again afterwards.
+func (w *Watcher) setStatus(key string, status *types.AppStatus) {
+	saved := *status
+	saved.BaseImages = slices.Clone(status.BaseImages)
+	w.update(func(s *state.State) {
+		appState := s.App(key)
+		appState.LastCheck = saved.LastCheck
+		if saved.CurrentTag != "" {
+			appState.CurrentTag = saved.CurrentTag
 		}
+		appState.Status = &saved
+	})
+}
 
-		image, _ := coolify.ExtractImageAndTag(coolifyApp.DockerImage)
-		apps = append(apps, types.AppConfig{
-			Name:  coolifyApp.Name,
-			UUID:  coolifyApp.UUID,
-			Image: image,
-			// Policy will be inherited from defaults
-		})
-	}
+// recordUpdate remembers a successful update and returns its time
+func (w *Watcher) recordUpdate(key, fromTag, toTag, digest string) *time.Time {
+	updateTime := time.Now()
+	w.update(func(s *state.State) {
+		appState := s.App(key)
+		appState.LastUpdate = &updateTime
+		appState.PreviousTag = fromTag
+		appState.CurrentTag = toTag
+		appState.DeployedDigest = digest
+	})
+	return &updateTime
+}
 
-	w.logger.Info("Auto-discovered applications", "count", len(apps))
-	return apps, nil
+// isRollback reports whether moving an app from fromTag to toTag goes back:
+// to the tag it had before its last update, or to an older version
+func isRollback(previousTag, fromTag, toTag string) bool {
+	if previousTag != "" && toTag == previousTag {
+		return true
+	}
+	from, err := semver.ParseVersion(fromTag)
+	if err != nil {
+		return false
+	}
+	to, err := semver.ParseVersion(toTag)
+	return err == nil && to.Compare(from) < 0
 }
 
-// checkAndUpdateApp checks a single application for updates
-func (w *Watcher) checkAndUpdateApp(ctx context.Context, app types.AppConfig) error {
-	logger := w.logger.With("app", app.Name, "uuid", app.UUID)
+// inCooldown reports whether the app was updated less than the cooldown ago
+func inCooldown(appState state.AppState, defaults *types.DefaultsConfig) bool {
+	if appState.LastUpdate == nil {
+		return false
+	}
+	cooldownDuration, _ := time.ParseDuration(defaults.Cooldown)
+	return time.Since(*appState.LastUpdate) < cooldownDuration
+}
 
-	// Get current application state from Coolify
-	currentApp, err := w.coolifyClient.GetApplication(ctx, app.UUID)
+// checkBaseImages checks the FROM images of an app's Dockerfile against the
+// registry and, if enabled, plans a rebuild when a newer allowed tag exists
+func (w *Watcher) checkBaseImages(ctx context.Context, coolifyClient *coolify.Client, app types.AppConfig, defaults *types.DefaultsConfig, logger *slog.Logger) (*plannedUpdate, error) {
+	content, err := w.readDockerfile(ctx, coolifyClient, app)
 	if err != nil {
-		return fmt.Errorf("getting current application state: %w", err)
+		return nil, err
 	}
 
-	// Extract current tag
-	_, currentTag := coolify.ExtractImageAndTag(currentApp.DockerImage)
-	
-	logger = logger.With("current_tag", currentTag, "image", app.Image)
-	
-	// Check cooldown
-	if lastUpdate, exists := w.lastUpdates[app.UUID]; exists {
-		cooldownDuration, _ := time.ParseDuration(w.config.Defaults.Cooldown)
-		if time.Since(lastUpdate) < cooldownDuration {
-			logger.Debug("App in cooldown period, skipping", "last_update", lastUpdate)
-			return nil
+	policy := config.GetUpdatePolicy(&app, defaults)
+	status := &types.AppStatus{
+		Name:      app.Name,
+		UUID:      app.UUID,
+		Instance:  app.Instance,
+		Image:     app.Image,
+		Policy:    string(policy),
+		LastCheck: time.Now(),
+	}
+
+	key := appKey(app)
+	appState := w.appState(key)
+	status.LastUpdate = appState.LastUpdate
+
+	refs := dockerfile.BaseImages(content)
+	if len(refs) == 0 {
+		logger.Warn("No base images found in Dockerfile")
+	}
+
+	var newerTags, pinnedTags []string
+	for _, ref := range refs {
+		image, currentTag := coolify.ExtractImageAndTag(ref)
+		imageLogger := logger.With("base_image", image, "current_tag", currentTag)
+
+		latest, err := w.latest(ctx, image, defaults.ExcludePatterns)
+		if err != nil {
+			imageLogger.Warn("Failed to get latest tag of base image", "error", err)
+			continue
+		}
+		latestTag := latest.Name
+
+		updateAllowed, reason := semver.IsUpdateAllowed(currentTag, latestTag, policy, app.Pin)
+		// A rebuild pulls the tag of the FROM line again. An exact version
+		// stays what it is, only floating tags like "20" or "lts" move.
+		_, parseErr := semver.ParseVersion(currentTag)
+		pinned := parseErr == nil
+		status.BaseImages = append(status.BaseImages, types.BaseImageStatus{
+			Image:        image,
+			CurrentTag:   currentTag,
+			LatestTag:    latestTag,
+			UpdateNeeded: updateAllowed,
+			Pinned:       pinned,
+		})
+
+		imageLogger.Info("Base image check completed",
+			"latest_tag", latestTag,
+			"update_needed", updateAllowed,
+			"policy", policy,
+			"reason", reason,
+		)
+		if !updateAllowed {
+			continue
+		}
+		status.UpdateNeeded = true
+		if pinned {
+			pinnedTags = append(pinnedTags, coolify.BuildImageReference(image, latestTag))
+		} else {
+			newerTags = append(newerTags, coolify.BuildImageReference(image, latestTag))
 		}
 	}
 
-	// Get latest tag from registry
-	latestTag, err := w.registryClient.GetLatestTag(ctx, app.Image, w.config.Defaults.ExcludePatterns)
-	if err != nil {
-		return fmt.Errorf("getting latest tag for %s: %w", app.Image, err)
+	w.setStatus(key, status)
+
+	signature := strings.Join(newerTags, ",")
+	if !status.UpdateNeeded {
+		w.addHistory(app, types.HistoryEntry{Event: types.EventCheck, Policy: status.Policy, Reason: "base images up to date"})
+		return nil, nil
+	}
+	if len(newerTags) == 0 {
+		w.addHistory(app, types.HistoryEntry{Event: types.EventSkip, ToTag: strings.Join(pinnedTags, ","), Policy: status.Policy, Reason: "FROM lines pin exact versions, a rebuild can't update them; edit the Dockerfile"})
+		return nil, nil
+	}
+	if !app.RebuildOnBaseUpdate {
+		w.addHistory(app, types.HistoryEntry{Event: types.EventSkip, ToTag: signature, Policy: status.Policy, Reason: "rebuild_on_base_update is disabled"})
+		return nil, nil
 	}
 
-	logger = logger.With("latest_tag", latestTag)
+	// The Dockerfile keeps its old FROM tags after a rebuild, so remember which
+	// newer tags were already handled instead of rebuilding every cycle
+	if appState.RebuiltFor == signature {
+		logger.Debug("Rebuild already triggered for these base images", "base_images", newerTags)
+		return nil, nil
+	}
 
-	// Update status tracking
-	status := &types.AppStatus{
-		Name:       app.Name,
-		UUID:       app.UUID,
-		Image:      app.Image,
-		CurrentTag: currentTag,
-		LatestTag:  latestTag,
-		Policy:     string(config.GetUpdatePolicy(&app, &w.config.Defaults)),
-		LastCheck:  time.Now(),
+	if inCooldown(appState, defaults) {
+		logger.Debug("App in cooldown period, skipping rebuild", "last_update", appState.LastUpdate)
+		w.addHistory(app, types.HistoryEntry{Event: types.EventSkip, ToTag: signature, Policy: status.Policy, Reason: "cooldown after last update"})
+		return nil, nil
 	}
 
-	// Check if update is needed and allowed
-	policy := config.GetUpdatePolicy(&app, &w.config.Defaults)
-	updateAllowed, reason := semver.IsUpdateAllowed(currentTag, latestTag, policy, app.Pin)
-	status.UpdateNeeded = updateAllowed
+	return &plannedUpdate{
+		app:     app,
+		client:  coolifyClient,
+		status:  status,
+		toTag:   signature,
+		reason:  "rebuild for newer base images",
+		rebuild: true,
+	}, nil
+}
 
-	logger.Info("Version check completed",
-		"update_needed", updateAllowed,
-		"policy", policy,
-		"reason", reason,
-	)
+// readDockerfile returns the Dockerfile of an app in base image mode, read
+// from the configured local path or from Coolify
+func (w *Watcher) readDockerfile(ctx context.Context, coolifyClient *coolify.Client, app types.AppConfig) (string, error) {
+	if app.Dockerfile != "" {
+		path := app.Dockerfile
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, "Dockerfile")
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("reading Dockerfile: %w", err)
+		}
+		return string(content), nil
+	}
 
-	if !updateAllowed {
-		logger.Info("Update not allowed or not needed", "reason", reason)
-		w.appStatuses[app.UUID] = status
-		return nil
+	currentApp, err := coolifyClient.GetApplication(ctx, app.UUID)
+	if err != nil {
+		return "", fmt.Errorf("getting current application state: %w", err)
+	}
+	if currentApp.Dockerfile == "" {
+		return "", fmt.Errorf("Coolify has no Dockerfile stored for this app (build pack %q); set dockerfile to a local checkout", currentApp.BuildPack)
 	}
+	return currentApp.Dockerfile, nil
+}
 
-	// Perform update
-	if w.dryRun {
-		logger.Info("DRY RUN: Would update application",
-			"from_tag", currentTag,
-			"to_tag", latestTag,
-		)
-	} else {
-		if err := w.performUpdate(ctx, app, latestTag, logger); err != nil {
-			return fmt.Errorf("performing update: %w", err)
+// currentTag returns the deployed tag, read from the app's image or from its
+// tag environment variable
+func (w *Watcher) currentTag(ctx context.Context, coolifyClient *coolify.Client, app types.AppConfig) (string, error) {
+	if app.TagEnv != "" {
+		tag, err := coolifyClient.GetEnv(ctx, app.Type, app.UUID, app.TagEnv)
+		if err != nil {
+			return "", fmt.Errorf("reading tag from %s: %w", app.TagEnv, err)
 		}
-		
-		// Record successful update
-		w.lastUpdates[app.UUID] = time.Now()
-		updateTime := time.Now()
-		status.LastUpdate = &updateTime
+		return tag, nil
 	}
 
-	w.appStatuses[app.UUID] = status
-	return nil
+	// Get current application state from Coolify
+	currentApp, err := coolifyClient.GetApplication(ctx, app.UUID)
+	if err != nil {
+		return "", fmt.Errorf("getting current application state: %w", err)
+	}
+
+	_, tag := coolify.ExtractImageAndTag(currentApp.DockerImage)
+	return tag, nil
 }
 
 // performUpdate actually updates an application
-func (w *Watcher) performUpdate(ctx context.Context, app types.AppConfig, newTag string, logger *slog.Logger) error {
+func (w *Watcher) performUpdate(ctx context.Context, coolifyClient *coolify.Client, app types.AppConfig, newTag string, logger *slog.Logger) error {
 	newImage := coolify.BuildImageReference(app.Image, newTag)
 	
 	logger.Info("Updating application", "new_image", newImage)
 
-	// Update the application
-	if err := w.coolifyClient.UpdateApplication(ctx, app.UUID, newImage); err != nil {
-		return fmt.Errorf("updating application config: %w", err)
+	// Update the tag where it lives: the image reference or an environment variable
+	if app.TagEnv != "" {
+		if err := coolifyClient.UpdateEnv(ctx, app.Type, app.UUID, app.TagEnv, newTag); err != nil {
+			return fmt.Errorf("updating %s: %w", app.TagEnv, err)
+		}
+	} else {
+		if err := coolifyClient.UpdateApplication(ctx, app.UUID, newImage); err != nil {
+			return fmt.Errorf("updating application config: %w", err)
+		}
 	}
 
 	// Trigger restart/redeploy
-	if err := w.coolifyClient.RestartApplication(ctx, app.UUID); err != nil {
-		return fmt.Errorf("restarting application: %w", err)
+	if app.Type == types.ResourceService {
+		if err := coolifyClient.RestartService(ctx, app.UUID); err != nil {
+			return fmt.Errorf("restarting service: %w", err)
+		}
+	} else {
+		if err := coolifyClient.RestartApplication(ctx, app.UUID); err != nil {
+			return fmt.Errorf("restarting application: %w", err)
+		}
 	}
 
 	logger.Info("Application updated successfully",
@@ -302,23 +1786,106 @@ func (w *Watcher) performUpdate(ctx context.Context, app types.AppConfig, newTag
 	return nil
 }
 
-// GetStatus returns current status of all watched applications
+// GetStatus returns a consistent snapshot of the status of all watched
+// applications. It is safe to call while a check cycle is running.
 func (w *Watcher) GetStatus() *types.StatusResponse {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	now := time.Now()
 	var apps []types.AppStatus
-	for _, status := range w.appStatuses {
-		apps = append(apps, *status)
+	for key, appState := range w.state.Apps {
+		if appState.Status == nil {
+			continue
+		}
+		status := *appState.Status
+		status.BaseImages = slices.Clone(status.BaseImages)
+		_, status.InProgress = w.inProgress[key]
+		status.NextCheck = appState.NextCheck
+		status.Breaker = appState.Breaker.Status(now)
+		status.Failing, status.Failures, status.LastError = appState.Failures > 0, appState.Failures, appState.LastError
+		apps = append(apps, status)
+	}
+
+	// Apps checked for the first time have no status yet
+	for key, app := range w.inProgress {
+		appState, ok := w.state.Apps[key]
+		if ok && appState.Status != nil {
+			continue
+		}
+		status := types.AppStatus{
+			Name:       app.Name,
+			UUID:       app.UUID,
+			Instance:   app.Instance,
+			Image:      app.Image,
+			InProgress: true,
+		}
+		if ok {
+			status.NextCheck = appState.NextCheck
+		}
+		apps = append(apps, status)
 	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Instance != apps[j].Instance {
+			return apps[i].Instance < apps[j].Instance
+		}
+		return apps[i].Name < apps[j].Name
+	})
 
-	return &types.StatusResponse{
-		Status:    "running",
-		LastCheck: w.lastCheck,
-		Apps:      apps,
+	response := &types.StatusResponse{
+		Status:      "running",
+		LastCheck:   w.state.LastCheck,
+		ReloadError: w.reloadErr,
+		Breaker:     w.state.Breaker.Status(now),
+		Queue:       slices.Clone(w.state.Queue),
+		Apps:        apps,
+	}
+	if !w.reloadedAt.IsZero() {
+		reloadedAt := w.reloadedAt
+		response.LastReload = &reloadedAt
+	}
+	if w.state.Pause != nil {
+		pause := *w.state.Pause
+		response.Status = "paused"
+		response.Pause = &pause
 	}
+	if w.standby {
+		response.Status = "standby"
+	}
+	return response
 }
 
-// DiscoverApps returns a list of all Coolify applications for discovery
+// DiscoverApps returns a list of all Coolify applications across all instances
 func (w *Watcher) DiscoverApps(ctx context.Context) ([]types.CoolifyApplication, error) {
-	return w.coolifyClient.ListApplications(ctx)
+	return w.discoverApps(ctx, false)
+}
+
+// discoverApps lists the applications of all instances. With skipRejected,
+// an instance rejecting its API token is left out for the running cycle
+// instead of failing the discovery.
+func (w *Watcher) discoverApps(ctx context.Context, skipRejected bool) ([]types.CoolifyApplication, error) {
+	var apps []types.CoolifyApplication
+	for _, instance := range w.config.Instances {
+		client, ok := w.coolifyClients[instance.Name]
+		if !ok {
+			return nil, fmt.Errorf("no Coolify client for instance '%s'", instance.Name)
+		}
+
+		instanceApps, err := client.ListApplications(ctx)
+		if skipRejected && errors.Is(err, coolify.ErrUnauthorized) {
+			w.rejectInstance(instance.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("listing applications of instance %s: %w", instance.Name, err)
+		}
+
+		for _, app := range instanceApps {
+			app.Instance = instance.Name
+			apps = append(apps, app)
+		}
+	}
+	return apps, nil
 }
 
 // GenerateSampleConfig generates a sample configuration based on discovered apps
@@ -338,7 +1905,23 @@ func (w *Watcher) GenerateSampleConfig(ctx context.Context) (*types.Config, erro
 		},
 	}
 
+	// Only spell out instances when there is more than one
+	multiInstance := len(w.config.Instances) > 1
+	if multiInstance {
+		config.Coolify = types.CoolifyConfig{}
+		for _, instance := range w.config.Instances {
+			config.Instances = append(config.Instances, types.CoolifyInstance{
+				Name:          instance.Name,
+				CoolifyConfig: types.CoolifyConfig{URL: instance.URL, Token: instance.Token},
+			})
+		}
+	}
+
 	for _, app := range apps {
+		if coolify.IsCoolifyInternal(app) {
+			continue
+		}
+
 		// Skip latest tags
 		_, tag := coolify.ExtractImageAndTag(app.DockerImage)
 		if tag == "latest" {
@@ -351,6 +1934,9 @@ func (w *Watcher) GenerateSampleConfig(ctx context.Context) (*types.Config, erro
 			UUID:  app.UUID,
 			Image: image,
 		}
+		if multiInstance {
+			appConfig.Instance = app.Instance
+		}
 
 		// Add suggested pin for well-known images
 		if strings.Contains(image, "postgres") {
@@ -363,4 +1949,4 @@ func (w *Watcher) GenerateSampleConfig(ctx context.Context) (*types.Config, erro
 	}
 
 	return config, nil
-}
\ No newline at end of file
+}
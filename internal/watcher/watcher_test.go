@@ -0,0 +1,1274 @@
+package watcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/internal/coolify"
+	"github.com/chrisdietr/coolify-patrol/internal/registry"
+	"github.com/chrisdietr/coolify-patrol/internal/state"
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func newTestWatcher(t *testing.T) *Watcher {
+	t.Helper()
+	cfg := &types.Config{Defaults: types.DefaultsConfig{Cooldown: "1h"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewWatcher(cfg, nil, nil, state.NewMemoryStore(), logger, false)
+}
+
+func TestGetStatusInProgress(t *testing.T) {
+	w := newTestWatcher(t)
+	app := types.AppConfig{Name: "n8n", UUID: "n8n-uuid", Instance: "default", Image: "n8nio/n8n"}
+	key := appKey(app)
+
+	w.mu.Lock()
+	w.inProgress[key] = app
+	w.mu.Unlock()
+
+	status := w.GetStatus()
+	if len(status.Apps) != 1 || !status.Apps[0].InProgress || status.Apps[0].Name != "n8n" {
+		t.Fatalf("expected n8n in progress without a previous status, got %+v", status.Apps)
+	}
+
+	w.setStatus(key, &types.AppStatus{Name: "n8n", UUID: "n8n-uuid", Instance: "default", CurrentTag: "1.0.0"})
+	status = w.GetStatus()
+	if len(status.Apps) != 1 || !status.Apps[0].InProgress || status.Apps[0].CurrentTag != "1.0.0" {
+		t.Fatalf("expected previous status marked in progress, got %+v", status.Apps)
+	}
+
+	w.mu.Lock()
+	delete(w.inProgress, key)
+	w.mu.Unlock()
+
+	if status := w.GetStatus(); status.Apps[0].InProgress {
+		t.Error("expected app not to be in progress anymore")
+	}
+}
+
+func TestSetStatusCopies(t *testing.T) {
+	w := newTestWatcher(t)
+	status := &types.AppStatus{
+		Name:       "api",
+		UUID:       "api-uuid",
+		BaseImages: []types.BaseImageStatus{{Image: "node", CurrentTag: "20.1.0"}},
+	}
+	w.setStatus("default/api-uuid", status)
+
+	// Changing the caller's status must not leak into snapshots
+	status.Deferred = "deployment in progress"
+	status.BaseImages[0].CurrentTag = "20.2.0"
+
+	snapshot := w.GetStatus().Apps[0]
+	if snapshot.Deferred != "" || snapshot.BaseImages[0].CurrentTag != "20.1.0" {
+		t.Errorf("expected snapshot of the status as set, got %+v", snapshot)
+	}
+}
+
+// TestConcurrentStatus is meant to be run with -race
+func TestConcurrentStatus(t *testing.T) {
+	w := newTestWatcher(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			app := types.AppConfig{Name: fmt.Sprintf("app-%d", i%10), UUID: fmt.Sprintf("uuid-%d", i%10), Instance: "default"}
+			key := appKey(app)
+
+			w.mu.Lock()
+			w.inProgress[key] = app
+			w.mu.Unlock()
+
+			w.addHistory(app, types.HistoryEntry{Event: types.EventCheck})
+			w.setStatus(key, &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, LastCheck: time.Now()})
+			w.recordUpdate(key, "1.0.0", "1.0.1", "")
+
+			w.mu.Lock()
+			delete(w.inProgress, key)
+			w.mu.Unlock()
+		}
+	}()
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				w.GetStatus()
+				w.History(types.HistoryQuery{Limit: 10})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if apps := w.GetStatus().Apps; len(apps) != 10 {
+		t.Errorf("expected 10 apps, got %d", len(apps))
+	}
+}
+
+// blockingStore is a memory store whose saves wait until release is closed
+type blockingStore struct {
+	*state.MemoryStore
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(saved *state.State) error {
+	s.saving <- struct{}{}
+	<-s.release
+	return s.MemoryStore.Save(saved)
+}
+
+func TestSlowSaveDoesNotBlockReaders(t *testing.T) {
+	store := &blockingStore{MemoryStore: state.NewMemoryStore(), saving: make(chan struct{}, 10), release: make(chan struct{})}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := NewWatcher(&types.Config{}, nil, nil, store, logger, false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.setStatus(fmt.Sprintf("default/app-%d", i), &types.AppStatus{Name: fmt.Sprintf("app-%d", i)})
+		}()
+	}
+	<-store.saving
+
+	// The state is readable and changeable while it is written
+	changed := make(chan struct{})
+	go func() {
+		for len(w.GetStatus().Apps) < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		close(changed)
+	}()
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("state blocked by a running save")
+	}
+
+	close(store.release)
+	wg.Wait()
+
+	// Changes made during the first write are saved together by one more
+	if saves := 1 + len(store.saving); saves > 2 {
+		t.Errorf("expected at most 2 saves, got %d", saves)
+	}
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved.Apps) != 3 {
+		t.Errorf("expected all 3 apps saved, got %d", len(saved.Apps))
+	}
+}
+
+func TestCheckAllRecordsFailures(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.CheckConcurrency = 3
+
+	// Without a client for their instance every check fails, which must not abort the cycle
+	var apps []types.AppConfig
+	for i := 0; i < 10; i++ {
+		apps = append(apps, types.AppConfig{Name: fmt.Sprintf("app-%d", i), UUID: fmt.Sprintf("uuid-%d", i), Instance: "missing"})
+	}
+
+	plans, err := w.checkAll(context.Background(), apps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plans) != 0 {
+		t.Errorf("expected no planned updates, got %d", len(plans))
+	}
+
+	entries := w.History(types.HistoryQuery{})
+	if len(entries) != len(apps) {
+		t.Fatalf("expected %d history entries, got %d", len(apps), len(entries))
+	}
+	for _, entry := range entries {
+		if entry.Event != types.EventCheckFailed {
+			t.Errorf("expected event '%s', got '%s'", types.EventCheckFailed, entry.Event)
+		}
+	}
+	if failures := w.appState("missing/uuid-0").Failures; failures != 1 {
+		t.Errorf("expected 1 failure, got %d", failures)
+	}
+}
+
+func TestCheckApplicationsSkipsOverlappingCycle(t *testing.T) {
+	w := newTestWatcher(t)
+
+	w.cycleMu.Lock()
+	defer w.cycleMu.Unlock()
+
+	if err := w.checkApplications(context.Background(), false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !w.GetStatus().LastCheck.IsZero() {
+		t.Error("expected overlapping cycle not to start")
+	}
+}
+
+func TestRegistrySemaphore(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.RegistryConcurrency = 2
+
+	docker := w.registrySemaphore("docker.io")
+	if cap(docker) != 2 {
+		t.Errorf("expected capacity 2, got %d", cap(docker))
+	}
+	if w.registrySemaphore("docker.io") != docker {
+		t.Error("expected the same semaphore for the same registry")
+	}
+	if w.registrySemaphore("ghcr.io") == docker {
+		t.Error("expected separate semaphores per registry")
+	}
+}
+
+func TestApplyPlanOutsideMaintenanceWindow(t *testing.T) {
+	w := newTestWatcher(t)
+
+	// A one hour window starting two hours from now is closed right now
+	start := time.Now().UTC().Add(2 * time.Hour)
+	app := types.AppConfig{
+		Name:     "n8n",
+		UUID:     "n8n-uuid",
+		Instance: "default",
+		Image:    "n8nio/n8n",
+		MaintenanceWindows: []types.MaintenanceWindow{{
+			Start:    start.Format("15:04"),
+			End:      start.Add(time.Hour).Format("15:04"),
+			Timezone: "UTC",
+		}},
+	}
+	plan := &plannedUpdate{
+		app:     app,
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+		fromTag: "1.0.0",
+		toTag:   "1.0.1",
+	}
+
+	applied, err := w.applyPlan(context.Background(), plan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if applied {
+		t.Fatal("expected update to stay pending outside the window")
+	}
+
+	status := w.GetStatus().Apps[0]
+	if status.Deferred != "outside maintenance window" {
+		t.Errorf("expected deferred reason, got '%s'", status.Deferred)
+	}
+	if status.NextWindow == nil || status.NextWindow.Sub(start) > time.Minute || start.Sub(*status.NextWindow) > time.Minute {
+		t.Errorf("expected next window around %v, got %v", start, status.NextWindow)
+	}
+
+	entries := w.History(types.HistoryQuery{})
+	if len(entries) != 1 || entries[0].Event != types.EventSkip || entries[0].ToTag != "1.0.1" {
+		t.Errorf("expected a skip entry for 1.0.1, got %+v", entries)
+	}
+}
+
+func TestApplyPlanOnHold(t *testing.T) {
+	app := types.AppConfig{Name: "shop", UUID: "shop-uuid", Instance: "default", Image: "shop/shop", Labels: []string{"production"}}
+	today := time.Now().UTC().Format("2006-01-02")
+
+	tests := []struct {
+		name     string
+		paused   bool
+		freezes  []types.FreezePeriod
+		expected string
+	}{
+		{name: "paused", paused: true, expected: "paused"},
+		{
+			name:     "frozen by label",
+			freezes:  []types.FreezePeriod{{Name: "black-friday", Start: today, End: today, Timezone: "UTC", Labels: []string{"production"}}},
+			expected: "change freeze 'black-friday' until ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWatcher(t)
+			w.config.Freezes = tt.freezes
+			if tt.paused {
+				w.Pause("incident", "test")
+			}
+
+			// Without a Coolify client, getting past the hold would fail the update
+			plan := &plannedUpdate{
+				app:     app,
+				status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+				fromTag: "1.0.0",
+				toTag:   "1.0.1",
+			}
+			applied, err := w.applyPlan(context.Background(), plan)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if applied {
+				t.Fatal("expected update to be held back")
+			}
+
+			status := w.GetStatus().Apps[0]
+			if !strings.HasPrefix(status.Deferred, tt.expected) {
+				t.Errorf("expected deferred reason '%s...', got '%s'", tt.expected, status.Deferred)
+			}
+			entries := w.History(types.HistoryQuery{})
+			if len(entries) != 1 || entries[0].Event != types.EventSkip {
+				t.Errorf("expected a skip entry, got %+v", entries)
+			}
+		})
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	store := state.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := NewWatcher(&types.Config{}, nil, nil, store, logger, false)
+
+	first := w.Pause("black friday", "alice")
+	if again := w.Pause("other", "bob"); again != first {
+		t.Errorf("expected pausing again to keep the first pause, got %+v", again)
+	}
+
+	status := w.GetStatus()
+	if status.Status != "paused" || status.Pause == nil || status.Pause.Reason != "black friday" {
+		t.Errorf("expected paused status, got %+v", status)
+	}
+
+	// The pause survives a restart
+	restarted := NewWatcher(&types.Config{}, nil, nil, store, logger, false)
+	if pause := restarted.Paused(); pause == nil || pause.Actor != "alice" {
+		t.Fatalf("expected persisted pause, got %+v", pause)
+	}
+
+	if !restarted.Resume("alice") {
+		t.Error("expected resume to report a pause")
+	}
+	if restarted.Resume("alice") {
+		t.Error("expected second resume to report no pause")
+	}
+	if status := restarted.GetStatus(); status.Status != "running" || status.Pause != nil {
+		t.Errorf("expected running status, got %+v", status)
+	}
+}
+
+func TestPerAppSchedule(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Defaults.Interval = "15m"
+
+	hourly := types.AppConfig{Name: "postgres", UUID: "postgres-uuid", Instance: "default", Interval: "1h"}
+	nightly := types.AppConfig{Name: "n8n", UUID: "n8n-uuid", Instance: "default", Schedule: "0 3 * * *"}
+	regular := types.AppConfig{Name: "umami", UUID: "umami-uuid", Instance: "default"}
+	apps := []types.AppConfig{hourly, nightly, regular}
+
+	cycleStart := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	if due := w.dueApps(apps, cycleStart); len(due) != 3 {
+		t.Fatalf("expected apps never checked to be due, got %d", len(due))
+	}
+
+	w.scheduleChecks(apps, cycleStart)
+	w.known = map[string]types.AppConfig{appKey(hourly): hourly, appKey(nightly): nightly, appKey(regular): regular}
+
+	expected := map[string]time.Time{
+		appKey(hourly):  cycleStart.Add(time.Hour),
+		appKey(nightly): time.Date(2026, 3, 2, 3, 0, 0, 0, time.Local),
+		appKey(regular): cycleStart.Add(15 * time.Minute),
+	}
+	for key, next := range expected {
+		if got := w.appState(key).NextCheck; !got.Equal(next) {
+			t.Errorf("%s: expected next check %v, got %v", key, next, got)
+		}
+	}
+
+	due := w.dueApps(apps, cycleStart.Add(20*time.Minute))
+	if len(due) != 1 || due[0].Name != "umami" {
+		t.Errorf("expected only umami to be due after 20 minutes, got %+v", due)
+	}
+
+	// The default interval wakes the scheduler as well, here at the same time as umami
+	if next := w.nextCycle(cycleStart.Add(time.Minute)); !next.Equal(cycleStart.Add(15 * time.Minute)) {
+		t.Errorf("expected next cycle at %v, got %v", cycleStart.Add(15*time.Minute), next)
+	}
+}
+
+func TestDefaultsForAppOverrides(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Defaults = types.DefaultsConfig{
+		Schedule:        "0 * * * *",
+		Interval:        "15m",
+		Cooldown:        "1h",
+		UpdateDelay:     "30s",
+		ExcludePatterns: []string{"-rc"},
+	}
+
+	app := types.AppConfig{Name: "api", Interval: "5m", Cooldown: "10m", UpdateDelay: "1m", ExcludePatterns: []string{"-beta"}}
+	defaults := w.defaultsFor(app)
+	if defaults.Schedule != "" || defaults.Interval != "5m" {
+		t.Errorf("expected the app interval to replace the default schedule, got schedule '%s' interval '%s'", defaults.Schedule, defaults.Interval)
+	}
+	if defaults.Cooldown != "10m" || defaults.ExcludePatterns[0] != "-beta" {
+		t.Errorf("expected app overrides, got %+v", defaults)
+	}
+	if delay := w.updateDelay(app); delay != time.Minute {
+		t.Errorf("expected update delay 1m, got %v", delay)
+	}
+
+	lastUpdate := time.Now().Add(-30 * time.Minute)
+	if inCooldown(state.AppState{LastUpdate: &lastUpdate}, defaults) {
+		t.Error("expected app cooldown of 10m to be over")
+	}
+	if !inCooldown(state.AppState{LastUpdate: &lastUpdate}, w.defaultsFor(types.AppConfig{Name: "other"})) {
+		t.Error("expected default cooldown of 1h to still apply to other apps")
+	}
+}
+
+func TestApprovalWorkflow(t *testing.T) {
+	w := newTestWatcher(t)
+	app := types.AppConfig{Name: "postgres", UUID: "pg-uuid", Instance: "default", Image: "postgres"}
+
+	pending := w.requestApproval(app, "16.4.0", "17.0.0", "sha256:aaa", "auto-minor policy only allows minor and patch updates")
+	if pending.Status != types.ApprovalPending {
+		t.Fatalf("expected pending update, got %+v", pending)
+	}
+	if w.approvedUpdate(app, "16.4.0") != nil {
+		t.Fatal("expected no approved update before approval")
+	}
+
+	if _, err := w.Approve("unknown", "alice"); !errors.Is(err, ErrPendingNotFound) {
+		t.Errorf("expected ErrPendingNotFound, got %v", err)
+	}
+	if _, err := w.Approve(pending.ID, "alice"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	approved := w.approvedUpdate(app, "16.4.0")
+	if approved == nil || approved.ToTag != "17.0.0" || approved.Digest != "sha256:aaa" || approved.DecidedBy != "alice" {
+		t.Fatalf("expected 17.0.0 approved by alice, got %+v", approved)
+	}
+
+	entries := w.History(types.HistoryQuery{})
+	if len(entries) != 1 || entries[0].Event != types.EventApproved || entries[0].Actor != "alice" {
+		t.Errorf("expected an approved entry by alice, got %+v", entries)
+	}
+
+	// Updated by hand in the meantime, the approval is dropped
+	if w.approvedUpdate(app, "17.0.0") != nil {
+		t.Error("expected approval of the deployed tag to be dropped")
+	}
+	if len(w.Pending()) != 0 {
+		t.Errorf("expected no pending updates left, got %+v", w.Pending())
+	}
+}
+
+func TestApprovalReopenedForChangedImage(t *testing.T) {
+	w := newTestWatcher(t)
+	app := types.AppConfig{Name: "postgres", UUID: "pg-uuid", Instance: "default", Image: "postgres"}
+
+	pending := w.requestApproval(app, "16.4.0", "17.0.0", "sha256:aaa", "notify-only policy - update available but not applied")
+	if _, err := w.Approve(pending.ID, "alice"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 17.0.0 was pushed again after the approval
+	reopened := w.reopenApproval(app, *w.approvedUpdate(app, "16.4.0"), "sha256:bbb")
+	if reopened.Status != types.ApprovalPending || reopened.Digest != "sha256:bbb" || reopened.DecidedBy != "" {
+		t.Errorf("expected the update to await approval of the new image, got %+v", reopened)
+	}
+	if w.approvedUpdate(app, "16.4.0") != nil {
+		t.Error("expected no approved update left")
+	}
+}
+
+func TestImageChangedBeforeUpdate(t *testing.T) {
+	instance := newFakeCoolify(t, coolify.ApplicationResponse{UUID: "pg-uuid", Name: "postgres", DockerImage: "postgres:16.4.0"})
+	app := types.AppConfig{Name: "postgres", UUID: "pg-uuid", Instance: "default", Image: "postgres"}
+	w := newCycleWatcher(t, &types.Config{Apps: []types.AppConfig{app}}, map[string]*fakeCoolify{"default": instance},
+		map[string][]string{"postgres": {"16.4.0", "17.0.0"}})
+
+	// 17.0.0 was pushed again between the check and the update, the registry now lists sha256:17.0.0
+	pending := w.requestApproval(app, "16.4.0", "17.0.0", "sha256:aaa", "auto-minor policy only allows minor and patch updates")
+	approved, err := w.Approve(pending.ID, "alice")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan := &plannedUpdate{
+		app:     app,
+		client:  instance.client(),
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance},
+		fromTag: "16.4.0",
+		toTag:   "17.0.0",
+		digest:  "sha256:aaa",
+		approve: &approved,
+	}
+	if applied, err := w.applyUpdate(context.Background(), plan); err != nil || applied {
+		t.Fatalf("expected the approved update to be held back, got %v, %v", applied, err)
+	}
+	if changes := instance.recorded(); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+	reopened := w.Pending()
+	if len(reopened) != 1 || reopened[0].Status != types.ApprovalPending || reopened[0].Digest != "sha256:17.0.0" {
+		t.Errorf("expected the new image to await approval, got %+v", reopened)
+	}
+
+	// Without an approval the update goes ahead and records the image deployed
+	plan.approve = nil
+	if applied, err := w.applyUpdate(context.Background(), plan); err != nil || !applied {
+		t.Fatalf("expected update to be applied, got %v, %v", applied, err)
+	}
+	if digest := w.appState(appKey(app)).DeployedDigest; digest != "sha256:17.0.0" {
+		t.Errorf("expected digest 'sha256:17.0.0' recorded, got '%s'", digest)
+	}
+}
+
+func TestRejectedUpdateIsRemembered(t *testing.T) {
+	w := newTestWatcher(t)
+	app := types.AppConfig{Name: "n8n", UUID: "n8n-uuid", Instance: "default", Image: "n8nio/n8n"}
+
+	pending := w.requestApproval(app, "1.0.0", "2.0.0", "", "notify-only policy - update available but not applied")
+	if _, err := w.Reject(pending.ID, "bob"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	again := w.requestApproval(app, "1.0.0", "2.0.0", "", "notify-only policy - update available but not applied")
+	if again.Status != types.ApprovalRejected || again.DecidedBy != "bob" {
+		t.Errorf("expected the rejection to be remembered, got %+v", again)
+	}
+	if w.approvedUpdate(app, "1.0.0") != nil {
+		t.Error("expected rejected update not to be applied")
+	}
+}
+
+func TestReload(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Defaults.Interval = "15m"
+	w.config.Apps = []types.AppConfig{
+		{Name: "n8n", Instance: "default", Image: "n8nio/n8n"},
+		{Name: "umami", Instance: "default", Image: "ghcr.io/umami-software/umami"},
+	}
+	n8n := types.AppConfig{Name: "n8n", UUID: "n8n-uuid", Instance: "default", Image: "n8nio/n8n"}
+	umami := types.AppConfig{Name: "umami", UUID: "umami-uuid", Instance: "default", Image: "ghcr.io/umami-software/umami"}
+	w.known = map[string]types.AppConfig{appKey(n8n): n8n, appKey(umami): umami}
+
+	lastCheck := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	w.scheduleChecks([]types.AppConfig{n8n, umami}, lastCheck)
+	w.update(func(s *state.State) {
+		s.App(appKey(n8n)).LastCheck = lastCheck
+		s.App(appKey(umami)).LastCheck = lastCheck
+	})
+
+	// A state directory can't be moved while running
+	invalid := &types.Config{StateDir: "/data", Defaults: w.config.Defaults}
+	if _, err := w.Reload(invalid, nil); err == nil {
+		t.Fatal("expected error changing state_dir")
+	}
+	if status := w.GetStatus(); !strings.Contains(status.ReloadError, "state_dir") || status.LastReload != nil {
+		t.Errorf("expected reload error in status, got %+v", status)
+	}
+
+	reloaded := &types.Config{
+		Defaults: types.DefaultsConfig{Interval: "5m", Cooldown: "1h"},
+		Apps: []types.AppConfig{
+			{Name: "n8n", Instance: "default", Image: "n8nio/n8n", Interval: "1h"},
+		},
+	}
+	changes, err := w.Reload(reloaded, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) == 0 {
+		t.Error("expected changes to be reported")
+	}
+	if w.config != reloaded {
+		t.Error("expected the new config to be in use")
+	}
+
+	status := w.GetStatus()
+	if status.ReloadError != "" || status.LastReload == nil {
+		t.Errorf("expected successful reload in status, got %+v", status)
+	}
+	if _, ok := w.known[appKey(umami)]; ok {
+		t.Error("expected removed app to be forgotten")
+	}
+	if next := w.appState(appKey(n8n)).NextCheck; !next.Equal(lastCheck.Add(time.Hour)) {
+		t.Errorf("expected n8n rescheduled to its new interval, got %v", next)
+	}
+
+	// The state is kept
+	if w.appState(appKey(umami)).LastCheck.IsZero() {
+		t.Error("expected state to survive the reload")
+	}
+}
+
+// fakeLock is a leader lock held whenever held is set
+type fakeLock struct {
+	mu       sync.Mutex
+	held     bool
+	released bool
+}
+
+func (l *fakeLock) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.held, nil
+}
+
+func (l *fakeLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held, l.released = false, true
+	return nil
+}
+
+func (l *fakeLock) set(held bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.held = held
+}
+
+func TestLeaderLock(t *testing.T) {
+	store := state.NewMemoryStore()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := NewWatcher(&types.Config{}, nil, nil, store, logger, false)
+	lock := &fakeLock{}
+	w.UseLock(lock, time.Second)
+
+	// Without the lock, a single run refuses to check anything
+	if err := w.Start(context.Background(), true); !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected ErrNotLeader, got %v", err)
+	}
+	if status := w.GetStatus(); status.Status != "standby" {
+		t.Errorf("expected standby status, got '%s'", status.Status)
+	}
+
+	// The standby follows the state saved by the leader
+	leader := NewWatcher(&types.Config{}, nil, nil, store, logger, false)
+	leader.Pause("incident", "alice")
+	if w.holdLock(context.Background()) {
+		t.Fatal("expected lock not to be held")
+	}
+	if pause := w.Paused(); pause == nil || pause.Actor != "alice" {
+		t.Errorf("expected the leader's pause, got %+v", pause)
+	}
+
+	lock.set(true)
+	if !w.holdLock(context.Background()) || w.Standby() {
+		t.Fatal("expected lock to be taken")
+	}
+
+	// Losing the lock fails the queued jobs, a standby refuses new ones
+	queued, err := w.Enqueue(types.Job{Kind: types.JobCheck})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock.set(false)
+	w.holdLock(context.Background())
+	if job, _ := w.Job(queued.ID); job.Status != types.JobFailed || job.Error != ErrNotLeader.Error() || job.FinishedAt == nil {
+		t.Errorf("expected job to fail without the lock, got %+v", job)
+	}
+	if _, err := w.Enqueue(types.Job{Kind: types.JobCheck}); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected ErrNotLeader, got %v", err)
+	}
+	lock.set(true)
+	w.holdLock(context.Background())
+
+	// Losing the lock in the middle of a cycle aborts the update
+	lock.set(false)
+	app := types.AppConfig{Name: "shop", UUID: "shop-uuid", Instance: "default", Image: "shop/shop"}
+	plan := &plannedUpdate{app: app, status: &types.AppStatus{Name: app.Name}, fromTag: "1.0.0", toTag: "1.0.1"}
+	if _, err := w.applyUpdate(context.Background(), plan); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected ErrNotLeader, got %v", err)
+	}
+	if appState := w.appState(appKey(app)); appState.Failures != 0 || appState.Status != nil {
+		t.Errorf("expected no state recorded without the lock, got %+v", appState)
+	}
+	if !w.Standby() {
+		t.Error("expected standby after losing the lock")
+	}
+
+	// Shutting down hands the lock over right away
+	lock.set(true)
+	w.holdLock(context.Background())
+	w.releaseLock()
+	if !lock.released {
+		t.Error("expected lock to be released")
+	}
+}
+
+func TestLockLostDuringSlowHook(t *testing.T) {
+	server, restarted := fakeServers(t, nil)
+	w := newTestWatcher(t)
+	lock := &fakeLock{held: true}
+	w.UseLock(lock, 20*time.Millisecond)
+	w.config.Hooks.PreUpdate = []types.Hook{{Name: "backup", Command: "sleep 5"}}
+
+	app := types.AppConfig{Name: "app-a", UUID: "a", Instance: "default", Type: types.ResourceService, TagEnv: "VERSION"}
+	plan := &plannedUpdate{
+		app:     app,
+		client:  coolify.NewClient(server.URL, "token"),
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+		fromTag: "1.0",
+		toTag:   "1.1",
+	}
+
+	// Another instance takes over while the hook runs, long before it ends
+	time.AfterFunc(100*time.Millisecond, func() { lock.set(false) })
+	start := time.Now()
+	err := w.whileLeader(context.Background(), func(ctx context.Context) error {
+		_, err := w.applyAll(ctx, []types.AppConfig{app}, []*plannedUpdate{plan})
+		return err
+	})
+	if !errors.Is(err, ErrNotLeader) {
+		t.Fatalf("expected ErrNotLeader, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the hook to be stopped, took %v", elapsed)
+	}
+	if got := restarted(); len(got) != 0 {
+		t.Errorf("expected no update without the lock, got restarts %v", got)
+	}
+	if appState := w.appState(appKey(app)); appState.Failures != 0 {
+		t.Errorf("expected no failure recorded without the lock, got %+v", appState)
+	}
+	if !w.Standby() {
+		t.Error("expected standby after losing the lock")
+	}
+}
+
+func TestPreUpdateHookAbortsUpdate(t *testing.T) {
+	w := newTestWatcher(t)
+	w.config.Hooks.PreUpdate = []types.Hook{{Name: "backup", Command: `echo "backing up $PATROL_APP"; exit 3`}}
+
+	// A service skips the deployment check, without a Coolify client the update itself would fail
+	app := types.AppConfig{Name: "postgres", UUID: "pg-uuid", Instance: "default", Type: types.ResourceService, TagEnv: "PG_VERSION"}
+	app.Hooks.OnFailure = []types.Hook{{Name: "alert", Command: `echo "$PATROL_ERROR"`}}
+	plan := &plannedUpdate{
+		app:     app,
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+		fromTag: "16.3",
+		toTag:   "16.4",
+	}
+
+	applied, err := w.applyUpdate(context.Background(), plan)
+	if err != nil || applied {
+		t.Fatalf("expected update to be aborted without ending the cycle, got %v, %v", applied, err)
+	}
+
+	// Newest first
+	entries := w.History(types.HistoryQuery{})
+	events := make([]types.HistoryEvent, len(entries))
+	for i, entry := range entries {
+		events[i] = entry.Event
+	}
+	expected := []types.HistoryEvent{types.EventUpdateFailed, types.EventHook, types.EventHookFailed, types.EventUpdateStarted}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	if entries[2].Hook != "pre_update: backup" || entries[2].Output != "backing up postgres" {
+		t.Errorf("expected pre-update hook output, got %+v", entries[2])
+	}
+	if entries[1].Hook != "on_failure: alert" || !strings.Contains(entries[1].Output, "pre_update hook 'backup' failed") {
+		t.Errorf("expected failure hook to get the error, got %+v", entries[1])
+	}
+	if !strings.Contains(entries[0].Reason, "exit status 3") {
+		t.Errorf("expected update failure caused by the hook, got '%s'", entries[0].Reason)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	w := newTestWatcher(t)
+	threshold, globalThreshold := 2, 0
+	w.config.Breaker = types.BreakerConfig{Threshold: &threshold, GlobalThreshold: &globalThreshold, Window: "1h", Timeout: "6h"}
+	w.config.Hooks.OnBreakerOpen = []types.Hook{{Name: "alert", Command: `echo "$PATROL_ERROR"`}}
+
+	// Every update fails in its pre-update hook
+	app := types.AppConfig{Name: "postgres", UUID: "pg-uuid", Instance: "default", Type: types.ResourceService, TagEnv: "PG_VERSION"}
+	app.Hooks.PreUpdate = []types.Hook{{Name: "check", Command: "exit 1"}}
+	update := func() {
+		t.Helper()
+		plan := &plannedUpdate{
+			app:     app,
+			status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance, UpdateNeeded: true},
+			fromTag: "16.3",
+			toTag:   "16.4",
+		}
+		if _, err := w.applyUpdate(context.Background(), plan); err != nil {
+			t.Fatalf("unexpected error ending the cycle: %v", err)
+		}
+	}
+
+	update()
+	if breaker := w.GetStatus().Breaker; breaker != nil {
+		t.Errorf("expected no global breaker while disabled, got %+v", breaker)
+	}
+	update()
+
+	// Newest first: the failure itself is recorded after the breaker opened
+	entries := w.History(types.HistoryQuery{Limit: 3})
+	if entries[0].Event != types.EventUpdateFailed || entries[1].Hook != "on_breaker_open: alert" || entries[2].Event != types.EventBreakerOpened {
+		t.Fatalf("expected breaker to open and alert, got %+v", entries)
+	}
+	if !strings.Contains(entries[1].Output, "circuit breaker opened after 2 failed updates") {
+		t.Errorf("expected alert to describe the breaker, got '%s'", entries[1].Output)
+	}
+
+	// Further updates are held back, without counting as failures
+	update()
+	status := w.GetStatus().Apps[0]
+	if !strings.Contains(status.Deferred, "circuit breaker open until") {
+		t.Errorf("expected update to be deferred by the breaker, got '%s'", status.Deferred)
+	}
+	if status.Breaker == nil || !status.Breaker.Open || status.Breaker.Failures != 2 {
+		t.Errorf("expected open breaker in the status, got %+v", status.Breaker)
+	}
+
+	if err := w.ResetBreaker("redis", "alice"); !errors.Is(err, ErrBreakerNotFound) {
+		t.Errorf("expected ErrBreakerNotFound for an app without breaker, got %v", err)
+	}
+	if err := w.ResetBreaker("postgres", "alice"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if breaker := w.GetStatus().Apps[0].Breaker; breaker != nil {
+		t.Errorf("expected breaker to be reset, got %+v", breaker)
+	}
+	if entry := w.History(types.HistoryQuery{Limit: 1})[0]; entry.Event != types.EventBreakerReset || entry.Actor != "alice" {
+		t.Errorf("expected reset in the history, got %+v", entry)
+	}
+}
+
+func TestBaseImageRebuild(t *testing.T) {
+	instance := newFakeCoolify(t,
+		coolify.ApplicationResponse{UUID: "api-uuid", Name: "api", BuildPack: "dockerfile", Dockerfile: "FROM node:20\nCOPY . .\n"},
+		coolify.ApplicationResponse{UUID: "worker-uuid", Name: "worker", BuildPack: "dockerfile", Dockerfile: "FROM python:3.12.1 AS build\nFROM build\n"},
+	)
+	cfg := &types.Config{
+		Defaults: types.DefaultsConfig{Policy: types.AutoAll},
+		Apps: []types.AppConfig{
+			{Name: "api", UUID: "api-uuid", Instance: "default", WatchBaseImages: true, RebuildOnBaseUpdate: true},
+			{Name: "worker", UUID: "worker-uuid", Instance: "default", WatchBaseImages: true, RebuildOnBaseUpdate: true},
+		},
+	}
+	w := newCycleWatcher(t, cfg, map[string]*fakeCoolify{"default": instance},
+		map[string][]string{"node": {"20", "20.12.0"}, "python": {"3.12.1", "3.12.2"}})
+
+	if err := w.checkApplications(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the floating tag moves with a rebuild
+	if changes := fmt.Sprint(instance.recorded()); changes != "[deploy api-uuid]" {
+		t.Errorf("expected a rebuild of api only, got %s", changes)
+	}
+	api := w.History(types.HistoryQuery{App: "api", Limit: 1})
+	if len(api) != 1 || api[0].Event != types.EventRebuild || api[0].FromTag != "" || api[0].ToTag != "node:20.12.0" {
+		t.Errorf("expected a rebuild entry for node:20.12.0, got %+v", api)
+	}
+
+	worker := w.History(types.HistoryQuery{App: "worker", Limit: 1})
+	if len(worker) != 1 || worker[0].Event != types.EventSkip || !strings.HasPrefix(worker[0].Reason, "FROM lines pin exact versions") {
+		t.Errorf("expected the pinned FROM line to be skipped, got %+v", worker)
+	}
+	status := w.appState("default/worker-uuid").Status
+	if status == nil || len(status.BaseImages) != 1 || !status.BaseImages[0].Pinned || !status.BaseImages[0].UpdateNeeded {
+		t.Errorf("expected python reported as pinned with an update, got %+v", status)
+	}
+}
+
+func TestUnresolvedNameIsPruned(t *testing.T) {
+	instance := newFakeCoolify(t, coolify.ApplicationResponse{UUID: "web-uuid", Name: "web", DockerImage: "acme/web:1.0.0"})
+	cfg := &types.Config{Apps: []types.AppConfig{{Name: "web", Instance: "default", Image: "acme/web"}}}
+	w := newCycleWatcher(t, cfg, map[string]*fakeCoolify{"default": instance}, map[string][]string{"acme/web": {"1.0.0"}})
+
+	if err := w.checkApplications(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apps := w.GetStatus().Apps; len(apps) != 1 || apps[0].UUID != "web-uuid" {
+		t.Fatalf("expected web resolved and checked, got %+v", apps)
+	}
+
+	// Renamed in Coolify, the configured name matches nothing anymore
+	instance.mu.Lock()
+	instance.apps["web-uuid"].Name = "web-old"
+	instance.mu.Unlock()
+
+	if err := w.checkApplications(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if apps := w.GetStatus().Apps; len(apps) != 0 {
+		t.Errorf("expected the unresolved app to be pruned from the status, got %+v", apps)
+	}
+	if _, ok := w.resolvedUUID(nameKey(cfg.Apps[0])); ok {
+		t.Error("expected the resolved UUID to be dropped")
+	}
+}
+
+func TestIsRollback(t *testing.T) {
+	tests := []struct {
+		name                        string
+		previousTag, fromTag, toTag string
+		expected                    bool
+	}{
+		{name: "newer version", previousTag: "1.0.0", fromTag: "1.1.0", toTag: "1.2.0", expected: false},
+		{name: "older version", fromTag: "1.2.0", toTag: "1.1.9", expected: true},
+		{name: "previous tag", previousTag: "stable", fromTag: "latest", toTag: "stable", expected: true},
+		{name: "unparsable tags", previousTag: "stable", fromTag: "latest", toTag: "edge", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRollback(tt.previousTag, tt.fromTag, tt.toTag); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestUpdateToOlderTagIsRollback(t *testing.T) {
+	server, _ := fakeServers(t, nil)
+	w := newTestWatcher(t)
+	app := types.AppConfig{Name: "app-a", UUID: "a", Instance: "default", Type: types.ResourceService, TagEnv: "VERSION"}
+	w.recordUpdate(appKey(app), "1.0.0", "1.1.0", "")
+
+	plan := &plannedUpdate{
+		app:     app,
+		client:  coolify.NewClient(server.URL, "token"),
+		status:  &types.AppStatus{Name: app.Name, UUID: app.UUID, Instance: app.Instance},
+		fromTag: "1.1.0",
+		toTag:   "1.0.0",
+		actor:   "alice",
+	}
+	if applied, err := w.applyUpdate(context.Background(), plan); err != nil || !applied {
+		t.Fatalf("expected update to be applied, got %v, %v", applied, err)
+	}
+	if entry := w.History(types.HistoryQuery{Limit: 1})[0]; entry.Event != types.EventRollback || entry.ToTag != "1.0.0" {
+		t.Errorf("expected rollback in the history, got %+v", entry)
+	}
+	if previous := w.appState(appKey(app)).PreviousTag; previous != "1.1.0" {
+		t.Errorf("expected previous tag '1.1.0', got '%s'", previous)
+	}
+}
+
+// fakeCoolify serves a Coolify instance with the given applications and their
+// environment variables, recording the changes made. With rejected set, every
+// request fails with a 401.
+type fakeCoolify struct {
+	t        *testing.T
+	server   *httptest.Server
+	mu       sync.Mutex
+	apps     map[string]*coolify.ApplicationResponse
+	envs     map[string]map[string]string // Env values by resource UUID and key
+	requests int
+	changes  []string
+	rejected bool
+}
+
+func newFakeCoolify(t *testing.T, apps ...coolify.ApplicationResponse) *fakeCoolify {
+	t.Helper()
+	f := &fakeCoolify{
+		t:    t,
+		apps: make(map[string]*coolify.ApplicationResponse),
+		envs: make(map[string]map[string]string),
+	}
+	for _, app := range apps {
+		f.apps[app.UUID] = &app
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+// client returns a client of the instance that doesn't retry
+func (f *fakeCoolify) client() *coolify.Client {
+	client := coolify.NewClient(f.server.URL, "token")
+	client.SetRetryConfig(coolify.RetryConfig{})
+	return client
+}
+
+// setEnv sets an environment variable of a resource
+func (f *fakeCoolify) setEnv(uuid, key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.envs[uuid] == nil {
+		f.envs[uuid] = make(map[string]string)
+	}
+	f.envs[uuid][key] = value
+}
+
+// recorded returns the changes made so far, like "image web-uuid acme/web:1.1.0",
+// "env services/n8n-uuid VERSION=1.1.0", "restart applications/web-uuid" or
+// "deploy web-uuid"
+func (f *fakeCoolify) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.changes...)
+}
+
+func (f *fakeCoolify) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if f.rejected {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message": "Unauthenticated."}`)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+	switch {
+	case r.URL.Path == "/api/v1/applications":
+		var response coolify.ApplicationsListResponse
+		for _, app := range f.apps {
+			response.Data = append(response.Data, *app)
+		}
+		sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].UUID < response.Data[j].UUID })
+		json.NewEncoder(w).Encode(response)
+	case r.URL.Path == "/api/v1/deploy":
+		f.changes = append(f.changes, "deploy "+r.URL.Query().Get("uuid"))
+	case r.URL.Path == "/api/v1/deployments" || r.URL.Path == "/api/v1/servers":
+		fmt.Fprint(w, `[]`)
+	case len(parts) == 3 && parts[0] == "deployments":
+		fmt.Fprint(w, `{"deployments": []}`)
+	case len(parts) == 3 && parts[2] == "restart":
+		f.changes = append(f.changes, "restart "+parts[0]+"/"+parts[1])
+	case len(parts) == 3 && parts[2] == "envs" && r.Method == http.MethodGet:
+		var envs []coolify.EnvResponse
+		for key, value := range f.envs[parts[1]] {
+			envs = append(envs, coolify.EnvResponse{Key: key, Value: value})
+		}
+		json.NewEncoder(w).Encode(envs)
+	case len(parts) == 3 && parts[2] == "envs" && r.Method == http.MethodPatch:
+		var env coolify.EnvUpdateRequest
+		json.NewDecoder(r.Body).Decode(&env)
+		if _, ok := f.envs[parts[1]][env.Key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.envs[parts[1]][env.Key] = env.Value
+		f.changes = append(f.changes, fmt.Sprintf("env %s/%s %s=%s", parts[0], parts[1], env.Key, env.Value))
+	case len(parts) == 2 && parts[0] == "applications" && f.apps[parts[1]] != nil:
+		app := f.apps[parts[1]]
+		if r.Method == http.MethodPatch {
+			var update coolify.UpdateRequest
+			json.NewDecoder(r.Body).Decode(&update)
+			app.DockerImage = update.DockerImage
+			f.changes = append(f.changes, fmt.Sprintf("image %s %s", app.UUID, app.DockerImage))
+			return
+		}
+		json.NewEncoder(w).Encode(app)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fakeRegistry returns a registry client that lists the given tags of Docker
+// Hub images, with a made-up digest for each
+func fakeRegistry(t *testing.T, tags map[string][]string) *registry.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		image := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/repositories/"), "/tags/")
+		image = strings.TrimPrefix(image, "library/")
+		names, ok := tags[image]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		type result struct {
+			Name   string              `json:"name"`
+			Images []map[string]string `json:"images"`
+		}
+		var results []result
+		for _, name := range names {
+			results = append(results, result{Name: name, Images: []map[string]string{{"digest": "sha256:" + name}}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	return registry.NewClientWithHTTPClient(&http.Client{Transport: redirectTransport{target: target}})
+}
+
+// redirectTransport sends every request to the target server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = rt.target.Scheme
+	r.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newCycleWatcher returns a watcher of the given Coolify instances, with a
+// registry serving tags
+func newCycleWatcher(t *testing.T, cfg *types.Config, instances map[string]*fakeCoolify, tags map[string][]string) *Watcher {
+	t.Helper()
+	clients := make(map[string]*coolify.Client)
+	for name, instance := range instances {
+		cfg.Instances = append(cfg.Instances, types.CoolifyInstance{Name: name})
+		clients[name] = instance.client()
+	}
+	if cfg.Defaults.Policy == "" {
+		cfg.Defaults.Policy = types.AutoMinor
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewWatcher(cfg, clients, fakeRegistry(t, tags), state.NewMemoryStore(), logger, false)
+}
+
+func TestRejectedTokenSkipsOnlyItsInstance(t *testing.T) {
+	prod := newFakeCoolify(t, coolify.ApplicationResponse{UUID: "web-uuid", Name: "web", DockerImage: "acme/web:1.0.0"})
+	staging := newFakeCoolify(t,
+		coolify.ApplicationResponse{UUID: "web-staging-uuid", Name: "web", DockerImage: "acme/web:1.0.0"},
+		coolify.ApplicationResponse{UUID: "worker-staging-uuid", Name: "worker", DockerImage: "acme/worker:1.0.0"},
+	)
+	staging.rejected = true
+
+	cfg := &types.Config{Apps: []types.AppConfig{
+		{Name: "web", UUID: "web-staging-uuid", Instance: "staging", Image: "acme/web"},
+		{Name: "worker", UUID: "worker-staging-uuid", Instance: "staging", Image: "acme/worker"},
+		{Name: "web", UUID: "web-uuid", Instance: "prod", Image: "acme/web"},
+	}}
+	w := newCycleWatcher(t, cfg, map[string]*fakeCoolify{"prod": prod, "staging": staging},
+		map[string][]string{"acme/web": {"1.0.0", "1.1.0"}, "acme/worker": {"1.0.0", "1.1.0"}})
+
+	if err := w.checkApplications(context.Background(), true); err != nil {
+		t.Fatalf("expected the cycle to go on, got %v", err)
+	}
+
+	if changes := fmt.Sprint(prod.recorded()); changes != "[image web-uuid acme/web:1.1.0 restart applications/web-uuid]" {
+		t.Errorf("expected prod to be updated, got %s", changes)
+	}
+	staging.mu.Lock()
+	requests := staging.requests
+	staging.mu.Unlock()
+	if requests != 1 {
+		t.Errorf("expected the remaining staging apps to be skipped after the rejection, got %d requests", requests)
+	}
+
+	failed := w.appState("staging/web-staging-uuid").Failures + w.appState("staging/worker-staging-uuid").Failures
+	if failed != 1 {
+		t.Errorf("expected the app that got the rejection to fail, got %d failures", failed)
+	}
+	if failures := w.appState("prod/web-uuid").Failures; failures != 0 {
+		t.Errorf("expected no failure of prod, got %d", failures)
+	}
+}
+
+func TestDiscoveryCycle(t *testing.T) {
+	apps := []coolify.ApplicationResponse{
+		{UUID: "a-patrol-uuid", Name: "patrol", DockerImage: "chrisdietr/coolify-patrol:1.0.0"},
+		{UUID: "coolify-uuid", Name: "coolify", DockerImage: "ghcr.io/coollabsio/coolify:4.0.0"},
+		{UUID: "proxy-uuid", Name: "coolify-proxy", DockerImage: "traefik:3.0.0"},
+		{UUID: "web-uuid", Name: "web", DockerImage: "acme/web:1.0.0"},
+	}
+	tags := map[string][]string{
+		"chrisdietr/coolify-patrol": {"1.0.0", "1.1.0"},
+		"traefik":                   {"3.0.0", "3.1.0"},
+		"acme/web":                  {"1.0.0", "1.1.0"},
+	}
+
+	tests := []struct {
+		name    string
+		self    types.SelfConfig
+		changes string
+	}{
+		{
+			name:    "self-update disabled",
+			changes: "[image web-uuid acme/web:1.1.0 restart applications/web-uuid]",
+		},
+		{
+			// Listed first, patrol is still updated after every other app
+			name:    "self-update enabled",
+			self:    types.SelfConfig{Update: true},
+			changes: "[image web-uuid acme/web:1.1.0 restart applications/web-uuid image a-patrol-uuid chrisdietr/coolify-patrol:1.1.0 restart applications/a-patrol-uuid]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := newFakeCoolify(t, apps...)
+			w := newCycleWatcher(t, &types.Config{Self: tt.self}, map[string]*fakeCoolify{"default": instance}, tags)
+
+			if err := w.checkApplications(context.Background(), true); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if changes := fmt.Sprint(instance.recorded()); changes != tt.changes {
+				t.Errorf("expected changes %s, got %s", tt.changes, changes)
+			}
+
+			// Coolify's own services are neither checked nor shown
+			for _, status := range w.GetStatus().Apps {
+				if status.UUID == "coolify-uuid" || status.UUID == "proxy-uuid" {
+					t.Errorf("expected Coolify internal service to be excluded, got %+v", status)
+				}
+			}
+			if entries := w.History(types.HistoryQuery{App: "coolify-proxy"}); len(entries) != 0 {
+				t.Errorf("expected no history of Coolify internal services, got %+v", entries)
+			}
+		})
+	}
+}
+
+func TestTagEnvCycle(t *testing.T) {
+	instance := newFakeCoolify(t, coolify.ApplicationResponse{UUID: "api-uuid", Name: "api", DockerImage: "acme/api:${API_VERSION}"})
+	instance.setEnv("n8n-uuid", "N8N_VERSION", "1.0.0")
+	instance.setEnv("api-uuid", "API_VERSION", "2.0.0")
+	instance.setEnv("worker-uuid", "WORKER_VERSION", "3.0.0")
+
+	cfg := &types.Config{Apps: []types.AppConfig{
+		{Name: "n8n", UUID: "n8n-uuid", Instance: "default", Image: "n8nio/n8n", Type: types.ResourceService, TagEnv: "N8N_VERSION"},
+		{Name: "api", UUID: "api-uuid", Instance: "default", Image: "acme/api", TagEnv: "API_VERSION"},
+		{Name: "worker", UUID: "worker-uuid", Instance: "default", Image: "acme/worker", Type: types.ResourceService, TagEnv: "WORKER_VERSION"},
+	}}
+	w := newCycleWatcher(t, cfg, map[string]*fakeCoolify{"default": instance}, map[string][]string{
+		"n8nio/n8n":   {"1.0.0", "1.1.0"},
+		"acme/api":    {"2.0.0", "2.0.1"},
+		"acme/worker": {"3.0.0"},
+	})
+
+	if err := w.checkApplications(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The variable is changed and the resource restarted, the image of api is left alone
+	expected := "[env services/n8n-uuid N8N_VERSION=1.1.0 restart services/n8n-uuid env applications/api-uuid API_VERSION=2.0.1 restart applications/api-uuid]"
+	if changes := fmt.Sprint(instance.recorded()); changes != expected {
+		t.Errorf("expected changes %s, got %s", expected, changes)
+	}
+
+	for _, tt := range []struct{ key, currentTag string }{
+		{"default/n8n-uuid", "1.1.0"},
+		{"default/api-uuid", "2.0.1"},
+		{"default/worker-uuid", "3.0.0"},
+	} {
+		appState := w.appState(tt.key)
+		if appState.CurrentTag != tt.currentTag || appState.Status == nil || appState.Status.CurrentTag != tt.currentTag {
+			t.Errorf("expected %s at %s, got %+v", tt.key, tt.currentTag, appState)
+		}
+	}
+	if appState := w.appState("default/worker-uuid"); appState.Status.UpdateNeeded || appState.LastUpdate != nil {
+		t.Errorf("expected worker to be up to date, got %+v", appState)
+	}
+}
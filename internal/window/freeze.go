@@ -0,0 +1,115 @@
+package window
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Freeze is a period during which no updates are applied
+type Freeze struct {
+	Name       string
+	start, end time.Time // end is exclusive
+	apps       []string
+	labels     []string
+}
+
+// ParseFreezes parses the configured freeze periods
+func ParseFreezes(configs []types.FreezePeriod) ([]*Freeze, error) {
+	var freezes []*Freeze
+	for i, config := range configs {
+		f, err := ParseFreeze(config)
+		if err != nil {
+			return nil, fmt.Errorf("freeze #%d: %w", i+1, err)
+		}
+		freezes = append(freezes, f)
+	}
+	return freezes, nil
+}
+
+// ParseFreeze parses a configured freeze period. Start and end are dates or
+// RFC 3339 times; an end date includes the whole day.
+func ParseFreeze(config types.FreezePeriod) (*Freeze, error) {
+	loc := time.Local
+	if config.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone '%s': %w", config.Timezone, err)
+		}
+	}
+
+	start, _, err := parseMoment(config.Start, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start: %w", err)
+	}
+	end, isDate, err := parseMoment(config.End, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end: %w", err)
+	}
+	if isDate {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end must be after start")
+	}
+
+	return &Freeze{
+		Name:   config.Name,
+		start:  start,
+		end:    end,
+		apps:   config.Apps,
+		labels: config.Labels,
+	}, nil
+}
+
+// Covers reports whether the freeze applies to app at t
+func (f *Freeze) Covers(app types.AppConfig, t time.Time) bool {
+	if t.Before(f.start) || !t.Before(f.end) {
+		return false
+	}
+	if len(f.apps) == 0 && len(f.labels) == 0 {
+		return true
+	}
+	if slices.Contains(f.apps, app.Name) || (app.UUID != "" && slices.Contains(f.apps, app.UUID)) {
+		return true
+	}
+	for _, label := range app.Labels {
+		if slices.Contains(f.labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// End returns the moment the freeze is lifted
+func (f *Freeze) End() time.Time {
+	return f.end
+}
+
+// ActiveFreeze returns the first freeze covering app at t, or nil
+func ActiveFreeze(freezes []*Freeze, app types.AppConfig, t time.Time) *Freeze {
+	for _, f := range freezes {
+		if f.Covers(app, t) {
+			return f
+		}
+	}
+	return nil
+}
+
+// parseMoment parses a date or an RFC 3339 time and reports whether it was a date
+func parseMoment(value string, loc *time.Location) (time.Time, bool, error) {
+	if value == "" {
+		return time.Time{}, false, fmt.Errorf("value is required")
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("'%s' is neither a date (2006-01-02) nor an RFC 3339 time", value)
+	}
+	return t, false, nil
+}
@@ -0,0 +1,97 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestParseFreeze(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    types.FreezePeriod
+		expectErr bool
+	}{
+		{name: "dates", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30"}},
+		{name: "single day", config: types.FreezePeriod{Start: "2026-11-27", End: "2026-11-27"}},
+		{name: "times", config: types.FreezePeriod{Start: "2026-11-20T18:00:00+01:00", End: "2026-11-21T06:00:00+01:00"}},
+		{name: "missing start", config: types.FreezePeriod{End: "2026-11-30"}, expectErr: true},
+		{name: "invalid end", config: types.FreezePeriod{Start: "2026-11-20", End: "end of november"}, expectErr: true},
+		{name: "end before start", config: types.FreezePeriod{Start: "2026-11-30", End: "2026-11-20"}, expectErr: true},
+		{name: "invalid timezone", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "Nowhere"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFreeze(tt.config)
+			if tt.expectErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFreezeCovers(t *testing.T) {
+	shop := types.AppConfig{Name: "shop", UUID: "shop-uuid", Labels: []string{"production"}}
+	blog := types.AppConfig{Name: "blog", UUID: "blog-uuid"}
+	during := time.Date(2026, 11, 30, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		config   types.FreezePeriod
+		app      types.AppConfig
+		at       time.Time
+		expected bool
+	}{
+		{name: "all apps", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC"}, app: blog, at: during, expected: true},
+		{name: "end date is inclusive", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC"}, app: blog, at: during.Add(time.Minute), expected: false},
+		{name: "before start", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC"}, app: blog, at: time.Date(2026, 11, 19, 23, 0, 0, 0, time.UTC), expected: false},
+		{name: "by name", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC", Apps: []string{"shop"}}, app: shop, at: during, expected: true},
+		{name: "by uuid", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC", Apps: []string{"shop-uuid"}}, app: shop, at: during, expected: true},
+		{name: "other app", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC", Apps: []string{"shop"}}, app: blog, at: during, expected: false},
+		{name: "by label", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC", Labels: []string{"production"}}, app: shop, at: during, expected: true},
+		{name: "label not set", config: types.FreezePeriod{Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC", Labels: []string{"production"}}, app: blog, at: during, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFreeze(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := f.Covers(tt.app, tt.at); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestActiveFreeze(t *testing.T) {
+	freezes, err := ParseFreezes([]types.FreezePeriod{
+		{Name: "release", Start: "2026-06-01", End: "2026-06-07", Timezone: "UTC", Labels: []string{"production"}},
+		{Name: "black-friday", Start: "2026-11-20", End: "2026-11-30", Timezone: "UTC"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	app := types.AppConfig{Name: "blog"}
+	if f := ActiveFreeze(freezes, app, time.Date(2026, 6, 3, 0, 0, 0, 0, time.UTC)); f != nil {
+		t.Errorf("expected no freeze for an app without labels, got %s", f.Name)
+	}
+	f := ActiveFreeze(freezes, app, time.Date(2026, 11, 27, 12, 0, 0, 0, time.UTC))
+	if f == nil || f.Name != "black-friday" {
+		t.Fatalf("expected black-friday freeze, got %v", f)
+	}
+	if !f.End().Equal(time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected freeze to end at the start of 2026-12-01, got %v", f.End())
+	}
+
+	if _, err := ParseFreezes([]types.FreezePeriod{{Start: "tomorrow", End: "2026-11-30"}}); err == nil {
+		t.Error("expected error for invalid freeze")
+	}
+}
@@ -0,0 +1,214 @@
+package window
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+// Window is a recurring period during which updates may be applied
+type Window struct {
+	loc *time.Location
+
+	// Day/time range
+	days       [7]bool // Indexed by time.Weekday
+	start, end int     // Minutes after midnight, end <= start spans midnight
+
+	// Cron alternative
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// Set is a list of windows. An empty set places no restriction.
+type Set []*Window
+
+// ParseAll parses a list of configured windows
+func ParseAll(configs []types.MaintenanceWindow) (Set, error) {
+	var set Set
+	for i, config := range configs {
+		w, err := Parse(config)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window #%d: %w", i+1, err)
+		}
+		set = append(set, w)
+	}
+	return set, nil
+}
+
+// Parse parses a configured window, given either as days with start and end
+// times or as a cron schedule with a duration
+func Parse(config types.MaintenanceWindow) (*Window, error) {
+	loc := time.Local
+	if config.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone '%s': %w", config.Timezone, err)
+		}
+	}
+	w := &Window{loc: loc}
+
+	if config.Schedule != "" {
+		if config.Days != "" || config.Start != "" || config.End != "" {
+			return nil, fmt.Errorf("schedule can't be combined with days, start or end")
+		}
+		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+		schedule, err := parser.Parse(config.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", config.Schedule, err)
+		}
+		duration, err := time.ParseDuration(config.Duration)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("schedule needs a positive duration, got '%s'", config.Duration)
+		}
+		w.schedule, w.duration = schedule, duration
+		return w, nil
+	}
+
+	if config.Duration != "" {
+		return nil, fmt.Errorf("duration is only used with schedule, use start and end")
+	}
+	if config.Start == "" || config.End == "" {
+		return nil, fmt.Errorf("start and end are required")
+	}
+
+	var err error
+	if w.start, err = parseClock(config.Start); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseClock(config.End); err != nil {
+		return nil, err
+	}
+	if w.start == w.end {
+		return nil, fmt.Errorf("start and end must differ")
+	}
+	if w.days, err = parseDays(config.Days); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Contains reports whether t lies inside the window
+func (w *Window) Contains(t time.Time) bool {
+	if w.schedule != nil {
+		// The window is open if it started less than duration ago
+		start := w.schedule.Next(t.In(w.loc).Add(-w.duration))
+		return !start.After(t)
+	}
+
+	local := t.In(w.loc)
+	minute := local.Hour()*60 + local.Minute()
+	weekday := local.Weekday()
+	if w.end > w.start {
+		return w.days[weekday] && minute >= w.start && minute < w.end
+	}
+
+	// Spanning midnight: the evening part belongs to today's window, the
+	// morning part to yesterday's
+	if minute >= w.start {
+		return w.days[weekday]
+	}
+	return minute < w.end && w.days[(weekday+6)%7]
+}
+
+// NextStart returns the first start of the window after t
+func (w *Window) NextStart(t time.Time) time.Time {
+	if w.schedule != nil {
+		return w.schedule.Next(t.In(w.loc))
+	}
+
+	local := t.In(w.loc)
+	for offset := 0; offset <= 7; offset++ {
+		day := local.AddDate(0, 0, offset)
+		start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, w.loc)
+		if w.days[start.Weekday()] && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{} // Unreachable, every window has at least one day
+}
+
+// Open reports whether updates are allowed at t
+func (s Set) Open(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, w := range s {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextStart returns the earliest window start after t, or the zero time for
+// an empty set
+func (s Set) NextStart(t time.Time) time.Time {
+	var next time.Time
+	for _, w := range s {
+		if start := w.NextStart(t); next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s': use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseDays parses comma-separated days and ranges like "mon-fri,sun".
+// An empty value means every day.
+func parseDays(value string) ([7]bool, error) {
+	var days [7]bool
+	if value == "" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		from, to, isRange := strings.Cut(part, "-")
+
+		first, ok := weekdays[from]
+		if !ok {
+			return days, fmt.Errorf("invalid day '%s': use mon, tue, wed, thu, fri, sat or sun", from)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[to]; !ok {
+				return days, fmt.Errorf("invalid day '%s': use mon, tue, wed, thu, fri, sat or sun", to)
+			}
+		}
+
+		// Ranges may wrap around the week, like fri-mon
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
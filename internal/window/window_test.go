@@ -0,0 +1,168 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrisdietr/coolify-patrol/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    types.MaintenanceWindow
+		expectErr bool
+	}{
+		{name: "days and times", config: types.MaintenanceWindow{Days: "mon-fri", Start: "02:00", End: "05:00", Timezone: "Europe/Berlin"}},
+		{name: "every day", config: types.MaintenanceWindow{Start: "22:00", End: "02:00"}},
+		{name: "cron", config: types.MaintenanceWindow{Schedule: "0 2 * * 1-5", Duration: "3h"}},
+		{name: "missing end", config: types.MaintenanceWindow{Start: "02:00"}, expectErr: true},
+		{name: "invalid time", config: types.MaintenanceWindow{Start: "2am", End: "05:00"}, expectErr: true},
+		{name: "same start and end", config: types.MaintenanceWindow{Start: "02:00", End: "02:00"}, expectErr: true},
+		{name: "invalid day", config: types.MaintenanceWindow{Days: "weekdays", Start: "02:00", End: "05:00"}, expectErr: true},
+		{name: "invalid timezone", config: types.MaintenanceWindow{Start: "02:00", End: "05:00", Timezone: "Mars/Olympus"}, expectErr: true},
+		{name: "cron without duration", config: types.MaintenanceWindow{Schedule: "0 2 * * *"}, expectErr: true},
+		{name: "cron with start", config: types.MaintenanceWindow{Schedule: "0 2 * * *", Duration: "1h", Start: "02:00"}, expectErr: true},
+		{name: "duration without cron", config: types.MaintenanceWindow{Start: "02:00", End: "05:00", Duration: "1h"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.config)
+			if tt.expectErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestContainsAndNextStart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	weekdays := types.MaintenanceWindow{Days: "mon-fri", Start: "02:00", End: "05:00", Timezone: "Europe/Berlin"}
+	overnight := types.MaintenanceWindow{Days: "fri", Start: "22:00", End: "02:00", Timezone: "Europe/Berlin"}
+	cronWindow := types.MaintenanceWindow{Schedule: "0 2 * * 1-5", Duration: "3h", Timezone: "Europe/Berlin"}
+
+	// 2026-03-02 is a Monday
+	tests := []struct {
+		name         string
+		config       types.MaintenanceWindow
+		at           time.Time
+		expectOpen   bool
+		expectedNext time.Time
+	}{
+		{
+			name:         "inside weekday window",
+			config:       weekdays,
+			at:           time.Date(2026, 3, 2, 3, 30, 0, 0, berlin),
+			expectOpen:   true,
+			expectedNext: time.Date(2026, 3, 3, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:         "inside, checked in UTC",
+			config:       weekdays,
+			at:           time.Date(2026, 3, 2, 2, 30, 0, 0, time.UTC),
+			expectOpen:   true,
+			expectedNext: time.Date(2026, 3, 3, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:         "end is exclusive",
+			config:       weekdays,
+			at:           time.Date(2026, 3, 2, 5, 0, 0, 0, berlin),
+			expectedNext: time.Date(2026, 3, 3, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:         "saturday waits for monday",
+			config:       weekdays,
+			at:           time.Date(2026, 3, 7, 3, 0, 0, 0, berlin),
+			expectedNext: time.Date(2026, 3, 9, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:         "overnight evening part",
+			config:       overnight,
+			at:           time.Date(2026, 3, 6, 23, 0, 0, 0, berlin),
+			expectOpen:   true,
+			expectedNext: time.Date(2026, 3, 13, 22, 0, 0, 0, berlin),
+		},
+		{
+			name:         "overnight morning part belongs to previous day",
+			config:       overnight,
+			at:           time.Date(2026, 3, 7, 1, 0, 0, 0, berlin),
+			expectOpen:   true,
+			expectedNext: time.Date(2026, 3, 13, 22, 0, 0, 0, berlin),
+		},
+		{
+			name:         "overnight morning part of a closed day",
+			config:       overnight,
+			at:           time.Date(2026, 3, 6, 1, 0, 0, 0, berlin),
+			expectedNext: time.Date(2026, 3, 6, 22, 0, 0, 0, berlin),
+		},
+		{
+			name:         "cron inside",
+			config:       cronWindow,
+			at:           time.Date(2026, 3, 2, 4, 59, 0, 0, berlin),
+			expectOpen:   true,
+			expectedNext: time.Date(2026, 3, 3, 2, 0, 0, 0, berlin),
+		},
+		{
+			name:         "cron outside",
+			config:       cronWindow,
+			at:           time.Date(2026, 3, 2, 5, 0, 0, 0, berlin),
+			expectedNext: time.Date(2026, 3, 3, 2, 0, 0, 0, berlin),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := Parse(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if open := w.Contains(tt.at); open != tt.expectOpen {
+				t.Errorf("expected open %v, got %v", tt.expectOpen, open)
+			}
+			if next := w.NextStart(tt.at); !next.Equal(tt.expectedNext) {
+				t.Errorf("expected next start %v, got %v", tt.expectedNext, next)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	var empty Set
+	if !empty.Open(now) {
+		t.Error("expected an empty set to always be open")
+	}
+	if !empty.NextStart(now).IsZero() {
+		t.Error("expected no next start for an empty set")
+	}
+
+	set, err := ParseAll([]types.MaintenanceWindow{
+		{Start: "20:00", End: "22:00", Timezone: "UTC"},
+		{Start: "14:00", End: "15:00", Timezone: "UTC"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if set.Open(now) {
+		t.Error("expected set to be closed at noon")
+	}
+	if next := set.NextStart(now); !next.Equal(time.Date(2026, 3, 2, 14, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected earliest next start at 14:00, got %v", next)
+	}
+	if !set.Open(now.Add(2*time.Hour + 30*time.Minute)) {
+		t.Error("expected set to be open at 14:30")
+	}
+
+	if _, err := ParseAll([]types.MaintenanceWindow{{Start: "bad", End: "05:00"}}); err == nil {
+		t.Error("expected error for invalid window")
+	}
+}
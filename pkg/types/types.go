@@ -14,15 +14,44 @@ const (
 
 // Config represents the main configuration file
 type Config struct {
-	Coolify  CoolifyConfig `yaml:"coolify"`
-	Defaults DefaultsConfig `yaml:"defaults"`
-	Apps     []AppConfig   `yaml:"apps,omitempty"`
+	Coolify   CoolifyConfig     `yaml:"coolify"`
+	Instances []CoolifyInstance `yaml:"instances,omitempty"` // Replaces coolify when several servers are managed
+	Defaults  DefaultsConfig    `yaml:"defaults"`
+	Discovery DiscoveryConfig   `yaml:"discovery,omitempty"`
+	Self      SelfConfig        `yaml:"self,omitempty"`
+	Apps      []AppConfig       `yaml:"apps,omitempty"`
+	StateDir  string            `yaml:"state_dir,omitempty"` // Directory for persistent state, kept in memory if empty
+
+	Freezes   []FreezePeriod    `yaml:"freezes,omitempty"`   // Periods without any updates
+	APIToken  string            `yaml:"api_token,omitempty"` // Required by HTTP endpoints that change state, if set
+	Leader    LeaderConfig      `yaml:"leader,omitempty"`    // Lock keeping a second patrol instance from updating
+	Hooks     Hooks             `yaml:"hooks,omitempty"`     // Run around the updates of all apps, before those of the app
+	Breaker   BreakerConfig     `yaml:"breaker,omitempty"`   // Stops updates after repeated failures
+
+	CheckConcurrency    int `yaml:"check_concurrency,omitempty"`    // Apps checked in parallel (default 4)
+	RegistryConcurrency int `yaml:"registry_concurrency,omitempty"` // Parallel requests per registry (default 2)
+
+	// Limits of the updates applied, further updates are queued for the
+	// following cycles. Zero values don't limit.
+	MaxUpdatesPerCycle     int    `yaml:"max_updates_per_cycle,omitempty"`
+	MaxConcurrentPerServer int    `yaml:"max_concurrent_per_server,omitempty"` // Deployments running at once on the same Coolify server
+	BatchSize              string `yaml:"batch_size,omitempty"`                // Share of the watched apps per cycle, like "10%"
 }
 
 // CoolifyConfig holds Coolify API connection details
 type CoolifyConfig struct {
-	URL   string `yaml:"url"`
-	Token string `yaml:"token"`
+	URL          string `yaml:"url"`
+	Token        string `yaml:"token"`
+	Retries      *int   `yaml:"retries,omitempty"`       // Retries for idempotent API calls (default 3)
+	RetryBackoff string `yaml:"retry_backoff,omitempty"` // Initial backoff, doubled per retry (default 1s)
+}
+
+// CoolifyInstance is a named Coolify server with its own connection details
+// and optional overrides of the global defaults
+type CoolifyInstance struct {
+	Name          string `yaml:"name"`
+	CoolifyConfig `yaml:",inline"`
+	Defaults      *DefaultsConfig `yaml:"defaults,omitempty"`
 }
 
 // DefaultsConfig holds default values for all apps
@@ -32,21 +61,179 @@ type DefaultsConfig struct {
 	Schedule        string       `yaml:"schedule"`        // Cron schedule (takes priority over Interval)
 	Cooldown        string       `yaml:"cooldown"`
 	ExcludePatterns []string     `yaml:"exclude_patterns"`
+	UpdateDelay     string       `yaml:"update_delay,omitempty"` // Pause between two updates of a cycle (default 30s)
+	MaxBackoff      string       `yaml:"max_backoff,omitempty"`  // Longest wait between checks of a failing app (default 24h)
+
+	MaintenanceWindows []MaintenanceWindow `yaml:"maintenance_windows,omitempty"` // Updates are only applied inside one of these
+}
+
+// MaintenanceWindow is a recurring period during which updates may be applied,
+// given either as Days with Start and End or as a cron Schedule with a Duration
+type MaintenanceWindow struct {
+	Days     string `yaml:"days,omitempty"`     // Like "mon-fri" or "sat,sun", every day if empty
+	Start    string `yaml:"start,omitempty"`    // Like "02:00"
+	End      string `yaml:"end,omitempty"`      // Like "05:00", before Start to span midnight
+	Schedule string `yaml:"schedule,omitempty"` // Cron expression for the window starts
+	Duration string `yaml:"duration,omitempty"` // Length of a window opened by Schedule
+	Timezone string `yaml:"timezone,omitempty"` // IANA name like "Europe/Berlin" (default: local time)
+}
+
+// DiscoveryConfig controls which applications auto-discovery picks up
+type DiscoveryConfig struct {
+	Include      []DiscoveryFilter `yaml:"include,omitempty"`        // Apps must match one of these (all apps if empty)
+	Exclude      []DiscoveryFilter `yaml:"exclude,omitempty"`        // Apps matching any of these are skipped
+	RequireOptIn bool              `yaml:"require_opt_in,omitempty"` // Only pick up apps annotated with patrol.enable=true
+}
+
+// DiscoveryFilter matches discovered applications. Every field is a glob
+// pattern and empty fields match anything. Image is matched without the tag.
+type DiscoveryFilter struct {
+	Instance    string       `yaml:"instance,omitempty"`
+	Project     string       `yaml:"project,omitempty"`
+	Environment string       `yaml:"environment,omitempty"`
+	Server      string       `yaml:"server,omitempty"`
+	Name        string       `yaml:"name,omitempty"`
+	Image       string       `yaml:"image,omitempty"`
+	Policy      UpdatePolicy `yaml:"policy,omitempty"` // Policy for apps matched by an include filter
+	Pin         string       `yaml:"pin,omitempty"`    // Pin for apps matched by an include filter
+}
+
+// SelfConfig describes how patrol recognizes and updates its own deployment
+type SelfConfig struct {
+	UUID   string       `yaml:"uuid,omitempty"`   // Coolify UUID of patrol itself
+	Image  string       `yaml:"image,omitempty"`  // Patrol's image without tag (default: any image named coolify-patrol)
+	Update bool         `yaml:"update,omitempty"` // Update patrol itself, always as the last step of a cycle
+	Policy UpdatePolicy `yaml:"policy,omitempty"` // Policy for self-updates (default: the app's or default policy)
+}
+
+// LeaderConfig selects the lock a patrol instance must hold to change
+// anything, so that two instances, like the old and new container during a
+// redeploy of patrol, never update the same app
+type LeaderConfig struct {
+	Lock     LockKind `yaml:"lock,omitempty"`     // Default: file with a state directory, otherwise none
+	Path     string   `yaml:"path,omitempty"`     // File lock: lock file (default: <state_dir>/patrol.lock)
+	Shared   bool     `yaml:"shared,omitempty"`   // File lock: the lock file is on a volume all patrol containers mount
+	URL      string   `yaml:"url,omitempty"`      // HTTP lock: lease endpoint
+	App      string   `yaml:"app,omitempty"`      // Coolify lock: UUID of the app holding the marker (default: self.uuid)
+	Instance string   `yaml:"instance,omitempty"` // Coolify lock: instance of that app (default: the first one)
+	TTL      string   `yaml:"ttl,omitempty"`      // Lease duration of the HTTP and Coolify locks (default 30s)
+	ID       string   `yaml:"id,omitempty"`       // Name of this instance (default: hostname)
+}
+
+// LockKind is the mechanism of the leader lock
+type LockKind string
+
+const (
+	LockNone    LockKind = "none"
+	LockFile    LockKind = "file"    // flock on a file, on a volume shared by the instances
+	LockHTTP    LockKind = "http"    // Lease taken from an HTTP endpoint
+	LockCoolify LockKind = "coolify" // Lease marker in an environment variable of a Coolify app
+)
+
+// BreakerConfig sets when the circuit breakers open. An app's breaker stops
+// its updates after consecutive failed updates of the app, the global breaker
+// stops all updates after consecutive failed updates of any apps. Checks
+// continue while a breaker is open.
+type BreakerConfig struct {
+	Threshold       *int   `yaml:"threshold,omitempty"`        // Failed updates of an app that open its breaker (default 3, 0 disables)
+	GlobalThreshold *int   `yaml:"global_threshold,omitempty"` // Failed updates of any apps that open the global breaker (default 5, 0 disables)
+	Window          string `yaml:"window,omitempty"`           // Only failures within this period count (default 24h)
+	Timeout         string `yaml:"timeout,omitempty"`          // An open breaker closes after this, unless reset before (default 6h)
+}
+
+// FreezePeriod is a date range during which no updates are applied, to all
+// apps or only to those matching Apps or Labels
+type FreezePeriod struct {
+	Name     string   `yaml:"name,omitempty"`
+	Start    string   `yaml:"start"`              // Date like "2026-11-20" or RFC 3339 time
+	End      string   `yaml:"end"`                // Dates include the whole day
+	Timezone string   `yaml:"timezone,omitempty"` // For dates, IANA name (default: local time)
+	Apps     []string `yaml:"apps,omitempty"`     // App names or UUIDs
+	Labels   []string `yaml:"labels,omitempty"`
 }
 
 // AppConfig defines a single application to monitor
 type AppConfig struct {
-	Name   string       `yaml:"name"`
-	UUID   string       `yaml:"uuid"`
-	Image  string       `yaml:"image"`
-	Policy UpdatePolicy `yaml:"policy,omitempty"`
-	Pin    string       `yaml:"pin,omitempty"`
+	Name        string       `yaml:"name"`
+	UUID        string       `yaml:"uuid,omitempty"`        // Optional, the app is looked up by name in Coolify if empty
+	Project     string       `yaml:"project,omitempty"`     // Narrows the name lookup to a Coolify project
+	Environment string       `yaml:"environment,omitempty"` // Narrows the name lookup to a Coolify environment
+	Image       string       `yaml:"image"`
+	Instance    string       `yaml:"instance,omitempty"` // Name of the Coolify instance, optional with a single instance
+	Type        ResourceType `yaml:"type,omitempty"`     // Coolify resource type (default: application)
+	TagEnv      string       `yaml:"tag_env,omitempty"`  // Environment variable holding the image tag, e.g. N8N_VERSION
+	Policy      UpdatePolicy `yaml:"policy,omitempty"`
+	Pin         string       `yaml:"pin,omitempty"`
+	Labels      []string     `yaml:"labels,omitempty"` // Free-form labels, matched by freeze periods
+
+	// Overrides of the defaults for this app
+	Interval        string   `yaml:"interval,omitempty"`
+	Schedule        string   `yaml:"schedule,omitempty"` // Cron schedule (takes priority over Interval)
+	Cooldown        string   `yaml:"cooldown,omitempty"`
+	ExcludePatterns []string `yaml:"exclude_patterns,omitempty"`
+	UpdateDelay     string   `yaml:"update_delay,omitempty"`
+
+	// Base image mode for apps built from a Dockerfile
+	WatchBaseImages     bool   `yaml:"watch_base_images,omitempty"`      // Check FROM images of Coolify's stored Dockerfile
+	Dockerfile          string `yaml:"dockerfile,omitempty"`             // Local Dockerfile or checkout directory, implies watch_base_images
+	RebuildOnBaseUpdate bool   `yaml:"rebuild_on_base_update,omitempty"` // Trigger a rebuild instead of only reporting
+
+	MaintenanceWindows []MaintenanceWindow `yaml:"maintenance_windows,omitempty"` // Replaces the default windows
+	Hooks              Hooks               `yaml:"hooks,omitempty"`               // Run after the global hooks
+
+	// Backup of a Coolify database before every update, e.g. of the app's own database
+	BackupBeforeUpdate bool   `yaml:"backup_before_update,omitempty"`
+	BackupDatabase     string `yaml:"backup_database,omitempty"` // UUID of the Coolify database with a scheduled backup
+	BackupTimeout      string `yaml:"backup_timeout,omitempty"`  // How long to wait for the backup (default 30m)
+}
+
+// Hooks are commands or HTTP calls run around an update
+type Hooks struct {
+	PreUpdate  []Hook `yaml:"pre_update,omitempty"`  // Before updating, a failure aborts the update
+	PostUpdate []Hook `yaml:"post_update,omitempty"` // After a successful update
+	OnFailure  []Hook `yaml:"on_failure,omitempty"`  // After a failed or aborted update
+
+	OnBreakerOpen []Hook `yaml:"on_breaker_open,omitempty"` // After a failed update opened a circuit breaker
+}
+
+// Hook runs a local command or calls an HTTP endpoint. Commands get the
+// update in PATROL_* environment variables, Env, URL, Headers and Body are
+// templates with the same fields, like {{.App}} and {{.ToTag}}.
+type Hook struct {
+	Name    string            `yaml:"name,omitempty"`    // Shown in logs and history (default: the command or URL)
+	Command string            `yaml:"command,omitempty"` // Run with /bin/sh -c
+	Env     map[string]string `yaml:"env,omitempty"`     // Additional environment of the command
+	URL     string            `yaml:"url,omitempty"`     // Called instead of running a command
+	Method  string            `yaml:"method,omitempty"`  // HTTP method (default POST)
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Body    string            `yaml:"body,omitempty"`    // Request body (default: the update as JSON)
+	Timeout string            `yaml:"timeout,omitempty"` // Default 5m
 }
 
+// HookStage is the point of an update at which hooks run
+type HookStage string
+
+const (
+	HookPreUpdate  HookStage = "pre_update"
+	HookPostUpdate HookStage = "post_update"
+	HookOnFailure  HookStage = "on_failure"
+
+	HookBreakerOpen HookStage = "on_breaker_open"
+)
+
+// ResourceType is the kind of Coolify resource an app refers to
+type ResourceType string
+
+const (
+	ResourceApplication ResourceType = "application"
+	ResourceService     ResourceType = "service" // docker-compose based service, requires TagEnv
+)
+
 // AppStatus represents current status of an app
 type AppStatus struct {
 	Name         string     `json:"name"`
 	UUID         string     `json:"uuid"`
+	Instance     string     `json:"instance"`
 	Image        string     `json:"image"`
 	CurrentTag   string     `json:"current_tag"`
 	LatestTag    string     `json:"latest_tag"`
@@ -54,7 +241,26 @@ type AppStatus struct {
 	UpdateNeeded bool       `json:"update_needed"`
 	LastCheck    time.Time  `json:"last_check"`
 	LastUpdate   *time.Time `json:"last_update,omitempty"`
-	NextCheck    time.Time  `json:"next_check"`
+	NextCheck    time.Time  `json:"next_check"` // When the app is checked next, following its own schedule
+	PendingID    string     `json:"pending_id,omitempty"`  // Update waiting for approval, see /pending
+	Deferred     string     `json:"deferred,omitempty"`    // Why a needed update was postponed
+	InProgress   bool       `json:"in_progress,omitempty"` // A check of the app is running right now
+	NextWindow   *time.Time `json:"next_window,omitempty"` // Start of the next maintenance window for a pending update
+	Failing      bool       `json:"failing,omitempty"`     // The last check or update failed, checks back off
+	Failures     int        `json:"failures,omitempty"`    // Consecutive failed checks or updates
+	LastError    string     `json:"last_error,omitempty"`
+
+	Breaker    *BreakerStatus    `json:"breaker,omitempty"`     // Set after failed updates of the app
+	BaseImages []BaseImageStatus `json:"base_images,omitempty"` // Only for apps in base image mode
+}
+
+// BaseImageStatus represents the check result of one FROM image of a Dockerfile
+type BaseImageStatus struct {
+	Image        string `json:"image"`
+	CurrentTag   string `json:"current_tag"`
+	LatestTag    string `json:"latest_tag"`
+	UpdateNeeded bool   `json:"update_needed"`
+	Pinned       bool   `json:"pinned,omitempty"` // The FROM line names an exact version, a rebuild doesn't move it
 }
 
 // RegistryTag represents a tag from a Docker registry
@@ -69,17 +275,206 @@ type CoolifyApplication struct {
 	Name         string `json:"name"`
 	DockerImage  string `json:"docker_image"`
 	Status       string `json:"status"`
+	Instance     string `json:"instance,omitempty"` // Set by the watcher, not by the Coolify API
+	BuildPack    string `json:"build_pack,omitempty"`
+	Dockerfile   string `json:"dockerfile,omitempty"` // Dockerfile content stored in Coolify, if any
+
+	// Location within Coolify, filled in by coolify.Client.ResolveLocations
+	EnvironmentID int    `json:"environment_id,omitempty"`
+	Project       string `json:"project,omitempty"`
+	Environment   string `json:"environment,omitempty"`
+	Server        string `json:"server,omitempty"`
+
+	// Patrol settings read from Coolify tags, custom labels and description,
+	// keyed like "patrol.policy"
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// CoolifyDeployment represents an entry in Coolify's deployment queue
+type CoolifyDeployment struct {
+	DeploymentUUID  string `json:"deployment_uuid"`
+	Status          string `json:"status"`
+	ServerName      string `json:"server_name,omitempty"`      // Server the deployment runs on
+	ApplicationName string `json:"application_name,omitempty"` // Only set when listing all deployments
 }
 
 // StatusResponse is returned by /status endpoint
 type StatusResponse struct {
-	Status    string      `json:"status"`
-	LastCheck time.Time   `json:"last_check"`
-	Apps      []AppStatus `json:"apps"`
+	Status      string         `json:"status"` // "running", "paused", or "standby" while another instance holds the leader lock
+	LastCheck   time.Time      `json:"last_check"`
+	LastReload  *time.Time     `json:"last_reload,omitempty"`  // Last time the config was reloaded
+	ReloadError string         `json:"reload_error,omitempty"` // Why the last reload failed, the previous config stays in use
+	Pause       *PauseInfo     `json:"pause,omitempty"`
+	Breaker     *BreakerStatus `json:"breaker,omitempty"` // Global circuit breaker, set after failed updates
+	Queue       []QueuedUpdate `json:"queue,omitempty"`   // Updates waiting for a later cycle, in the order they are applied
+	Apps        []AppStatus    `json:"apps"`
+}
+
+// QueuedUpdate is an update held back by the update limits of a cycle. It is
+// applied by one of the following cycles, before any newer updates.
+type QueuedUpdate struct {
+	Instance string    `json:"instance"`
+	App      string    `json:"app"`
+	UUID     string    `json:"uuid"`
+	Server   string    `json:"server,omitempty"` // Only known with max_concurrent_per_server
+	FromTag  string    `json:"from_tag"`
+	ToTag    string    `json:"to_tag"`
+	QueuedAt time.Time `json:"queued_at"`
+	Reason   string    `json:"reason"` // The limit that held it back last
+}
+
+// BreakerStatus describes a circuit breaker, see BreakerConfig
+type BreakerStatus struct {
+	Open      bool       `json:"open"`     // Updates are stopped
+	Failures  int        `json:"failures"` // Consecutive failed updates within the window
+	LastError string     `json:"last_error,omitempty"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+	ClosesAt  *time.Time `json:"closes_at,omitempty"` // When updates resume without a reset
+}
+
+// PauseInfo describes why and since when updates are paused
+type PauseInfo struct {
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason,omitempty"`
+	Actor  string    `json:"actor"`
+}
+
+// ActionRequest is the optional body of POST endpoints like /pause, /resume
+// and /pending/{id}/approve
+type ActionRequest struct {
+	Reason string `json:"reason,omitempty"`
+	Actor  string `json:"actor,omitempty"` // Who takes the action (default: api)
 }
 
 // HealthResponse is returned by /health endpoint
 type HealthResponse struct {
 	OK      bool   `json:"ok"`
 	Version string `json:"version,omitempty"`
-}
\ No newline at end of file
+}
+// HistoryEvent is the kind of decision a history entry records
+type HistoryEvent string
+
+const (
+	EventCheck           HistoryEvent = "check"        // Checked, no update needed
+	EventCheckFailed     HistoryEvent = "check_failed" // The check itself failed
+	EventSkip            HistoryEvent = "skip"         // An update exists but was not applied
+	EventUpdateStarted   HistoryEvent = "update_started"
+	EventUpdateSucceeded HistoryEvent = "update_succeeded"
+	EventUpdateFailed    HistoryEvent = "update_failed"
+	EventRebuild         HistoryEvent = "rebuild" // A rebuild for newer base images was triggered, the FROM lines are unchanged
+	EventRollback        HistoryEvent = "rollback"       // An app was moved back to its previous tag or an older version
+	EventApproved        HistoryEvent = "approved"       // A pending update was approved
+	EventRejected        HistoryEvent = "rejected"       // A pending update was rejected
+	EventBackup          HistoryEvent = "backup"         // A database was backed up before an update
+	EventHook            HistoryEvent = "hook"           // A hook ran successfully
+	EventHookFailed      HistoryEvent = "hook_failed"    // A hook failed or timed out
+	EventBreakerOpened   HistoryEvent = "breaker_opened" // Repeated failures stopped updates
+	EventBreakerReset    HistoryEvent = "breaker_reset"  // An open breaker was reset through the API
+)
+
+// ActorScheduler is the actor of decisions made during scheduled check cycles
+const ActorScheduler = "scheduler"
+
+// ActorAPI is the actor of HTTP API requests that don't name one
+const ActorAPI = "api"
+
+// HistoryEntry records one decision patrol made about an app
+type HistoryEntry struct {
+	ID       int64        `json:"id"`   // Increases with every entry
+	Time     time.Time    `json:"time"` // First occurrence
+	Instance string       `json:"instance"`
+	App      string       `json:"app"`
+	UUID     string       `json:"uuid"`
+	Event    HistoryEvent `json:"event"`
+	FromTag  string       `json:"from_tag,omitempty"`
+	ToTag    string       `json:"to_tag,omitempty"`
+	Policy   string       `json:"policy,omitempty"`
+	Reason   string       `json:"reason,omitempty"`
+	Actor    string       `json:"actor"`
+	DryRun   bool         `json:"dry_run,omitempty"`
+	Hook     string       `json:"hook,omitempty"`   // Stage and name of the hook, for hook events
+	Output   string       `json:"output,omitempty"` // End of the hook's output or response
+
+	// Checks and skips identical to the app's previous entry are collapsed
+	// into it
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+	Repeats  int        `json:"repeats,omitempty"`
+}
+
+// HistoryQuery selects history entries. Zero values match everything.
+type HistoryQuery struct {
+	App   string    // App name or UUID
+	Since time.Time // Only entries at or after this time
+	Limit int       // Only the most recent entries
+}
+
+// HistoryResponse is returned by /history endpoint, newest entries first
+type HistoryResponse struct {
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// ApprovalStatus is the state of a pending update
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved" // Applied by the next cycle
+	ApprovalRejected ApprovalStatus = "rejected" // Kept so the version isn't proposed again
+)
+
+// PendingUpdate is an update the policy holds back, waiting for a human to
+// approve or reject it
+type PendingUpdate struct {
+	ID        string         `json:"id"`
+	Instance  string         `json:"instance"`
+	App       string         `json:"app"`
+	UUID      string         `json:"uuid"`
+	FromTag   string         `json:"from_tag"`
+	ToTag     string         `json:"to_tag"`
+	Digest    string         `json:"digest,omitempty"` // Image of ToTag, the one applied once approved
+	Reason    string         `json:"reason"`
+	CreatedAt time.Time      `json:"created_at"`
+	Status    ApprovalStatus `json:"status"`
+	DecidedAt *time.Time     `json:"decided_at,omitempty"`
+	DecidedBy string         `json:"decided_by,omitempty"`
+}
+
+// PendingResponse is returned by /pending endpoint, newest first
+type PendingResponse struct {
+	Updates []PendingUpdate `json:"updates"`
+}
+
+// JobKind is the kind of work requested through the HTTP API
+type JobKind string
+
+const (
+	JobCheck     JobKind = "check"      // Full check cycle of all apps
+	JobCheckApp  JobKind = "check_app"  // Check cycle of a single app
+	JobUpdateApp JobKind = "update_app" // Update of a single app to a given tag
+)
+
+// JobStatus is the progress of a job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is work requested through the HTTP API, run between scheduled cycles
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       JobKind    `json:"kind"`
+	Instance   string     `json:"instance,omitempty"` // Narrows app jobs to an instance
+	UUID       string     `json:"uuid,omitempty"`     // App of check_app and update_app jobs
+	Tag        string     `json:"tag,omitempty"`      // Target of update_app jobs, latest if empty
+	Actor      string     `json:"actor"`
+	Status     JobStatus  `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Result     string     `json:"result,omitempty"` // What the job did, once finished
+	Error      string     `json:"error,omitempty"`
+}